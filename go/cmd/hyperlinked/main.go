@@ -0,0 +1,54 @@
+// Command hyperlinked works with output captured by the ps package's sinks.
+//
+// Usage:
+//
+//	hyperlinked query [flags] db
+//	hyperlinked view file.jsonl|file.db
+//	hyperlinked replay [flags] file.jsonl|file.db
+//	hyperlinked merge [flags] [label=]file.jsonl|file.db ...
+//	hyperlinked markdown [flags] file.jsonl|file.db
+//
+// The command is a module of its own, like pssqlite, so that programs
+// importing ps do not depend on its terminal UI.
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+var commands = map[string]func(args []string) error{
+	"markdown": markdown,
+	"merge":    merge,
+	"query":    query,
+	"replay":   replay,
+	"view":     view,
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: hyperlinked <command> [flags] [args]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "commands:")
+	fmt.Fprintln(os.Stderr, "  markdown  export a JSONL or SQLite sink as Markdown with source links")
+	fmt.Fprintln(os.Stderr, "  merge     interleave several JSONL or SQLite sinks by time")
+	fmt.Fprintln(os.Stderr, "  query     print entries from a SQLite sink matching filters")
+	fmt.Fprintln(os.Stderr, "  replay    reprint a JSONL or SQLite sink, optionally at its original pace")
+	fmt.Fprintln(os.Stderr, "  view      browse a JSONL or SQLite sink interactively")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "hyperlinked: unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "hyperlinked %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+func markdown(args []string) error {
+	fs := flag.NewFlagSet("markdown", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: hyperlinked markdown [flags] file.jsonl|file.db")
+		fs.PrintDefaults()
+	}
+	github := fs.Bool("github", false, "link to GitHub at the current commit of the git checkout in the working directory, rather than to the editor")
+	out := fs.String("o", "", "write to this file rather than stdout")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var opts []ps.MarkdownOption
+	if *github {
+		opt, err := githubLinks()
+		if err != nil {
+			return err
+		}
+		opts = append(opts, opt)
+	}
+	src, err := openSource(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	entries, err := src.Read()
+	if err != nil {
+		return err
+	}
+	w := os.Stdout
+	if *out != "" {
+		if w, err = os.Create(*out); err != nil {
+			return err
+		}
+		defer w.Close()
+	}
+	return ps.WriteMarkdown(w, entries, opts...)
+}
+
+// githubLinks returns the option linking to GitHub at the current commit
+// of the git checkout in the working directory, whose origin must be on
+// GitHub.
+func githubLinks() (ps.MarkdownOption, error) {
+	git := func(args ...string) (string, error) {
+		out, err := exec.Command("git", args...).Output()
+		if err != nil {
+			return "", fmt.Errorf("git %s: %v", strings.Join(args, " "), err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+	remote, err := git("remote", "get-url", "origin")
+	if err != nil {
+		return nil, err
+	}
+	rev, err := git("rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	root, err := git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	repo, ok := githubRepo(remote)
+	if !ok {
+		return nil, fmt.Errorf("origin %s is not on GitHub", remote)
+	}
+	return ps.GitHubLinks(repo, rev, root), nil
+}
+
+// githubRepo returns the web URL of the GitHub repository with the git
+// remote URL remote, given over HTTPS or SSH.
+func githubRepo(remote string) (string, bool) {
+	remote = strings.TrimSuffix(remote, ".git")
+	for _, prefix := range []string{"https://github.com/", "ssh://git@github.com/", "git@github.com:"} {
+		if path, ok := strings.CutPrefix(remote, prefix); ok {
+			return "https://github.com/" + path, true
+		}
+	}
+	return "", false
+}
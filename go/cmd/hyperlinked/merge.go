@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+func merge(args []string) error {
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: hyperlinked merge [flags] [label=]file.jsonl|file.db ...")
+		fmt.Fprintln(fs.Output())
+		fmt.Fprintln(fs.Output(), "Interleaves the entries of several sinks by time, each line prefixed with")
+		fmt.Fprintln(fs.Output(), "the label of its file, which defaults to the file name without extension.")
+		fs.PrintDefaults()
+	}
+	offsets := offsetFlag{}
+	fs.Var(offsets, "offset", "shift the times of a source, as label=duration, e.g. srv=+134ms (repeatable)")
+	align := fs.String("align", "", "align the clocks of the sources with the first on the entries sharing values of this field, e.g. request")
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var sources []ps.Source
+	for _, arg := range fs.Args() {
+		label, path := sourceLabel(arg)
+		src, err := openSource(path)
+		if err != nil {
+			return err
+		}
+		entries, err := src.Read()
+		src.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		sources = append(sources, ps.Source{Label: label, Entries: entries, Offset: offsets[label]})
+	}
+	for label := range offsets {
+		if !slices.ContainsFunc(sources, func(s ps.Source) bool { return s.Label == label }) {
+			return fmt.Errorf("-offset: no source labelled %q", label)
+		}
+	}
+	if *align != "" {
+		ps.AlignSources(*align, sources)
+		for _, s := range sources[1:] {
+			if s.Offset != 0 && offsets[s.Label] == 0 {
+				fmt.Fprintf(os.Stderr, "hyperlinked merge: aligned %s by %+v\n", s.Label, s.Offset)
+			}
+		}
+	}
+	for _, e := range ps.Merge(sources...) {
+		fmt.Print(e.String())
+	}
+	return nil
+}
+
+// sourceLabel splits arg, "label=path" or "path", into the label and the
+// path, the label defaulting to the file name without extension.
+func sourceLabel(arg string) (label, path string) {
+	if label, path, ok := strings.Cut(arg, "="); ok && label != "" && !strings.ContainsRune(label, filepath.Separator) {
+		return label, path
+	}
+	base := filepath.Base(arg)
+	return strings.TrimSuffix(base, filepath.Ext(base)), arg
+}
+
+// offsetFlag collects -offset flags, label=duration, by label.
+type offsetFlag map[string]time.Duration
+
+func (f offsetFlag) String() string { return "" }
+
+func (f offsetFlag) Set(s string) error {
+	label, d, ok := strings.Cut(s, "=")
+	if !ok {
+		return fmt.Errorf("want label=duration, got %q", s)
+	}
+	offset, err := time.ParseDuration(strings.TrimPrefix(d, "+"))
+	if err != nil {
+		return err
+	}
+	f[label] = offset
+	return nil
+}
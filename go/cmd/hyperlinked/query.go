@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+	"github.com/dandavison/hyperlinked/go/pssqlite"
+)
+
+func query(args []string) error {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: hyperlinked query [flags] db")
+		fs.PrintDefaults()
+	}
+	var (
+		at        = fs.String("at", "", "call site as file or file:line, e.g. server.go:42")
+		fn        = fs.String("func", "", "function name substring")
+		text      = fs.String("grep", "", "message substring")
+		level     = fs.String("level", "", "minimum level (debug, info, warn, error)")
+		tag       = fs.String("tag", "", "tag, e.g. failure")
+		goroutine = fs.Int64("goroutine", 0, "goroutine ID")
+		after     = fs.String("after", "", "wall-clock start time (RFC3339)")
+		before    = fs.String("before", "", "wall-clock end time (RFC3339)")
+		from      = fs.Duration("from", 0, "start time relative to the start timer, e.g. 1.5s")
+		to        = fs.Duration("to", 0, "end time relative to the start timer")
+		limit     = fs.Int("n", 0, "maximum number of entries")
+	)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	f := pssqlite.Filter{
+		Func:      *fn,
+		Text:      *text,
+		Tag:       ps.Tag(*tag),
+		Goroutine: *goroutine,
+		From:      *from,
+		To:        *to,
+		Limit:     *limit,
+	}
+	if *at != "" {
+		f.File = *at
+		if i := strings.LastIndexByte(*at, ':'); i >= 0 {
+			line, err := strconv.Atoi((*at)[i+1:])
+			if err != nil {
+				return fmt.Errorf("-at: invalid line in %q", *at)
+			}
+			f.File, f.Line = (*at)[:i], line
+		}
+	}
+	if *level != "" {
+		l, err := ps.ParseLevel(*level)
+		if err != nil {
+			return err
+		}
+		f.Level = &l
+	}
+	var err error
+	if f.After, err = parseTime(*after); err != nil {
+		return fmt.Errorf("-after: %v", err)
+	}
+	if f.Before, err = parseTime(*before); err != nil {
+		return fmt.Errorf("-before: %v", err)
+	}
+
+	db, err := pssqlite.OpenDB(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	entries, err := pssqlite.Query(db, f)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		fmt.Print(e.String())
+	}
+	return nil
+}
+
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
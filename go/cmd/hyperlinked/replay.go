@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+func replay(args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: hyperlinked replay [flags] file.jsonl|file.db")
+		fs.PrintDefaults()
+	}
+	speed := fs.Float64("speed", 0, "pace lines by their recorded times, sped up by this factor (1 = real time; 0 = no pacing)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	src, err := openSource(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	entries, err := src.Read()
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := ps.ReplayEntries(ctx, entries, *speed); err != nil && err != context.Canceled {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/mattn/go-runewidth"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+	"github.com/dandavison/hyperlinked/go/pssqlite"
+)
+
+func view(args []string) error {
+	fs := flag.NewFlagSet("view", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: hyperlinked view file.jsonl|file.db")
+		fmt.Fprintln(fs.Output())
+		fmt.Fprintln(fs.Output(), viewHelp)
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	src, err := openSource(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	_, err = tea.NewProgram(&viewer{src: src, follow: true}, tea.WithAltScreen()).Run()
+	return err
+}
+
+const viewHelp = `keys:
+  ↑/↓ k/j, pgup/pgdn, home/end   move
+  enter                          open the selected entry's location
+  /                              filter by message text
+  f                              filter by file
+  l                              cycle the minimum level
+  g                              toggle filtering by the selected goroutine
+  c                              toggle collapsing repeated call sites
+  F                              toggle following new entries
+  esc                            clear filters
+  q                              quit`
+
+// source reads entries from a sink's output, returning only those added
+// since the previous call.
+type source interface {
+	Read() ([]ps.Entry, error)
+	Close() error
+}
+
+func openSource(path string) (source, error) {
+	switch filepath.Ext(path) {
+	case ".db", ".sqlite", ".sqlite3":
+		db, err := pssqlite.OpenDB(path)
+		if err != nil {
+			return nil, err
+		}
+		return &sqliteSource{db: db}, nil
+	default:
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonlSource{f: f, r: bufio.NewReader(f)}, nil
+	}
+}
+
+type sqliteSource struct {
+	db   *sql.DB
+	seen int
+}
+
+func (s *sqliteSource) Read() ([]ps.Entry, error) {
+	entries, err := pssqlite.Query(s.db, pssqlite.Filter{Offset: s.seen})
+	s.seen += len(entries)
+	return entries, err
+}
+
+func (s *sqliteSource) Close() error { return s.db.Close() }
+
+type jsonlSource struct {
+	f       *os.File
+	r       *bufio.Reader
+	partial string
+}
+
+func (s *jsonlSource) Read() ([]ps.Entry, error) {
+	var entries []ps.Entry
+	for {
+		line, err := s.r.ReadString('\n')
+		if err == io.EOF {
+			// Keep an incomplete last line until the writer finishes it.
+			s.partial += line
+			return entries, nil
+		}
+		if err != nil {
+			return entries, err
+		}
+		line, s.partial = s.partial+line, ""
+		var e ps.Entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+}
+
+func (s *jsonlSource) Close() error { return s.f.Close() }
+
+// row is a line of the viewer: an entry, and the number of following
+// entries from the same call site collapsed into it.
+type row struct {
+	entry     ps.Entry
+	collapsed int
+}
+
+type inputMode int
+
+const (
+	inputNone inputMode = iota
+	inputText
+	inputFile
+)
+
+type viewer struct {
+	src     source
+	entries []ps.Entry
+	rows    []row
+	err     error
+
+	cursor, top   int
+	width, height int
+	follow        bool
+	collapse      bool
+
+	text      string
+	file      string
+	level     *ps.Level
+	goroutine int64
+
+	mode  inputMode
+	input string
+}
+
+type tickMsg struct{}
+
+func tick() tea.Cmd {
+	return tea.Tick(500*time.Millisecond, func(time.Time) tea.Msg { return tickMsg{} })
+}
+
+func (v *viewer) Init() tea.Cmd {
+	v.load()
+	return tick()
+}
+
+func (v *viewer) load() {
+	entries, err := v.src.Read()
+	v.err = err
+	if len(entries) == 0 {
+		return
+	}
+	v.entries = append(v.entries, entries...)
+	v.refilter()
+}
+
+func (v *viewer) matches(e ps.Entry) bool {
+	if v.text != "" && !strings.Contains(strings.ToLower(e.Msg), strings.ToLower(v.text)) {
+		return false
+	}
+	if v.file != "" && !strings.Contains(e.File, v.file) {
+		return false
+	}
+	if v.level != nil && e.Level < *v.level {
+		return false
+	}
+	if v.goroutine != 0 && e.Goroutine != v.goroutine {
+		return false
+	}
+	return true
+}
+
+func (v *viewer) refilter() {
+	v.rows = v.rows[:0]
+	for _, e := range v.entries {
+		if !v.matches(e) {
+			continue
+		}
+		if n := len(v.rows); v.collapse && n > 0 && v.rows[n-1].entry.File == e.File && v.rows[n-1].entry.Line == e.Line {
+			v.rows[n-1].collapsed++
+			continue
+		}
+		v.rows = append(v.rows, row{entry: e})
+	}
+	if v.follow || v.cursor >= len(v.rows) {
+		v.cursor = len(v.rows) - 1
+	}
+	if v.cursor < 0 {
+		v.cursor = 0
+	}
+	v.scroll()
+}
+
+// listHeight is the number of rows available for entries.
+func (v *viewer) listHeight() int {
+	return max(v.height-1, 1)
+}
+
+func (v *viewer) scroll() {
+	if v.cursor < v.top {
+		v.top = v.cursor
+	}
+	if h := v.listHeight(); v.cursor >= v.top+h {
+		v.top = v.cursor - h + 1
+	}
+}
+
+func (v *viewer) move(delta int) {
+	v.cursor = min(max(v.cursor+delta, 0), max(len(v.rows)-1, 0))
+	v.follow = v.cursor == len(v.rows)-1 && v.follow
+	v.scroll()
+}
+
+func (v *viewer) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tickMsg:
+		v.load()
+		return v, tick()
+	case tea.WindowSizeMsg:
+		v.width, v.height = msg.Width, msg.Height
+		v.scroll()
+	case tea.KeyMsg:
+		if v.mode != inputNone {
+			v.updateInput(msg)
+			return v, nil
+		}
+		switch msg.String() {
+		case "q", "ctrl+c":
+			return v, tea.Quit
+		case "up", "k":
+			v.move(-1)
+		case "down", "j":
+			v.move(1)
+		case "pgup":
+			v.move(-v.listHeight())
+		case "pgdown":
+			v.move(v.listHeight())
+		case "home":
+			v.move(-len(v.rows))
+		case "end":
+			v.move(len(v.rows))
+		case "F":
+			v.follow = !v.follow
+			if v.follow {
+				v.move(len(v.rows))
+			}
+		case "/":
+			v.mode, v.input = inputText, v.text
+		case "f":
+			v.mode, v.input = inputFile, v.file
+		case "l":
+			v.level = nextLevel(v.level)
+			v.refilter()
+		case "g":
+			if v.goroutine != 0 {
+				v.goroutine = 0
+			} else if e, ok := v.selected(); ok {
+				v.goroutine = e.Goroutine
+			}
+			v.refilter()
+		case "c":
+			v.collapse = !v.collapse
+			v.refilter()
+		case "esc":
+			v.text, v.file, v.level, v.goroutine = "", "", nil, 0
+			v.refilter()
+		case "enter":
+			if e, ok := v.selected(); ok && e.File != "" {
+				v.err = openURL(ps.FormatURL(e.File, e.Line))
+			}
+		}
+	}
+	return v, nil
+}
+
+func (v *viewer) updateInput(msg tea.KeyMsg) {
+	switch msg.Type {
+	case tea.KeyEnter:
+		if v.mode == inputText {
+			v.text = v.input
+		} else {
+			v.file = v.input
+		}
+		v.mode = inputNone
+		v.refilter()
+	case tea.KeyEsc:
+		v.mode = inputNone
+	case tea.KeyBackspace:
+		if r := []rune(v.input); len(r) > 0 {
+			v.input = string(r[:len(r)-1])
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		v.input += string(msg.Runes)
+	}
+}
+
+func (v *viewer) selected() (ps.Entry, bool) {
+	if v.cursor < 0 || v.cursor >= len(v.rows) {
+		return ps.Entry{}, false
+	}
+	return v.rows[v.cursor].entry, true
+}
+
+func nextLevel(l *ps.Level) *ps.Level {
+	levels := []ps.Level{ps.LevelDebug, ps.LevelInfo, ps.LevelWarn, ps.LevelError}
+	if l == nil {
+		return &levels[0]
+	}
+	for i, level := range levels {
+		if level == *l && i+1 < len(levels) {
+			return &levels[i+1]
+		}
+	}
+	return nil
+}
+
+func (v *viewer) View() string {
+	var b strings.Builder
+	h := v.listHeight()
+	for i := v.top; i < v.top+h; i++ {
+		if i < len(v.rows) {
+			line := formatRow(v.rows[i], v.width)
+			if i == v.cursor {
+				line = "\x1b[7m" + runewidth.FillRight(line, v.width) + "\x1b[0m"
+			}
+			b.WriteString(line)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(v.status())
+	return b.String()
+}
+
+func formatRow(r row, width int) string {
+	e := r.entry
+	msg := e.Msg
+	for _, f := range e.Fields {
+		msg += " " + f.String()
+	}
+	if emoji := e.Tag.Emoji(); emoji != "" {
+		msg = emoji + " " + msg
+	}
+	text := fmt.Sprintf("[%5d] %-5s g%-4d %s", e.Elapsed.Milliseconds(), e.Level, e.Goroutine, msg)
+	if r.collapsed > 0 {
+		text += fmt.Sprintf(" (×%d)", r.collapsed+1)
+	}
+	location := ""
+	if e.File != "" {
+		location = fmt.Sprintf("  %s:%d", ps.DisplayPath(e.File), e.Line)
+	}
+	text = strings.ReplaceAll(text, "\n", "⏎")
+	if width <= 0 {
+		return text + location
+	}
+	avail := width - runewidth.StringWidth(location)
+	if avail < 1 {
+		return runewidth.Truncate(text, width, "…")
+	}
+	text = runewidth.Truncate(text, avail, "…")
+	return runewidth.FillRight(text, avail) + location
+}
+
+func (v *viewer) status() string {
+	switch v.mode {
+	case inputText:
+		return "/" + v.input
+	case inputFile:
+		return "file: " + v.input
+	}
+	var parts []string
+	parts = append(parts, fmt.Sprintf("%d/%d", len(v.rows), len(v.entries)))
+	if v.text != "" {
+		parts = append(parts, "text="+v.text)
+	}
+	if v.file != "" {
+		parts = append(parts, "file="+v.file)
+	}
+	if v.level != nil {
+		parts = append(parts, "level>="+v.level.String())
+	}
+	if v.goroutine != 0 {
+		parts = append(parts, fmt.Sprintf("goroutine=%d", v.goroutine))
+	}
+	if v.collapse {
+		parts = append(parts, "collapsed")
+	}
+	if v.follow {
+		parts = append(parts, "following")
+	}
+	if v.err != nil {
+		parts = append(parts, "error: "+v.err.Error())
+	}
+	return "\x1b[2m" + strings.Join(parts, "  ") + "\x1b[0m"
+}
+
+// openURL opens url with the operating system's handler for its scheme.
+func openURL(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
@@ -4,10 +4,7 @@ go 1.24.0
 
 require (
 	github.com/mattn/go-runewidth v0.0.19
-	golang.org/x/term v0.38.0
+	github.com/rivo/uniseg v0.4.7
 )
 
-require (
-	github.com/clipperhouse/uax29/v2 v2.2.0 // indirect
-	golang.org/x/sys v0.39.0 // indirect
-)
+require github.com/clipperhouse/uax29/v2 v2.2.0 // indirect
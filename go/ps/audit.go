@@ -0,0 +1,89 @@
+package ps
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// auditFromEnv adds the sink of HYPERLINKED_AUDIT, if set.
+func auditFromEnv() {
+	if path := os.Getenv("HYPERLINKED_AUDIT"); path != "" {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ps: HYPERLINKED_AUDIT: %v\n", err)
+			return
+		}
+		AddSink(&auditSink{f: f})
+	}
+}
+
+// auditSink is the sink added by HYPERLINKED_AUDIT=path: it appends each
+// entry to the file at path as a line of JSON, as encoded by MarshalJSON
+// with an added "stack" key holding the complete stack of the goroutine
+// that printed it, innermost first, as "func file:line" strings. It
+// answers the question of which code path printed a line without
+// running the program again.
+type auditSink struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+// WriteEntry implements Sink. It is called on the goroutine that printed
+// e, whose stack it records.
+func (s *auditSink) WriteEntry(e Entry) error {
+	line, err := e.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	stack, err := json.Marshal(auditStack())
+	if err != nil {
+		return err
+	}
+	line = append(line[:len(line)-1], `,"stack":`...)
+	line = append(line, stack...)
+	line = append(line, '}', '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.f.Write(line)
+	return err
+}
+
+// Close closes the file.
+func (s *auditSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
+
+// psPackage is the import path of this package.
+var psPackage = modulePrefix + "ps"
+
+// auditStack returns the stack of the calling goroutine outside this
+// package.
+func auditStack() []string {
+	pcs := make([]uintptr, 128)
+	for {
+		n := runtime.Callers(2, pcs)
+		if n < len(pcs) {
+			pcs = pcs[:n]
+			break
+		}
+		pcs = make([]uintptr, 2*len(pcs))
+	}
+	var stack []string
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if framePackage(frame) != psPackage {
+			stack = append(stack, frame.Function+" "+frame.File+":"+strconv.Itoa(frame.Line))
+		}
+		if !more {
+			return stack
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package ps
+
+import (
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Handle ties the line printed by End to the line printed by Begin.
+type Handle struct {
+	p     *Printer
+	site  callSite
+	start time.Time
+}
+
+// Begin prints a line like F and returns a handle whose End prints the
+// matching line, such as the response to a request:
+//
+//	h := ps.Begin("⤴ sent request %s\n", id)
+//	...
+//	h.End("⬅ got response %d\n", code)
+//
+// prints:
+//
+//	[    0] ⤴ sent request 7
+//	[   34] ⬅ got response 200 after 34ms (from client.go:12)
+//
+// where "client.go:12" links to the Begin line's location. Terminals that
+// support OSC8 link ids highlight both when either is hovered.
+func Begin(format string, args ...interface{}) *Handle {
+	return std.begin(1, format, args)
+}
+
+// Begin is like the package-level Begin.
+func (p *Printer) Begin(format string, args ...interface{}) *Handle {
+	return p.begin(1, format, args)
+}
+
+func (p *Printer) begin(skip int, format string, args []interface{}) *Handle {
+	h := &Handle{p: p, site: p.callSite(skip + 1), start: time.Now()}
+	if p.prints("") {
+		p.printAt(h.site, newEntry(""), format, args)
+	}
+	return h
+}
+
+// End prints a line like F, followed by the time since Begin and the
+// location of Begin.
+func (h *Handle) End(format string, args ...interface{}) {
+	d := time.Since(h.start)
+	args = append(args[:len(args):len(args)], formatDuration(d), beginSite(h.site))
+	h.p.printf(1, "", addSuffix(format, " after %s (from %v)"), args)
+}
+
+// beginSite is the location of a Begin line, or of the call starting a
+// goroutine with Go, formatted as file:line and linked to it.
+type beginSite callSite
+
+func (s beginSite) String() string {
+	return filepath.Base(s.file) + ":" + strconv.Itoa(s.line)
+}
+
+// Location implements Locator.
+func (s beginSite) Location() (file string, line int) {
+	return s.file, s.line
+}
@@ -0,0 +1,76 @@
+package ps
+
+import (
+	"io"
+	"testing"
+)
+
+// raceEnabled reports whether the tests are built with -race.
+var raceEnabled bool
+
+// benchmarkOutput discards the output for the duration of b, with
+// truncation off, the case that is to print without allocating.
+func benchmarkOutput(b *testing.B) {
+	b.Helper()
+	SetOutput(io.Discard)
+	b.Cleanup(func() { SetOutput(nil) })
+	configure(b, func(s *Settings) { s.Truncate = false })
+	b.ReportAllocs()
+	b.ResetTimer()
+}
+
+func BenchmarkF(b *testing.B) {
+	b.Run("plain", func(b *testing.B) {
+		benchmarkOutput(b)
+		for i := 0; i < b.N; i++ {
+			F("hello\n")
+		}
+	})
+	b.Run("args", func(b *testing.B) {
+		benchmarkOutput(b)
+		for i := 0; i < b.N; i++ {
+			F("hello %s %d\n", "world", 42)
+		}
+	})
+	b.Run("multiline", func(b *testing.B) {
+		benchmarkOutput(b)
+		for i := 0; i < b.N; i++ {
+			F("hello\nworld\n")
+		}
+	})
+}
+
+func BenchmarkLn(b *testing.B) {
+	benchmarkOutput(b)
+	for i := 0; i < b.N; i++ {
+		Ln("hello")
+	}
+}
+
+func BenchmarkHyperlink(b *testing.B) {
+	benchmarkOutput(b)
+	for i := 0; i < b.N; i++ {
+		Hyperlink("hello", 0)
+	}
+}
+
+// TestFastPathAllocs checks that the lines BenchmarkF and BenchmarkLn
+// print without arguments needing to escape are printed without
+// allocating.
+func TestFastPathAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector allocates")
+	}
+	SetOutput(io.Discard)
+	t.Cleanup(func() { SetOutput(nil) })
+	configure(t, func(s *Settings) { s.Truncate = false })
+	for name, f := range map[string]func(){
+		"F":      func() { F("hello\n") },
+		"F/args": func() { F("hello %s %d\n", "world", 42) },
+		"Ln":     func() { Ln("hello") },
+	} {
+		if n := testing.AllocsPerRun(100, f); n != 0 {
+			t.Errorf("%s: %v allocs per line, want 0", name, n)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package ps
+
+import (
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+)
+
+// BuildInfo identifies the code of the program, as stamped into it by the
+// Go toolchain, so that links in captured output can later be resolved
+// against the revision of the repository they were printed from.
+type BuildInfo struct {
+	// Module is the path of the main module.
+	Module string `json:"module"`
+	// Version is the version of the main module, such as "v1.2.3" or
+	// "(devel)".
+	Version string `json:"version,omitempty"`
+	// Revision is the VCS revision the program was built from.
+	Revision string `json:"revision,omitempty"`
+	// Dirty reports whether the working tree had uncommitted changes.
+	Dirty bool `json:"dirty,omitempty"`
+}
+
+// String renders b as "module version rev revision", with " (dirty)" if
+// the working tree was dirty.
+func (b BuildInfo) String() string {
+	s := b.Module
+	if b.Version != "" {
+		s += " " + b.Version
+	}
+	if b.Revision != "" {
+		s += " rev " + b.Revision
+	}
+	if b.Dirty {
+		s += " (dirty)"
+	}
+	return s
+}
+
+// Build returns the build info of the program, or nil if it has none, as
+// for programs built without module support. It is recorded in the Build
+// field of the entries passed to sinks.
+func Build() *BuildInfo {
+	return buildInfo()
+}
+
+var buildInfo = sync.OnceValue(func() *BuildInfo {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Path == "" {
+		return nil
+	}
+	b := &BuildInfo{Module: info.Main.Path, Version: info.Main.Version}
+	for _, s := range info.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			b.Revision = s.Value
+		case "vcs.modified":
+			b.Dirty = s.Value == "true"
+		}
+	}
+	return b
+})
+
+// bannerShown records whether the banner has been printed.
+var bannerShown atomic.Bool
+
+// Banner prints a line identifying the build of the program, as returned
+// by Build, linked to the caller. Call it at startup, so that a captured
+// session shows what code it came from; with Settings.Banner, it is
+// printed before the first line instead.
+func Banner() {
+	bannerShown.Store(true)
+	std.printf(1, "", "%s\n", []interface{}{bannerText()})
+}
+
+// autoBanner prints the banner, unlinked, if Settings.Banner is set and it
+// has not been printed.
+func autoBanner() {
+	if cfg().Banner && bannerShown.CompareAndSwap(false, true) {
+		std.printAt(callSite{}, newEntry(""), "%s\n", []interface{}{bannerText()})
+	}
+}
+
+// bannerText returns the message of the banner.
+func bannerText() string {
+	if b := Build(); b != nil {
+		return "build: " + b.String()
+	}
+	return "build: unknown"
+}
@@ -0,0 +1,136 @@
+package ps
+
+import (
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Auto, passed as the skip argument of Hyperlink or WithSkip, links to the
+// first caller outside this module and the packages registered with
+// HelperPackage, however many frames up the stack it is.
+const Auto = -1
+
+var (
+	helpers    sync.Map // function name -> struct{}
+	hasHelpers atomic.Bool
+
+	helperPackagesMu sync.RWMutex
+	helperPackages   []string
+
+	// modulePrefix is the import path prefix of this module's packages.
+	modulePrefix = func() string {
+		pc, _, _, _ := runtime.Caller(0)
+		pkg := framePackage(runtime.Frame{Function: runtime.FuncForPC(pc).Name()})
+		return path.Dir(pkg) + "/"
+	}()
+)
+
+// HelperPackage registers the package with import path pkg as a helper
+// package: with Auto, its frames are skipped like those of this module.
+func HelperPackage(pkg string) {
+	helperPackagesMu.Lock()
+	defer helperPackagesMu.Unlock()
+	helperPackages = append(helperPackages, pkg)
+}
+
+// isHelperFrame reports whether Auto skips frame.
+func isHelperFrame(frame runtime.Frame) bool {
+	pkg := framePackage(frame)
+	if strings.HasPrefix(pkg, modulePrefix) {
+		return true
+	}
+	helperPackagesMu.RLock()
+	defer helperPackagesMu.RUnlock()
+	for _, p := range helperPackages {
+		if pkg == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Helper marks the calling function as a helper, like testing.T.Helper:
+// lines printed through it link to its caller instead. Call it at the
+// start of logging wrappers:
+//
+//	func logf(format string, args ...interface{}) {
+//		ps.Helper()
+//		ps.F("myapp: "+format, args...)
+//	}
+func Helper() {
+	pc, _, _, ok := runtime.Caller(1)
+	if !ok {
+		return
+	}
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		helpers.Store(fn.Name(), struct{}{})
+		hasHelpers.Store(true)
+	}
+}
+
+// Caller returns the source location that its caller's lines would link
+// to with WithSkip(skip): that of the caller skip frames up the stack, 0
+// being Caller's caller, skipping the frames of functions marked with
+// Helper, or with skip = Auto, that of the first caller outside this
+// module and helper packages. Helpers such as assertion libraries use it
+// to report the line they were called from.
+func Caller(skip int) (file string, line int, ok bool) {
+	if skip != Auto {
+		skip++
+	}
+	_, file, line, _, ok = caller(skip)
+	return file, line, ok
+}
+
+// caller is like runtime.Caller, but also returns the function name and
+// skips over frames of functions marked with Helper. With skip = Auto it
+// also skips frames of this module and of helper packages.
+func caller(skip int) (pc uintptr, file string, line int, funcName string, ok bool) {
+	auto := skip == Auto
+	if auto {
+		skip = 0
+	}
+	if !auto && !hasHelpers.Load() {
+		// Unlike runtime.Caller, this does not allocate. Callers returns
+		// return addresses; the call instruction (in the innermost inlined
+		// function, if any) is just before.
+		var pcs [1]uintptr
+		if runtime.Callers(skip+2, pcs[:]) < 1 {
+			return 0, "", 0, "", false
+		}
+		pc = pcs[0] - 1
+		fn := runtime.FuncForPC(pc)
+		if fn == nil {
+			return 0, "", 0, "", false
+		}
+		file, line = fn.FileLine(pc)
+		file, line = sourceFor(fn, file, line)
+		return pc, file, line, fn.Name(), true
+	}
+
+	// Skip 2: runtime.Callers + caller
+	var pcs [64]uintptr
+	n := runtime.Callers(skip+2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	var first runtime.Frame
+	for i := 0; ; i++ {
+		frame, more := frames.Next()
+		if i == 0 {
+			first = frame
+		}
+		_, helper := helpers.Load(frame.Function)
+		if !helper && !(auto && isHelperFrame(frame)) {
+			file, line = sourceFor(runtime.FuncForPC(frame.PC), frame.File, frame.Line)
+			return frame.PC, file, line, frame.Function, frame.PC != 0
+		}
+		if !more {
+			break
+		}
+	}
+	// Every frame is a helper: link to the innermost one.
+	file, line = sourceFor(runtime.FuncForPC(first.PC), first.File, first.Line)
+	return first.PC, file, line, first.Function, first.PC != 0
+}
@@ -0,0 +1,173 @@
+package ps
+
+import (
+	"io"
+	"runtime"
+	"testing"
+)
+
+// logf is a logging wrapper marked with Helper.
+func logf(format string, args ...interface{}) {
+	Helper()
+	F(format, args...)
+}
+
+// tracef is a wrapper marked with Helper around another one.
+func tracef(format string, args ...interface{}) {
+	Helper()
+	logf("trace: "+format, args...)
+}
+
+// inner prints a line skipping skip frames, called through outer.
+func inner(skip int) {
+	WithSkip(skip).F("inner\n")
+}
+
+func outer(skip int) {
+	inner(skip)
+}
+
+// innerLine and outerLine are the lines in inner and outer printing and
+// calling inner.
+const (
+	innerLine = 23
+	outerLine = 27
+)
+
+// testCaller prints with the Printer under test.
+func testCaller(t *testing.T) *entries {
+	t.Helper()
+	SetOutput(io.Discard)
+	t.Cleanup(func() { SetOutput(nil) })
+	return sink(t)
+}
+
+// resetHelpers forgets the functions marked with Helper when t ends, so
+// that caller takes its fast path again, and forces its slow path for t if
+// slow.
+func resetHelpers(t *testing.T, slow bool) {
+	if slow {
+		hasHelpers.Store(true)
+	}
+	t.Cleanup(func() {
+		helpers.Range(func(k, _ any) bool {
+			helpers.Delete(k)
+			return true
+		})
+		hasHelpers.Store(false)
+	})
+}
+
+// wantSite checks that e links to line of this file.
+func wantSite(t *testing.T, e Entry, line int) {
+	t.Helper()
+	_, file, _, _ := runtime.Caller(0)
+	if e.File != file || e.Line != line {
+		t.Errorf("linked to %s:%d, want %s:%d", e.File, e.Line, file, line)
+	}
+}
+
+func TestWithSkip(t *testing.T) {
+	for _, slow := range []bool{false, true} {
+		name := "fast"
+		if slow {
+			name = "slow"
+		}
+		t.Run(name, func(t *testing.T) {
+			resetHelpers(t, slow)
+			es := testCaller(t)
+			outer(0)
+			wantSite(t, es.last(), innerLine)
+			outer(1)
+			wantSite(t, es.last(), outerLine)
+			_, _, line, _ := runtime.Caller(0)
+			outer(2)
+			wantSite(t, es.last(), line+1)
+		})
+	}
+}
+
+func TestHelper(t *testing.T) {
+	resetHelpers(t, false)
+	es := testCaller(t)
+	_, _, line, _ := runtime.Caller(0)
+	logf("direct\n")
+	wantSite(t, es.last(), line+1)
+	tracef("nested\n")
+	wantSite(t, es.last(), line+3)
+	_, _, line, _ = runtime.Caller(0)
+	func() {
+		logf("in a closure\n")
+	}()
+	wantSite(t, es.last(), line+2)
+}
+
+func TestCaller(t *testing.T) {
+	resetHelpers(t, false)
+	// where is a helper returning the location of its caller.
+	where := func() (string, int) {
+		Helper()
+		file, line, _ := Caller(0)
+		return file, line
+	}
+	_, wantFile, wantLine, _ := runtime.Caller(0)
+	file, line := where()
+	if file != wantFile || line != wantLine+1 {
+		t.Errorf("Caller(0) in a helper returned %s:%d, want %s:%d", file, line, wantFile, wantLine+1)
+	}
+	if file, line, _ := Caller(Auto); file == wantFile {
+		t.Errorf("Caller(Auto) returned %s:%d in this module", file, line)
+	}
+}
+
+func TestHelperWithSkip(t *testing.T) {
+	resetHelpers(t, false)
+	es := testCaller(t)
+	// Helper frames are skipped before WithSkip's frames are counted.
+	skipf := func() {
+		Helper()
+		outer(1)
+	}
+	skipf()
+	wantSite(t, es.last(), outerLine)
+}
+
+func TestAuto(t *testing.T) {
+	es := testCaller(t)
+	// The first caller outside this module runs the test.
+	outer(Auto)
+	if e := es.last(); e.Func != "testing.tRunner" {
+		t.Errorf("Auto linked to %s (%s:%d), want testing.tRunner", e.Func, e.File, e.Line)
+	}
+	e := es.last()
+	WithSkip(Auto).WithSkip(1).F("still auto\n")
+	if got := es.last(); got.File != e.File || got.Line != e.Line {
+		t.Errorf("WithSkip(Auto).WithSkip(1) linked to %s:%d, want %s:%d", got.File, got.Line, e.File, e.Line)
+	}
+}
+
+func TestHelperPackage(t *testing.T) {
+	prev := helperPackages
+	t.Cleanup(func() {
+		helperPackagesMu.Lock()
+		helperPackages = prev
+		helperPackagesMu.Unlock()
+	})
+	frame := runtime.Frame{Function: "example.com/logx.Logf"}
+	if isHelperFrame(frame) {
+		t.Fatalf("%s is a helper frame before HelperPackage", frame.Function)
+	}
+	HelperPackage("example.com/logx")
+	if !isHelperFrame(frame) {
+		t.Errorf("%s is not a helper frame after HelperPackage", frame.Function)
+	}
+	if other := (runtime.Frame{Function: "example.com/logx/sub.Logf"}); isHelperFrame(other) {
+		t.Errorf("%s is a helper frame, but only its parent package is registered", other.Function)
+	}
+	es := testCaller(t)
+	HelperPackage("testing")
+	outer(Auto)
+	if e := es.last(); e.Func != "runtime.goexit" {
+		t.Errorf("Auto with testing a helper package linked to %s, want runtime.goexit", e.Func)
+	}
+}
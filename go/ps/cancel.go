@@ -0,0 +1,111 @@
+package ps
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// WithCancel is like context.WithCancel, but the cancel function records
+// where it is called, for WhyCanceled:
+//
+//	ctx, cancel := ps.WithCancel(ctx)
+func WithCancel(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	return ctx, func() { cancel(canceledHere(2, nil)) }
+}
+
+// WithCancelCause is like context.WithCancelCause, but the cancel function
+// records where it is called, for WhyCanceled. The cause returned by
+// context.Cause wraps the cause passed to it, or context.Canceled if it
+// is nil, and implements Locator, so that printing it links to the call.
+func WithCancelCause(parent context.Context) (context.Context, context.CancelCauseFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	return ctx, func(cause error) { cancel(canceledHere(2, cause)) }
+}
+
+// WithTimeout is like context.WithTimeout, but records where the timeout
+// was set, as the cause of the context when it expires, and where the
+// cancel function is called, for WhyCanceled.
+func WithTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return withDeadline(parent, time.Now().Add(timeout))
+}
+
+// WithDeadline is like context.WithDeadline, recording the same as
+// WithTimeout.
+func WithDeadline(parent context.Context, d time.Time) (context.Context, context.CancelFunc) {
+	return withDeadline(parent, d)
+}
+
+func withDeadline(parent context.Context, d time.Time) (context.Context, context.CancelFunc) {
+	expired := canceledHere(3, context.DeadlineExceeded)
+	ctx, cancel := context.WithCancelCause(parent)
+	ctx, stop := context.WithDeadlineCause(ctx, d, expired)
+	return ctx, func() {
+		cancel(canceledHere(2, nil))
+		stop()
+	}
+}
+
+// canceledHere returns cause, or context.Canceled if it is nil, located at
+// the caller skip frames above canceledHere.
+func canceledHere(skip int, cause error) error {
+	if cause == nil {
+		cause = context.Canceled
+	}
+	_, file, line, _, ok := caller(skip)
+	if !ok {
+		return cause
+	}
+	return &locatedError{err: cause, file: file, line: line}
+}
+
+// WhyCanceled prints a Bad line saying why ctx was canceled, with the
+// cause and the location of the call canceling it, linked to that call,
+// if ctx was made by WithCancel, WithCancelCause, WithTimeout or
+// WithDeadline, or derives from such a context that was canceled:
+//
+//	[  512] 🔴 context canceled at server.go:88: shutting down
+//	[ 1020] 🔴 context canceled at main.go:40
+//	[ 5003] 🔴 context deadline exceeded, set at fetch.go:21
+func WhyCanceled(ctx context.Context) {
+	std.whyCanceled(1, ctx)
+}
+
+// WhyCanceled is like the package-level WhyCanceled.
+func (p *Printer) WhyCanceled(ctx context.Context) {
+	p.whyCanceled(1, ctx)
+}
+
+func (p *Printer) whyCanceled(skip int, ctx context.Context) {
+	if !p.prints(Bad) {
+		return
+	}
+	site := p.callSite(skip + 1)
+	if ctx.Err() == nil {
+		p.printAt(site, newEntry(Bad), "context not canceled\n", nil)
+		return
+	}
+	cause := context.Cause(ctx)
+	var located *locatedError
+	switch {
+	case !errors.As(cause, &located):
+		if cause == context.Canceled || cause == context.DeadlineExceeded {
+			p.printAt(site, newEntry(Bad), "%v, where not recorded\n", []interface{}{cause})
+		} else {
+			p.printAt(site, newEntry(Bad), "context canceled, where not recorded: %v\n", []interface{}{cause})
+		}
+	case located.err == context.DeadlineExceeded:
+		p.printAt(site, newEntry(Bad), "context deadline exceeded, set at %v\n", []interface{}{locatedSite(located)})
+	case located.err == context.Canceled:
+		p.printAt(site, newEntry(Bad), "context canceled at %v\n", []interface{}{locatedSite(located)})
+	default:
+		p.printAt(site, newEntry(Bad), "context canceled at %v: %v\n", []interface{}{locatedSite(located), located.err})
+	}
+}
+
+// locatedSite returns the location of err, formatted as file:line and
+// linked to it.
+func locatedSite(err *locatedError) beginSite {
+	return beginSite{file: err.file, line: err.line, ok: true}
+}
@@ -0,0 +1,124 @@
+package ps
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// Close shuts down what this package runs on behalf of the program, for
+// servers stopping gracefully and for tests, returning the errors of
+// closing. In order, it:
+//
+//   - stops the goroutines started by Heartbeat and WatchFile
+//   - runs the hooks registered with OnExit, as Shutdown does, and
+//     summarizes the lines suppressed by the rate cap
+//   - flushes the output set by SetOutput, if it has a Flush method, as a
+//     bufio.Writer does
+//   - closes the registered sinks that implement io.Closer, which sends
+//     the entries buffered by asynchronous sinks such as psloki's, and
+//     removes them
+//
+// If ctx is done first, Close returns its error, leaving the rest to
+// finish in the background. Close is safe to call more than once and
+// concurrently: each hook, goroutine and sink is stopped once, and a later
+// call finds nothing left to close, except output to flush. Lines may
+// still be printed after Close, to the output and to the sinks added
+// since.
+func Close(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		stopTasks()
+		runExitHooks()
+		summarizeSuppressed()
+		done <- errors.Join(flushOutput(), closeSinks())
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close is the package-level Close: printers share the output, sinks and
+// hooks of the package, so closing through any printer closes them for
+// all.
+func (p *Printer) Close(ctx context.Context) error {
+	return Close(ctx)
+}
+
+// flushOutput flushes the output, if it has a Flush method.
+func flushOutput() error {
+	f, ok := Output().(interface{ Flush() error })
+	if !ok {
+		return nil
+	}
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	return f.Flush()
+}
+
+// closeSinks removes the registered sinks that implement io.Closer and
+// closes them, returning their errors.
+func closeSinks() error {
+	sinksMu.Lock()
+	var closers []io.Closer
+	if cur := sinks.Load(); cur != nil {
+		var next []Sink
+		for _, s := range *cur {
+			if c, ok := s.(io.Closer); ok {
+				closers = append(closers, c)
+			} else {
+				next = append(next, s)
+			}
+		}
+		sinks.Store(&next)
+	}
+	sinksMu.Unlock()
+
+	var errs []error
+	for _, c := range closers {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// task is a goroutine printing lines in the background, such as that of
+// Heartbeat, until it is stopped by the function it was started with or
+// by Close.
+type task struct {
+	stop func()
+}
+
+var (
+	tasksMu sync.Mutex
+	tasks   = map[*task]bool{}
+)
+
+// track registers the goroutine stopped by stop for Close, returning the
+// function that stops it and unregisters it.
+func track(stop func()) func() {
+	t := &task{stop: stop}
+	tasksMu.Lock()
+	tasks[t] = true
+	tasksMu.Unlock()
+	return func() {
+		tasksMu.Lock()
+		delete(tasks, t)
+		tasksMu.Unlock()
+		stop()
+	}
+}
+
+// stopTasks stops the goroutines registered by track.
+func stopTasks() {
+	tasksMu.Lock()
+	running := tasks
+	tasks = map[*task]bool{}
+	tasksMu.Unlock()
+	for t := range running {
+		t.stop()
+	}
+}
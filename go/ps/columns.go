@@ -0,0 +1,202 @@
+package ps
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Column names, as used in Column.Name.
+const (
+	ColumnProcess   = "process"
+	ColumnTime      = "time"
+	ColumnSeq       = "seq"
+	ColumnGoroutine = "goroutine"
+	ColumnLabels    = "labels"
+	ColumnTag       = "tag"
+	ColumnMsg       = "msg"
+	ColumnLocation  = "location"
+)
+
+// Column is a column of the layout set by SetColumns.
+type Column struct {
+	// Name is the content of the column, one of the Column constants.
+	Name string
+	// Width is the width of a fixed column, or 0 for a flexible column,
+	// as wide as its content.
+	Width int
+	// Min is the width below which the column is not shrunk to fit the
+	// line to the width. 0 lets the column be dropped altogether.
+	Min int
+	// Priority orders the shrinking of columns when a line is too wide:
+	// columns with a lower priority are shrunk first.
+	Priority int
+}
+
+// DefaultColumns is the layout set by HYPERLINKED_LAYOUT=columns: the
+// process label, if set with SetProcessLabel, the timestamp, as wide as
+// the precision set by SetPrecision needs, goroutine, tag, file:line and
+// message of each line. When a
+// line is too wide, the message is cut down to 20 columns first, so that
+// the other columns stay aligned, and then the location and the goroutine
+// are dropped.
+var DefaultColumns = []Column{
+	{Name: ColumnProcess, Priority: 5},
+	{Name: ColumnTime, Min: 7, Priority: 3},
+	{Name: ColumnGoroutine, Width: 5, Priority: 2},
+	{Name: ColumnTag, Width: 2, Min: 2, Priority: 4},
+	{Name: ColumnLocation, Width: 16, Priority: 1},
+	{Name: ColumnMsg, Min: 20, Priority: 0},
+}
+
+// SetColumns lays out each line as the given columns separated by spaces,
+// in place of the free-form timestamp, tag and message, so that lines
+// read as a table. Columns share the width that lines are truncated to
+// (see Truncate), shrinking in order of priority when a line is too wide.
+// Continuation lines of multi-line messages are indented to the message
+// column. Calling SetColumns with no columns restores the free-form
+// layout.
+func SetColumns(cols ...Column) {
+	if len(cols) == 0 {
+		cols = nil
+	} else {
+		cols = append([]Column(nil), cols...)
+	}
+	Configure(func(s *Settings) { s.Columns = cols })
+}
+
+// layoutColumns renders e, with message msg, in the columns cols, shrunk to
+// fit width if it is positive.
+func layoutColumns(cols []Column, e Entry, msg string, width int) string {
+	msg, hasNewline := strings.CutSuffix(msg, "\n")
+	cells := make([][]string, len(cols))
+	widths := make([]int, len(cols))
+	for i, c := range cols {
+		cells[i] = strings.Split(columnText(c.Name, e, msg), "\n")
+		widths[i] = c.Width
+		if c.Width == 0 {
+			for _, line := range cells[i] {
+				widths[i] = max(widths[i], visibleWidth(line))
+			}
+		}
+	}
+	if width > 0 {
+		shrinkColumns(cols, widths, width)
+	}
+
+	// Only the message can have more than one line; its continuation
+	// lines are padded with empty cells in the other columns.
+	var b strings.Builder
+	for n := 0; ; n++ {
+		more := false
+		var line []string
+		for i := range cols {
+			if widths[i] == 0 {
+				continue
+			}
+			cell := ""
+			if n < len(cells[i]) {
+				cell = cells[i][n]
+				more = more || n+1 < len(cells[i])
+			}
+			if visibleWidth(cell) > widths[i] {
+				cell = truncateToWidth(cell, widths[i])
+			}
+			line = append(line, cell+strings.Repeat(" ", widths[i]-visibleWidth(cell)))
+		}
+		if n > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(strings.TrimRight(strings.Join(line, " "), " "))
+		if !more {
+			break
+		}
+	}
+	if hasNewline {
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// shrinkColumns reduces widths, the widths of cols, in order of priority
+// until the columns and the spaces between them fit in width, or no column
+// can be shrunk further.
+func shrinkColumns(cols []Column, widths []int, width int) {
+	total := -1
+	for _, w := range widths {
+		if w > 0 {
+			total += w + 1
+		}
+	}
+	excess := total - width
+	if excess <= 0 {
+		return
+	}
+	order := make([]int, len(cols))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return cols[order[a]].Priority < cols[order[b]].Priority
+	})
+	for _, i := range order {
+		if excess <= 0 {
+			return
+		}
+		cut := min(excess, widths[i]-cols[i].Min)
+		if cut <= 0 {
+			continue
+		}
+		widths[i] -= cut
+		excess -= cut
+		if widths[i] == 0 {
+			// The separator goes with the column.
+			excess--
+		}
+	}
+}
+
+// columnText returns the content of the column named name for e.
+func columnText(name string, e Entry, msg string) string {
+	switch name {
+	case ColumnProcess:
+		return strings.TrimSuffix(string(appendProcessLabel(nil, e.Process)), " ")
+	case ColumnTime:
+		return string(CurrentTheme().Levels[e.Level].appendRender(nil, func(b []byte) []byte {
+			return appendTimestamp(b, e.Elapsed)
+		}))
+	case ColumnSeq:
+		if e.Seq == 0 {
+			return ""
+		}
+		return "#" + strconv.FormatUint(e.Seq, 10)
+	case ColumnGoroutine:
+		if e.Goroutine == 0 {
+			return ""
+		}
+		return "g" + strconv.FormatInt(e.Goroutine, 10)
+	case ColumnLabels:
+		return strings.TrimSuffix(string(appendLabels(nil, e.Labels)), " ")
+	case ColumnTag:
+		prefix := strings.TrimSuffix(e.Tag.prefix(), " ")
+		if e.Tag.Emoji() != "" {
+			// Emoji bring their own color.
+			return prefix
+		}
+		return CurrentTheme().Tags[e.Tag].Render(prefix)
+	case ColumnMsg:
+		return msg
+	case ColumnLocation:
+		if e.File == "" {
+			return ""
+		}
+		loc := DisplayPath(e.File) + ":" + strconv.Itoa(e.Line)
+		if deadLink(e.File, e.Line) {
+			// The mark goes first, so that cutting the column to its
+			// width does not cut it.
+			return CurrentTheme().Dim.Render("⚠ " + loc)
+		}
+		return loc
+	}
+	return ""
+}
@@ -0,0 +1,80 @@
+package ps
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Printf, Println and Print have the signatures and formatting of their
+// namesakes in package fmt, so that code can move from fmt to ps by
+// changing the package name alone:
+//
+//	sed -i 's/fmt\.Print/ps.Print/g' *.go
+//
+// Unlike those of fmt, they return nothing, as the line is written whole
+// or not at all.
+
+// Printf is F, under the name of fmt.Printf.
+func Printf(format string, args ...interface{}) {
+	std.printf(1, "", format, args)
+}
+
+// Println is Ln, under the name of fmt.Println.
+func Println(args ...interface{}) {
+	std.printf(1, "", printFormat(args, true), args)
+}
+
+// Print prints its arguments as fmt.Print does, separated by spaces where
+// neither side is a string.
+func Print(args ...interface{}) {
+	std.printf(1, "", printFormat(args, false), args)
+}
+
+// Printf is like the package-level Printf.
+func (p *Printer) Printf(format string, args ...interface{}) {
+	p.printf(1, "", format, args)
+}
+
+// Println is like the package-level Println.
+func (p *Printer) Println(args ...interface{}) {
+	p.printf(1, "", printFormat(args, true), args)
+}
+
+// Print is like the package-level Print.
+func (p *Printer) Print(args ...interface{}) {
+	p.printf(1, "", printFormat(args, false), args)
+}
+
+// printlnFormats holds the formats of Println for up to 8 arguments,
+// built once rather than at each call.
+var printlnFormats = func() (formats [9]string) {
+	for n := range formats {
+		formats[n] = strings.TrimSuffix(strings.Repeat("%v ", n), " ") + "\n"
+	}
+	return formats
+}()
+
+// printFormat returns the format that formats args as fmt.Sprintln does,
+// if ln, or else as fmt.Sprint does.
+func printFormat(args []interface{}, ln bool) string {
+	if ln && len(args) < len(printlnFormats) {
+		return printlnFormats[len(args)]
+	}
+	var b strings.Builder
+	for i, arg := range args {
+		if i > 0 && (ln || !isString(arg) && !isString(args[i-1])) {
+			b.WriteByte(' ')
+		}
+		b.WriteString("%v")
+	}
+	if ln {
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// isString reports whether arg is a string, of any string type, which
+// fmt.Sprint does not separate from its neighbours.
+func isString(arg interface{}) bool {
+	return arg != nil && reflect.TypeOf(arg).Kind() == reflect.String
+}
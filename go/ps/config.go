@@ -0,0 +1,420 @@
+package ps
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Settings are the settings read each time a line is printed. They are
+// replaced as a whole, so that they can be changed with Configure while
+// other goroutines print. Functions such as SetLevel and SetTheme change
+// them too. The state that is not a setting is kept apart: the output and
+// the sinks, which hold files and connections, the functions installed
+// with SetWidthFunc and SetErrorHandler, the fields of SetGlobalFields,
+// which are data of the entries, the themes registered with RegisterTheme,
+// which settings refer to by name, the labels of each goroutine, set by
+// Labeled, and the counts of printers, such as Seq.
+type Settings struct {
+	// LinkFormat controls the URL scheme for hyperlinks: "cursor" (the
+	// default), "wormhole", "vscode", "vscode-remote" (the default when
+	// the Environment has a remote authority), "file", or a
+	// comma-separated list of these in order of preference, such as
+	// "wormhole,vscode,file", of which the first available is used (see
+	// ResolvedLinkFormat). "githubdev" links to the github.dev web editor,
+	// at GitHubRepo, GitHubRef and GitHubRoot, for output from Codespaces
+	// or CI jobs. Set via HYPERLINKED_FORMAT env var, or SetLinkFormat,
+	// which validates it.
+	LinkFormat string
+	// Environment is where the program runs, as detected by
+	// DetectEnvironment.
+	Environment Environment
+	// PathMap rewrites the paths of source files in links, for programs
+	// running where the files are not at the paths the editor knows them
+	// by, such as in a container: a comma-separated list of from=to
+	// mappings, of which the first whose from is a prefix of the path is
+	// used, as in "/app=/home/me/src/app". Set via HYPERLINKED_PATH_MAP
+	// env var.
+	PathMap string
+	// Workspace is the root of the Bazel workspace the program was built
+	// in, which ResolveSource resolves the paths of Bazel's output and of
+	// relative paths against. Set via HYPERLINKED_WORKSPACE env var, or
+	// else BUILD_WORKSPACE_DIRECTORY, as set by bazel run.
+	Workspace string
+	// MissingSource is what links to code whose file, as reported by the
+	// runtime, does not exist, as for some code generated with //line
+	// directives and for cgo, link to: "function" (the default), the
+	// first line of the function containing the code, or that it is
+	// inlined into, if its file exists, which is often the generated
+	// file; or "keep", the file anyway. Set via HYPERLINKED_MISSING_SOURCE
+	// env var.
+	MissingSource string
+	// CheckLinks controls whether the file each line links to is checked
+	// to exist, as resolved by ResolveSource, before the line is printed.
+	// The location of lines whose link would be dead is shown dimmed and
+	// marked "⚠", at the end of the line or in the location column, so
+	// that a problem with the paths or their configuration is noticed
+	// before a link is clicked. Paths rewritten by PathMap are not
+	// checked, as they are for another machine. Set
+	// HYPERLINKED_CHECK_LINKS=1 to enable.
+	CheckLinks bool
+	// PathDisplay is how source files are shown where their paths appear
+	// as text, as in the location column and Stack: "base" (the default)
+	// for the base name, "short" for the path from the base name of the
+	// root of the file's module or of Workspace, or else with the home
+	// directory abbreviated to "~", or "full" for the whole path. Set via
+	// HYPERLINKED_PATH_DISPLAY env var.
+	PathDisplay string
+	// StdlibLinks is where files of the standard library, such as those of
+	// Stack frames in the runtime, link to: "local" (the default), the
+	// sources in the local GOROOT, including for paths trimmed by
+	// -trimpath, or "web", the sources of the Go version the program was
+	// built with on cs.opensource.google, for when the local GOROOT is not
+	// where the editor can open it, or is missing. Set via
+	// HYPERLINKED_STDLIB_LINKS env var.
+	StdlibLinks string
+	// Banner controls whether a line identifying the build of the program,
+	// as printed by Banner, is printed before the first line. Set
+	// HYPERLINKED_BANNER=1 to enable.
+	Banner bool
+	// Stats controls whether the lines written and the time spent looking
+	// up call sites, formatting and writing are counted, as returned by
+	// Stats, at the cost of reading the clock several times a line. Set
+	// HYPERLINKED_STATS=1 to enable.
+	Stats bool
+	// ShareLines is the number of the last lines printed kept for Share,
+	// or 0 to keep none. Keeping lines costs an allocation for each line
+	// printed. Set via HYPERLINKED_SHARE_LINES env var.
+	ShareLines int
+	// ShowGlobalFields controls whether the fields set by SetGlobalFields
+	// are shown, dimmed, at the end of each line, as they are always
+	// recorded for sinks. Set HYPERLINKED_SHOW_GLOBAL=1 to enable.
+	ShowGlobalFields bool
+	// MaxRate caps the lines printed per second, or is 0 for no cap. Once
+	// a second's lines exceed it, further lines that second are not
+	// printed, and at the end of it each call site whose lines were
+	// suppressed is summarized instead, as in "x.go:42 emitted 1,240 lines
+	// in 2s — suppressed", until its lines subside, so that a runaway loop
+	// does not flood an interactive terminal. Set via HYPERLINKED_MAX_RATE
+	// env var.
+	MaxRate int
+	// MaxDepth limits the levels of nesting of structs, maps, slices and
+	// arrays that arguments formatted with %v and %+v are expanded to, or
+	// is 0 for no limit, with the values beyond it shown as "{…}". While
+	// it or MaxArgLength is set, such arguments are formatted safely:
+	// pointers are followed at every level, as they are by fmt only at the
+	// top, and values that contain themselves, as through a
+	// self-referencing struct or a slice holding itself, are shown as
+	// "<cycle>" rather than expanded without end. Set via
+	// HYPERLINKED_MAX_DEPTH env var.
+	MaxDepth int
+	// MaxArgLength limits the bytes that each argument formatted safely,
+	// as for MaxDepth, expands to, or is 0 for no limit, with the rest
+	// shown as "…". Set via HYPERLINKED_MAX_ARG_LENGTH env var.
+	MaxArgLength int
+	// MaxMessageSize limits the bytes of the message of each line, or is 0
+	// for no limit. A larger message is written to a temporary file, and
+	// the line shows "⬇ wrote 2.3MB payload to /tmp/ps-1234.txt" in its
+	// place, linked to the file, as do the entries passed to sinks. Set
+	// via HYPERLINKED_MAX_MESSAGE env var.
+	MaxMessageSize int
+	// GitHubRepo is the GitHub repository, as "owner/name", that the
+	// "githubdev" link format links to. Set via HYPERLINKED_GITHUB_REPO
+	// env var, or else GITHUB_REPOSITORY, as set by GitHub Actions and
+	// Codespaces; if empty, the origin of the git checkout in the working
+	// directory is used.
+	GitHubRepo string
+	// GitHubRef is the branch, tag or commit that the "githubdev" link
+	// format links to. Set via HYPERLINKED_GITHUB_REF env var, or else
+	// GITHUB_HEAD_REF or GITHUB_REF_NAME, as set by GitHub Actions; if
+	// empty, the branch, or commit if detached, of the git checkout in the
+	// working directory is used.
+	GitHubRef string
+	// GitHubRoot is the directory of the checkout of GitHubRepo that
+	// source files are linked relative to by the "githubdev" link format.
+	// Files outside it are linked with "file" URLs. Set via
+	// HYPERLINKED_GITHUB_ROOT env var, or else GITHUB_WORKSPACE or
+	// CODESPACE_VSCODE_FOLDER; if empty, the root of the git checkout in
+	// the working directory is used.
+	GitHubRoot string
+	// WormholeAddr is the host:port of the wormhole server opening links
+	// of the "wormhole" format. Set via HYPERLINKED_WORMHOLE.
+	WormholeAddr string
+	// ProbeWormhole controls whether a LinkFormat of "wormhole" is checked
+	// on first use: if the wormhole server does not accept connections,
+	// links use "cursor", or "vscode" if only VS Code is installed,
+	// instead, and a line saying so is written to stderr. Set
+	// HYPERLINKED_PROBE=1 to enable.
+	ProbeWormhole bool
+	// Terminal is the profile of the terminal output is written to. It is
+	// detected from TERM, TERM_PROGRAM and terminal-specific environment
+	// variables, or set via HYPERLINKED_TERMINAL env var.
+	Terminal Terminal
+	// MaxURLLength is the length in bytes of the longest URL to link to,
+	// or 0 to use only the limit of Terminal. Text whose link would be
+	// longer is printed unlinked. Set via HYPERLINKED_MAX_URL env var.
+	MaxURLLength int
+	// Truncate controls whether output is truncated to terminal width.
+	// Set HYPERLINKED_NO_TRUNCATE=1 to disable.
+	Truncate bool
+	// Ellipsis marks where a line truncated to the terminal width was cut.
+	// "%d" in it is replaced by the number of characters cut, as in
+	// "[+%d chars]". The default is "…". Set via HYPERLINKED_ELLIPSIS env
+	// var.
+	Ellipsis string
+	// AmbiguousWidth is the number of columns, 1 or 2, that characters of
+	// ambiguous East Asian width, such as "…" and "→", are counted as when
+	// lines are truncated and aligned, as they are drawn two wide by
+	// terminals set up for East Asian text. The default is 2 in East Asian
+	// locales, and otherwise 1. Set via HYPERLINKED_AMBIGUOUS_WIDTH env
+	// var.
+	AmbiguousWidth int
+	// EmojiWidth is the number of columns, 1 or 2, that emoji made of a
+	// character and the variation selector U+FE0F, such as "⚙️", and
+	// sequences of emoji, such as flags, are counted as. The default is 2,
+	// as most terminals draw them; set it to 1 for those that ignore the
+	// selector. Set via HYPERLINKED_EMOJI_WIDTH env var.
+	EmojiWidth int
+	// AlignContinuation controls whether the continuation lines of
+	// multi-line messages are indented to align under the start of the
+	// message, after the timestamp column. Set HYPERLINKED_NO_ALIGN=1 to
+	// disable.
+	AlignContinuation bool
+	// TagColumn is the width of the column reserved for the tag of each
+	// line, after the timestamp, or 0 for none. Lines with shorter tags or
+	// none are padded to it, so that their messages line up; 2 fits the
+	// emoji of the built-in tags. Set via HYPERLINKED_TAG_COLUMN env var.
+	TagColumn int
+	// Sanitize controls whether the control characters of messages, such
+	// as those of a raw buffer dumped into one, are shown escaped, as in
+	// "\x07", rather than written to the terminal, where they could
+	// corrupt its state. Newlines and tabs are kept, and so are the
+	// escape sequences that style and link text in the format and in
+	// values of this package, such as those of Link; those in other
+	// arguments, which are data, are escaped. Set
+	// HYPERLINKED_NO_SANITIZE=1 to disable.
+	Sanitize bool
+	// NoTimerFormat is the format of times passed to Relative and
+	// RelativeMs when no timer has been started: "rfc3339" (the default)
+	// for time.RFC3339Nano, or "unixms" for milliseconds since the Unix
+	// epoch. Set via HYPERLINKED_NO_TIMER_FORMAT env var.
+	NoTimerFormat string
+	// NotifyOnFailure attaches a desktop notification to lines tagged
+	// Failure: "" (the default, never), "first" (the first failure only)
+	// or "all". Set via HYPERLINKED_NOTIFY env var.
+	NotifyOnFailure string
+	// NotifyStyle selects the notification escape sequence: "osc9" (the
+	// default; iTerm2, WezTerm, Windows Terminal), "osc777" (foot,
+	// Ghostty, rxvt-unicode) or "osc99" (kitty). Set via
+	// HYPERLINKED_NOTIFY_STYLE env var.
+	NotifyStyle string
+	// Marks selects the escape sequences Section emits so that terminals
+	// with shell integration can jump between sections as they do between
+	// prompts: "" (the default, none), "osc133" (FinalTerm semantic
+	// prompts, as used by WezTerm, kitty, VS Code, Windows Terminal and
+	// others) or "iterm2". Set via HYPERLINKED_MARKS env var.
+	Marks string
+	// Title selects how the current Section is shown outside the output,
+	// so that long runs show their status while the window is in the
+	// background: "" (the default, not at all), "title" (as the window or
+	// tab title), "progress" (with the progress indicator of terminals
+	// supporting OSC 9;4, which Progress also sets) or "both". Set via
+	// HYPERLINKED_TITLE env var.
+	Title string
+	// ResultStack is the number of stack frames Result prints after a
+	// failure. The default, 0, prints none. Set via
+	// HYPERLINKED_RESULT_STACK env var.
+	ResultStack int
+	// MaxDeferred is the number of deferred lines kept until they are
+	// flushed or discarded. Beyond it the oldest are dropped. Set via
+	// HYPERLINKED_MAX_DEFERRED env var.
+	MaxDeferred int
+	// MaxMessages is the number of messages kept for
+	// WriteSequenceDiagram. Beyond it further messages are counted but not
+	// kept.
+	MaxMessages int
+	// WatchInterval is how often WatchFile checks for changes. The default
+	// is 100ms; 0 or less is taken as the default.
+	WatchInterval time.Duration
+	// TraceURLTemplate is the URL of a trace in the tracing UI, such as
+	// Jaeger or Grafana Tempo, with "{trace_id}" in place of the trace ID,
+	// as in "http://jaeger:16686/trace/{trace_id}". When it is set, lines
+	// with a TraceField field end with a link to their trace, and TraceLink
+	// links to traces. Set via HYPERLINKED_TRACE_URL_TEMPLATE env var.
+	TraceURLTemplate string
+	// TraceField is the key of the fields holding trace IDs. The default
+	// is "trace_id". Set via HYPERLINKED_TRACE_FIELD env var.
+	TraceField string
+	// RedactKeys are the substrings of names, matched ignoring case, whose
+	// values Env and Config print as "[redacted]".
+	RedactKeys []string
+	// Level is the level below which entries are suppressed. The default
+	// is LevelDebug, printing everything. Set via HYPERLINKED_LEVEL env
+	// var, e.g. HYPERLINKED_LEVEL=warn, or SetLevel.
+	Level Level
+	// filter is the filter set by SetFilter, which parses it, or nil.
+	filter *callFilter
+	// SampleRate is the fraction of the hits at each call site printed by
+	// printers without a rate of their own (see Sample). The default, 1,
+	// prints everything. Set via HYPERLINKED_SAMPLE env var, e.g.
+	// HYPERLINKED_SAMPLE=0.1, or SetSampleRate.
+	SampleRate float64
+	// Precision is the resolution of the timestamp column, as described
+	// by SetPrecision. Set via HYPERLINKED_PRECISION env var.
+	Precision time.Duration
+	// Columns is the layout set by SetColumns, or nil for the free-form
+	// layout. Set HYPERLINKED_LAYOUT=columns for DefaultColumns.
+	Columns []Column
+	// Theme is the name of the theme selected by SetTheme, which checks
+	// that it is registered. Set via HYPERLINKED_THEME env var.
+	Theme string
+	// LabelKeys are the keys of the labels shown, as set by ShowLabels, or
+	// nil for all. Set via HYPERLINKED_LABELS env var.
+	LabelKeys []string
+	// ProcessLabel is the label of the process shown at the start of each
+	// line, as set by SetProcessLabel, or "" for none. The default is
+	// DefaultProcessLabel.
+	ProcessLabel string
+	// Trace is whether printing also emits runtime/trace events, as set
+	// by SetTrace. Set HYPERLINKED_TRACE=1 to enable.
+	Trace bool
+}
+
+// current holds the settings in effect.
+var current atomic.Pointer[Settings]
+
+// init sets up the package from the environment. It is the only init
+// function of the package, so that the report of HYPERLINKED_DEBUG=1
+// comes after all of the setup that it reports, whatever the order in
+// which Go runs the init functions of the files.
+func init() {
+	s := envSettings()
+	current.Store(&s)
+	auditFromEnv()
+	seqFromEnv()
+	if os.Getenv("HYPERLINKED_DEBUG") == "1" {
+		fmt.Fprint(os.Stderr, ConfigReport())
+	}
+}
+
+// envSettings returns the settings given by the environment.
+func envSettings() Settings {
+	env := DetectEnvironment()
+	s := Settings{
+		LinkFormat:        getEnvDefault("HYPERLINKED_FORMAT", defaultLinkFormat(env)),
+		Environment:       env,
+		PathMap:           os.Getenv("HYPERLINKED_PATH_MAP"),
+		Workspace:         cmp.Or(os.Getenv("HYPERLINKED_WORKSPACE"), os.Getenv("BUILD_WORKSPACE_DIRECTORY")),
+		MissingSource:     getEnvDefault("HYPERLINKED_MISSING_SOURCE", MissingSourceFunction),
+		CheckLinks:        os.Getenv("HYPERLINKED_CHECK_LINKS") == "1",
+		PathDisplay:       getEnvDefault("HYPERLINKED_PATH_DISPLAY", PathDisplayBase),
+		StdlibLinks:       getEnvDefault("HYPERLINKED_STDLIB_LINKS", StdlibLinksLocal),
+		Banner:            os.Getenv("HYPERLINKED_BANNER") == "1",
+		Stats:             os.Getenv("HYPERLINKED_STATS") == "1",
+		ShowGlobalFields:  os.Getenv("HYPERLINKED_SHOW_GLOBAL") == "1",
+		GitHubRepo:        cmp.Or(os.Getenv("HYPERLINKED_GITHUB_REPO"), os.Getenv("GITHUB_REPOSITORY")),
+		GitHubRef:         cmp.Or(os.Getenv("HYPERLINKED_GITHUB_REF"), os.Getenv("GITHUB_HEAD_REF"), os.Getenv("GITHUB_REF_NAME")),
+		GitHubRoot:        cmp.Or(os.Getenv("HYPERLINKED_GITHUB_ROOT"), os.Getenv("GITHUB_WORKSPACE"), os.Getenv("CODESPACE_VSCODE_FOLDER")),
+		WormholeAddr:      getEnvDefault("HYPERLINKED_WORMHOLE", "wormhole:7117"),
+		ProbeWormhole:     os.Getenv("HYPERLINKED_PROBE") == "1",
+		Terminal:          detectTerminal(),
+		Truncate:          os.Getenv("HYPERLINKED_NO_TRUNCATE") == "",
+		Ellipsis:          getEnvDefault("HYPERLINKED_ELLIPSIS", "…"),
+		AmbiguousWidth:    1,
+		EmojiWidth:        2,
+		AlignContinuation: os.Getenv("HYPERLINKED_NO_ALIGN") == "",
+		Sanitize:          os.Getenv("HYPERLINKED_NO_SANITIZE") == "",
+		NoTimerFormat:     getEnvDefault("HYPERLINKED_NO_TIMER_FORMAT", "rfc3339"),
+		NotifyOnFailure:   os.Getenv("HYPERLINKED_NOTIFY"),
+		NotifyStyle:       getEnvDefault("HYPERLINKED_NOTIFY_STYLE", "osc9"),
+		Marks:             os.Getenv("HYPERLINKED_MARKS"),
+		Title:             os.Getenv("HYPERLINKED_TITLE"),
+		TraceURLTemplate:  os.Getenv("HYPERLINKED_TRACE_URL_TEMPLATE"),
+		TraceField:        getEnvDefault("HYPERLINKED_TRACE_FIELD", "trace_id"),
+		MaxDeferred:       10000,
+		MaxMessages:       10000,
+		WatchInterval:     defaultWatchInterval,
+		RedactKeys:        []string{"password", "passwd", "secret", "token", "key", "credential", "auth"},
+		Level:             LevelDebug,
+		SampleRate:        1,
+		Precision:         time.Millisecond,
+		Theme:             getEnvDefault("HYPERLINKED_THEME", "dark"),
+		ProcessLabel:      DefaultProcessLabel(),
+		Trace:             os.Getenv("HYPERLINKED_TRACE") == "1",
+	}
+	s.MaxURLLength, _ = strconv.Atoi(os.Getenv("HYPERLINKED_MAX_URL"))
+	s.ResultStack, _ = strconv.Atoi(os.Getenv("HYPERLINKED_RESULT_STACK"))
+	s.ShareLines, _ = strconv.Atoi(os.Getenv("HYPERLINKED_SHARE_LINES"))
+	if runewidth.IsEastAsian() {
+		s.AmbiguousWidth = 2
+	}
+	if n, err := strconv.Atoi(os.Getenv("HYPERLINKED_AMBIGUOUS_WIDTH")); err == nil && (n == 1 || n == 2) {
+		s.AmbiguousWidth = n
+	}
+	if os.Getenv("HYPERLINKED_EMOJI_WIDTH") == "1" {
+		s.EmojiWidth = 1
+	}
+	s.MaxRate, _ = strconv.Atoi(os.Getenv("HYPERLINKED_MAX_RATE"))
+	s.TagColumn, _ = strconv.Atoi(os.Getenv("HYPERLINKED_TAG_COLUMN"))
+	s.MaxDepth, _ = strconv.Atoi(os.Getenv("HYPERLINKED_MAX_DEPTH"))
+	s.MaxArgLength, _ = strconv.Atoi(os.Getenv("HYPERLINKED_MAX_ARG_LENGTH"))
+	s.MaxMessageSize, _ = strconv.Atoi(os.Getenv("HYPERLINKED_MAX_MESSAGE"))
+	if n, err := strconv.Atoi(os.Getenv("HYPERLINKED_MAX_DEFERRED")); err == nil && n > 0 {
+		s.MaxDeferred = n
+	}
+	// An invalid level, filter or rate is ignored, printing everything.
+	if l, err := ParseLevel(os.Getenv("HYPERLINKED_LEVEL")); err == nil {
+		s.Level = l
+	}
+	s.filter, _ = parseFilter(os.Getenv("HYPERLINKED_FILTER"))
+	if r, err := strconv.ParseFloat(os.Getenv("HYPERLINKED_SAMPLE"), 64); err == nil && r > 0 && r <= 1 {
+		s.SampleRate = r
+	}
+	switch os.Getenv("HYPERLINKED_PRECISION") {
+	case "us":
+		s.Precision = time.Microsecond
+	case "ns":
+		s.Precision = time.Nanosecond
+	}
+	if os.Getenv("HYPERLINKED_LAYOUT") == "columns" {
+		s.Columns = slices.Clone(DefaultColumns)
+	}
+	if keys := os.Getenv("HYPERLINKED_LABELS"); keys != "" {
+		s.LabelKeys = strings.Split(keys, ",")
+	}
+	return s
+}
+
+// CurrentSettings returns the settings in effect.
+func CurrentSettings() Settings {
+	return *current.Load()
+}
+
+// Configure changes the settings in effect with f, which is passed a copy
+// of them. It is safe to call concurrently with printing and with other
+// calls of Configure, but f may be called more than once if they race, and
+// must replace rather than modify slices such as RedactKeys:
+//
+//	ps.Configure(func(s *ps.Settings) { s.Truncate = false })
+func Configure(f func(s *Settings)) {
+	for {
+		cur := current.Load()
+		next := *cur
+		f(&next)
+		if current.CompareAndSwap(cur, &next) {
+			return
+		}
+	}
+}
+
+// cfg returns the settings in effect, without copying them.
+func cfg() *Settings {
+	return current.Load()
+}
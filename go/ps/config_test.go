@@ -0,0 +1,64 @@
+package ps
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestSettersConfigure checks that the setters change the settings, so
+// that they are restored, read and reported with the others.
+func TestSettersConfigure(t *testing.T) {
+	configure(t, func(s *Settings) {})
+	SetLevel(LevelWarn)
+	if err := SetFilter("server/*.go"); err != nil {
+		t.Fatal(err)
+	}
+	SetSampleRate(0.5)
+	SetPrecision(5 * time.Microsecond)
+	SetColumns(DefaultColumns...)
+	if err := SetTheme("light"); err != nil {
+		t.Fatal(err)
+	}
+	ShowLabels("request")
+	SetProcessLabel("worker")
+	SetTrace(true)
+
+	s := CurrentSettings()
+	if s.Level != LevelWarn || MinLevel() != LevelWarn {
+		t.Errorf("Level = %v, want warn", s.Level)
+	}
+	if Filter() != "server/*.go" {
+		t.Errorf("Filter() = %q, want %q", Filter(), "server/*.go")
+	}
+	if s.SampleRate != 0.5 {
+		t.Errorf("SampleRate = %v, want 0.5", s.SampleRate)
+	}
+	if Precision() != time.Microsecond {
+		t.Errorf("Precision() = %v, want 1µs", Precision())
+	}
+	if len(s.Columns) != len(DefaultColumns) {
+		t.Errorf("Columns = %v, want DefaultColumns", s.Columns)
+	}
+	if s.Theme != "light" || CurrentTheme().Name != "light" {
+		t.Errorf("Theme = %q, want light", s.Theme)
+	}
+	if !slices.Equal(s.LabelKeys, []string{"request"}) {
+		t.Errorf("LabelKeys = %q, want [request]", s.LabelKeys)
+	}
+	if s.ProcessLabel != "worker" {
+		t.Errorf("ProcessLabel = %q, want worker", s.ProcessLabel)
+	}
+	if !s.Trace {
+		t.Error("Trace = false, want true")
+	}
+
+	if err := SetTheme("no such theme"); err == nil {
+		t.Error("SetTheme of an unknown theme succeeded")
+	}
+	SetColumns()
+	ShowLabels()
+	if s := CurrentSettings(); s.Columns != nil || s.LabelKeys != nil {
+		t.Errorf("Columns = %v, LabelKeys = %q after resetting, want nil", s.Columns, s.LabelKeys)
+	}
+}
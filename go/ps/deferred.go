@@ -0,0 +1,95 @@
+package ps
+
+import (
+	"fmt"
+	"sync"
+)
+
+// deferredLine is a line recorded by Defer, rendered when flushed.
+type deferredLine struct {
+	p      *Printer
+	site   callSite
+	entry  Entry
+	format string
+	args   []interface{}
+}
+
+var (
+	deferredMu sync.Mutex
+	// deferredLines is a ring buffer once it holds MaxDeferred lines, with
+	// the oldest at deferredNext.
+	deferredLines   []deferredLine
+	deferredNext    int
+	deferredDropped int
+)
+
+// Defer records a line to print later, like F. Only the caller and the
+// time are captured; formatting, linking and printing happen when
+// FlushDeferred is called, and not at all if DiscardDeferred is called
+// instead. This makes it cheap to instrument code heavily and only see
+// the output when something goes wrong:
+//
+//	ps.Defer("state: %v\n", state)
+//	...
+//	if err != nil {
+//		ps.FlushDeferred()
+//	}
+//
+// The arguments are formatted when flushed, so pointers and other mutable
+// values show their state at that time.
+func Defer(format string, args ...interface{}) {
+	std.deferf(1, format, args)
+}
+
+// Defer is like the package-level Defer.
+func (p *Printer) Defer(format string, args ...interface{}) {
+	p.deferf(1, format, args)
+}
+
+func (p *Printer) deferf(skip int, format string, args []interface{}) {
+	if !p.prints("") {
+		return
+	}
+	d := deferredLine{p: p, site: p.callSite(skip + 1), entry: newEntry(""), format: format, args: args}
+
+	deferredMu.Lock()
+	defer deferredMu.Unlock()
+	if limit := cfg().MaxDeferred; limit <= 0 || len(deferredLines) < limit {
+		deferredLines = append(deferredLines, d)
+		return
+	}
+	deferredLines[deferredNext] = d
+	deferredNext = (deferredNext + 1) % len(deferredLines)
+	deferredDropped++
+}
+
+// FlushDeferred prints the lines recorded by Defer, in the order they were
+// recorded, with the times they were recorded at.
+func FlushDeferred() {
+	lines, dropped := takeDeferred()
+	if dropped > 0 {
+		write(CurrentTheme().Dim.Render(fmt.Sprintf("(deferred: %d dropped)", dropped)) + "\n")
+	}
+	for _, d := range lines {
+		d.p.printAt(d.site, d.entry, d.format, d.args)
+	}
+}
+
+// DiscardDeferred drops the lines recorded by Defer without printing them.
+func DiscardDeferred() {
+	takeDeferred()
+}
+
+// takeDeferred removes and returns the recorded lines and the number
+// dropped since the last call.
+func takeDeferred() ([]deferredLine, int) {
+	deferredMu.Lock()
+	defer deferredMu.Unlock()
+	lines := deferredLines
+	if deferredNext > 0 {
+		lines = append(append([]deferredLine(nil), lines[deferredNext:]...), lines[:deferredNext]...)
+	}
+	dropped := deferredDropped
+	deferredLines, deferredNext, deferredDropped = nil, 0, 0
+	return lines, dropped
+}
@@ -0,0 +1,88 @@
+package ps
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Styles for Settings.PathDisplay.
+const (
+	// PathDisplayBase shows the base name of the file.
+	PathDisplayBase = "base"
+	// PathDisplayShort shows the path from the base name of the root of
+	// the file's module, or of Settings.Workspace, or else the path with
+	// the home directory abbreviated to "~".
+	PathDisplayShort = "short"
+	// PathDisplayFull shows the path as it is.
+	PathDisplayFull = "full"
+)
+
+// DisplayPath returns file as shown in visible text, such as the location
+// column, the locations of Stack and the rows of the viewer, in the style
+// set by Settings.PathDisplay.
+func DisplayPath(file string) string {
+	switch cfg().PathDisplay {
+	case PathDisplayFull:
+		return file
+	case PathDisplayShort:
+		return shortPath(file)
+	}
+	return filepath.Base(file)
+}
+
+// shortPath returns file in the style of PathDisplayShort.
+func shortPath(file string) string {
+	if ws := cfg().Workspace; ws != "" {
+		if rel, ok := under(ws, file); ok {
+			return filepath.Join(filepath.Base(ws), rel)
+		}
+	}
+	if root := moduleRoot(filepath.Dir(file)); root != "" {
+		if rel, ok := under(root, file); ok {
+			return filepath.Join(filepath.Base(root), rel)
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		if rel, ok := under(home, file); ok {
+			return filepath.Join("~", rel)
+		}
+	}
+	return file
+}
+
+// under returns the path of file relative to dir, if it is in dir.
+func under(dir, file string) (string, bool) {
+	rel, err := filepath.Rel(dir, file)
+	if err != nil || !filepath.IsLocal(rel) || !filepath.IsAbs(file) {
+		return "", false
+	}
+	return rel, true
+}
+
+var (
+	moduleRootsMu sync.RWMutex
+	// moduleRoots caches the module root of each directory looked up, ""
+	// for directories in no module.
+	moduleRoots = map[string]string{}
+)
+
+// moduleRoot returns the nearest directory containing go.mod of dir and
+// its parents, or "" if there is none.
+func moduleRoot(dir string) string {
+	moduleRootsMu.RLock()
+	root, ok := moduleRoots[dir]
+	moduleRootsMu.RUnlock()
+	if ok {
+		return root
+	}
+	if fileExists(filepath.Join(dir, "go.mod")) {
+		root = dir
+	} else if parent := filepath.Dir(dir); parent != dir {
+		root = moduleRoot(parent)
+	}
+	moduleRootsMu.Lock()
+	moduleRoots[dir] = root
+	moduleRootsMu.Unlock()
+	return root
+}
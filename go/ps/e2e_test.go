@@ -0,0 +1,192 @@
+package ps
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// e2eDir is the directory testdata/e2e is built in, removed by TestMain.
+var e2eDir string
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if e2eDir != "" {
+		os.RemoveAll(e2eDir)
+	}
+	os.Exit(code)
+}
+
+// e2eProgram builds testdata/e2e once for the tests and returns the path
+// of the binary.
+var e2eProgram = sync.OnceValues(func() (string, error) {
+	dir, err := os.MkdirTemp("", "ps-e2e-")
+	if err != nil {
+		return "", err
+	}
+	e2eDir = dir
+	bin := filepath.Join(dir, "e2e")
+	out, err := exec.Command("go", "build", "-o", bin, "./testdata/e2e").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%v\n%s", err, out)
+	}
+	return bin, nil
+})
+
+// TestEndToEnd runs testdata/e2e in controlled environments and checks the
+// bytes it writes, escape sequences included, so that the contract with
+// terminals is kept across refactors. Expected output is written with
+// {file} for the path of testdata/e2e/main.go.
+func TestEndToEnd(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a program")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+	bin, err := e2eProgram()
+	if err != nil {
+		t.Fatalf("building testdata/e2e: %v", err)
+	}
+	file, err := filepath.Abs("testdata/e2e/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const end = "\x1b]8;;\x1b\\"
+	// label is the process label column, the name of the program.
+	const label = "\x1b[35me2e       \x1b[0m "
+	link := func(url string) string { return "\x1b]8;;" + url + "\x1b\\" }
+	for _, tt := range []struct {
+		name string
+		env  []string
+		arg  string
+		want string
+	}{
+		{"F", nil, "f",
+			link("cursor://file/{file}:13") + label + "[    0] hello world\n" + end},
+		{"Ln", nil, "ln",
+			link("cursor://file/{file}:16") + label + "[    0] answer 42\n" + end},
+		{"T", nil, "tag",
+			link("cursor://file/{file}:19") + label + "[    0] ✅ done\n" + end},
+		{"multiline", nil, "multiline",
+			link("cursor://file/{file}:25") + label + "[    0] first\n                   second\n" + end},
+		{"control characters", nil, "bell",
+			link("cursor://file/{file}:28") + label + "[    0] bell\\x07 \\x1b[2J\n" + end},
+		{"escape sequences in arguments", nil, "inject",
+			link("cursor://file/{file}:31") + label + `[    0] dump: \x1b[31mred \x1b]8;;https://evil.example\x1b\link\x1b]8;;\x1b\` + "\n" + end},
+		{"Link", nil, "link",
+			link("cursor://file/{file}:34") + label + "[    0] see " + link("https://example.com") + "docs" + link("cursor://file/{file}:34") + "\n" + end},
+		{"HYPERLINKED_FORMAT=cursor", []string{"HYPERLINKED_FORMAT=cursor"}, "f",
+			link("cursor://file/{file}:13") + label + "[    0] hello world\n" + end},
+		{"HYPERLINKED_FORMAT=vscode", []string{"HYPERLINKED_FORMAT=vscode"}, "f",
+			link("vscode://file/{file}:13") + label + "[    0] hello world\n" + end},
+		{"HYPERLINKED_FORMAT=file", []string{"HYPERLINKED_FORMAT=file"}, "f",
+			link("file://{file}") + label + "[    0] hello world\n" + end},
+		{"HYPERLINKED_FORMAT=wormhole", []string{"HYPERLINKED_FORMAT=wormhole", "HYPERLINKED_WORMHOLE=localhost:7117"}, "f",
+			link("http://localhost:7117/file/{file}:13?land-in=editor") + label + "[    0] hello world\n" + end},
+		// VS Code is not found without PATH, so the list falls back.
+		{"HYPERLINKED_FORMAT list", []string{"HYPERLINKED_FORMAT=vscode,file"}, "f",
+			link("file://{file}") + label + "[    0] hello world\n" + end},
+		{"HYPERLINKED_TERMINAL=none", []string{"HYPERLINKED_TERMINAL=none"}, "f",
+			label + "[    0] hello world\n"},
+		{"HYPERLINKED_COLUMNS", []string{"HYPERLINKED_COLUMNS=31"}, "long",
+			link("cursor://file/{file}:22") + label + "[    0] 0123456789…\n" + end},
+		{"HYPERLINKED_ELLIPSIS", []string{"HYPERLINKED_COLUMNS=31", "HYPERLINKED_ELLIPSIS=>"}, "long",
+			link("cursor://file/{file}:22") + label + "[    0] 0123456789>\n" + end},
+		{"HYPERLINKED_NO_TRUNCATE", []string{"HYPERLINKED_COLUMNS=31", "HYPERLINKED_NO_TRUNCATE=1"}, "long",
+			link("cursor://file/{file}:22") + label + "[    0] 0123456789abcdefghijklmnopqrstuvwxyz\n" + end},
+		{"HYPERLINKED_SEQ", []string{"HYPERLINKED_SEQ=1"}, "f",
+			link("cursor://file/{file}:13") + label + "[    0] #1 hello world\n" + end},
+		{"HYPERLINKED_PRECISION", []string{"HYPERLINKED_PRECISION=us"}, "f",
+			link("cursor://file/{file}:13") + label + "[    0.000] hello world\n" + end},
+		{"HYPERLINKED_TAG_COLUMN", []string{"HYPERLINKED_TAG_COLUMN=3"}, "f",
+			link("cursor://file/{file}:13") + label + "[    0]     hello world\n" + end},
+		{"HYPERLINKED_LABEL", []string{"HYPERLINKED_LABEL=server-1"}, "f",
+			link("cursor://file/{file}:13") + "\x1b[33mserver-1  \x1b[0m [    0] hello world\n" + end},
+		{"HYPERLINKED_LABEL empty", []string{"HYPERLINKED_LABEL="}, "f",
+			link("cursor://file/{file}:13") + "[    0] hello world\n" + end},
+		{"HYPERLINKED_NO_SANITIZE", []string{"HYPERLINKED_NO_SANITIZE=1"}, "bell",
+			link("cursor://file/{file}:28") + label + "[    0] bell\a \x1b[2J\n" + end},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := exec.Command(bin, tt.arg)
+			// Only the variables of the test, so that neither the terminal
+			// nor the settings of whoever runs the tests are detected.
+			cmd.Env = append([]string{"HOME=" + t.TempDir(), "HYPERLINKED_TERMINAL=generic"}, tt.env...)
+			got, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("e2e %s: %v", tt.arg, err)
+			}
+			want := strings.ReplaceAll(tt.want, "{file}", file)
+			if string(got) != want {
+				t.Errorf("e2e %s wrote\n%q\nwant\n%q", tt.arg, got, want)
+			}
+		})
+	}
+}
+
+// TestDebugReport checks that the report printed at startup by
+// HYPERLINKED_DEBUG=1 shows the settings of the environment, including
+// those set up after the settings themselves.
+func TestDebugReport(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a program")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+	bin, err := e2eProgram()
+	if err != nil {
+		t.Fatalf("building testdata/e2e: %v", err)
+	}
+	cmd := exec.Command(bin, "f")
+	cmd.Env = []string{"HOME=" + t.TempDir(), "HYPERLINKED_TERMINAL=generic", "HYPERLINKED_DEBUG=1",
+		"HYPERLINKED_LEVEL=warn", "HYPERLINKED_SEQ=1", "HYPERLINKED_TRACE=1", "HYPERLINKED_LABELS=request,worker"}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("e2e f: %v", err)
+	}
+	lines := strings.Split(stderr.String(), "\n")
+	for _, want := range []string{"level=warn", "seq=true", "trace=true", "labels=request,worker", "process_label=e2e"} {
+		if !slices.Contains(lines, want) {
+			t.Errorf("report has no line %q:\n%s", want, stderr.String())
+		}
+	}
+}
+
+// TestPanicHook checks that a crash is reported again linked by the
+// monitor started by InstallPanicHook, which does not run the init
+// functions of the program.
+func TestPanicHook(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds a program")
+	}
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+	bin, err := e2eProgram()
+	if err != nil {
+		t.Fatalf("building testdata/e2e: %v", err)
+	}
+	cmd := exec.Command(bin, "crash")
+	cmd.Env = []string{"HOME=" + t.TempDir(), "HYPERLINKED_TERMINAL=generic", "E2E_INIT=1"}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err == nil {
+		t.Fatal("e2e crash exited successfully")
+	}
+	got := stderr.String()
+	if n := strings.Count(got, "init\n"); n != 1 {
+		t.Errorf("init ran %d times, want once, in the program only:\n%s", n, got)
+	}
+	if !strings.Contains(got, "crash report, linked:") || !strings.Contains(got, "\x1b]8;;") {
+		t.Errorf("no linked crash report:\n%s", got)
+	}
+}
@@ -0,0 +1,374 @@
+package ps
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+)
+
+// Entry is a single line printed by this package, as passed to sinks.
+type Entry struct {
+	// Time is the wall-clock time at which the entry was printed.
+	Time time.Time `json:"time"`
+	// Elapsed is the time since the innermost timer of the printing
+	// goroutine was pushed with PushTimer, or else since StartTimer, or 0
+	// if neither has been called.
+	Elapsed time.Duration `json:"elapsed"`
+	// Msg is the message, without timestamp prefix or trailing newline.
+	Msg string `json:"msg"`
+	// File and Line are the source location the entry links to.
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+	// Func is the fully qualified name of the function at File:Line.
+	Func string `json:"func,omitempty"`
+	// Level is the severity of the entry. F, Ln and Stack print at LevelInfo.
+	Level Level `json:"level"`
+	// Tag classifies the entry, if it was printed with T.
+	Tag Tag `json:"tag,omitempty"`
+	// Goroutine is the ID of the goroutine that printed the entry.
+	Goroutine int64 `json:"goroutine,omitempty"`
+	// Seq is the number of the entry among those printed by a printer
+	// returned by Seq, or 0.
+	Seq uint64 `json:"seq,omitempty"`
+	// Process is the label of the printing process, set with
+	// SetProcessLabel, or "".
+	Process string `json:"process,omitempty"`
+	// Build identifies the build of the printing program. It is set in
+	// the entries passed to sinks, to the info returned by Build.
+	Build *BuildInfo `json:"build,omitempty"`
+	// Global are the fields describing the process, set with
+	// SetGlobalFields. They are set in the entries passed to sinks.
+	Global []Field `json:"global,omitempty"`
+	// Labels are the pprof labels shown, set with Labeled or Labels, sorted
+	// by key.
+	Labels []Field `json:"labels,omitempty"`
+	// Fields are the key-value pairs attached with With, in order.
+	Fields []Field `json:"fields,omitempty"`
+}
+
+// Field is a key-value pair attached to an entry.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// String renders f as key=value, quoting the value if it contains spaces,
+// quotes or control characters, which are escaped.
+func (f Field) String() string {
+	return f.Key + "=" + f.value(false, "")
+}
+
+// value renders the value of f, with a panic in a method of the value
+// rendered plainly, or, if line, as by safeArg with outer.
+func (f Field) value(line bool, outer string) string {
+	b, p := appendSafe(nil, plainState{}, 'v', f.Value)
+	if p != nil {
+		if !line {
+			return p.text()
+		}
+		return p.render(outer)
+	}
+	v := string(b)
+	if v == "" || strings.ContainsAny(v, " \t\n\"=") || strings.IndexFunc(v, unicode.IsControl) >= 0 {
+		v = strconv.Quote(v)
+	}
+	return v
+}
+
+// String renders e as it is printed to the terminal: a timestamped line
+// hyperlinked to its source location.
+func (e Entry) String() string {
+	return Render(e, lineWidth())
+}
+
+// Render renders e as String does, but truncated to width, or not at all
+// if width is 0 or less, in place of the width set by SetWidthFunc or
+// HYPERLINKED_COLUMNS, so that TUIs and other renderers can lay out lines
+// in their own panes. The layout, theme and terminal in effect apply, as
+// for printed lines. Nothing is printed and sinks are not called.
+func Render(e Entry, width int) string {
+	var text string
+	if cols := cfg().Columns; cols != nil {
+		text = layoutColumns(cols, e, e.Msg+formatFields(e.Fields)+formatGlobal(e.Global)+"\n", width)
+	} else {
+		text = layoutLines(linePrefix(e), e.Msg+formatFields(e.Fields)+formatGlobal(e.Global)+"\n", width)
+	}
+	if e.File == "" {
+		return text
+	}
+	return FormatOSC8(text, FormatURL(e.File, e.Line))
+}
+
+// MarshalJSON encodes e as a JSON object with keys in a fixed order: time,
+// elapsed, msg, file, line, func, level, tag, goroutine, seq, process,
+// build, global, labels, fields. Empty file, line, func, tag, goroutine,
+// seq, process, build, global, labels and fields are omitted. Global
+// fields, labels and fields are each encoded as an object with keys in the
+// order they were added, as by MarshalFields.
+func (e Entry) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	add := func(key string, v interface{}) error {
+		if b.Len() > 1 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		b.Write(k)
+		b.WriteByte(':')
+		j, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		b.Write(j)
+		return nil
+	}
+	if err := add("time", e.Time); err != nil {
+		return nil, err
+	}
+	add("elapsed", int64(e.Elapsed))
+	add("msg", e.Msg)
+	if e.File != "" {
+		add("file", e.File)
+	}
+	if e.Line != 0 {
+		add("line", e.Line)
+	}
+	if e.Func != "" {
+		add("func", e.Func)
+	}
+	add("level", e.Level)
+	if e.Tag != "" {
+		add("tag", e.Tag)
+	}
+	if e.Goroutine != 0 {
+		add("goroutine", e.Goroutine)
+	}
+	if e.Seq != 0 {
+		add("seq", e.Seq)
+	}
+	if e.Process != "" {
+		add("process", e.Process)
+	}
+	if e.Build != nil {
+		add("build", e.Build)
+	}
+	if len(e.Global) > 0 {
+		b.WriteString(`,"global":`)
+		b.Write(MarshalFields(e.Global))
+	}
+	if len(e.Labels) > 0 {
+		b.WriteString(`,"labels":`)
+		b.Write(MarshalFields(e.Labels))
+	}
+	if len(e.Fields) > 0 {
+		b.WriteString(`,"fields":`)
+		b.Write(MarshalFields(e.Fields))
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// MarshalFields encodes fields as a JSON object, keeping their order, as
+// in the fields of an encoded entry. Values that cannot be encoded are
+// replaced by their fmt.Sprint form.
+func MarshalFields(fields []Field) []byte {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, f := range fields {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		k, _ := json.Marshal(f.Key)
+		b.Write(k)
+		b.WriteByte(':')
+		b.Write(marshalValue(f.Value))
+	}
+	b.WriteByte('}')
+	return b.Bytes()
+}
+
+// marshalValue encodes v as JSON, or if that fails, its fmt.Sprint form.
+// A panic in a MarshalJSON method of v is encoded as a string saying so,
+// as panics in its String method are by safeSprint.
+func marshalValue(v interface{}) (j []byte) {
+	defer func() {
+		if r := recover(); r != nil {
+			j, _ = json.Marshal("<panic in MarshalJSON(): " + safeSprint(r) + ">")
+		}
+	}()
+	j, err := json.Marshal(v)
+	if err != nil {
+		j, _ = json.Marshal(safeSprint(v))
+	}
+	return j
+}
+
+// UnmarshalJSON decodes an entry encoded by MarshalJSON.
+func (e *Entry) UnmarshalJSON(data []byte) error {
+	type plain Entry
+	var v struct {
+		plain
+		Global json.RawMessage `json:"global"`
+		Labels json.RawMessage `json:"labels"`
+		Fields json.RawMessage `json:"fields"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*e = Entry(v.plain)
+	global, err := UnmarshalFields(v.Global)
+	if err != nil {
+		return err
+	}
+	e.Global = global
+	labels, err := UnmarshalFields(v.Labels)
+	if err != nil {
+		return err
+	}
+	e.Labels = labels
+	fields, err := UnmarshalFields(v.Fields)
+	e.Fields = fields
+	return err
+}
+
+// UnmarshalFields decodes a JSON object of fields, as encoded in the
+// fields of an entry, keeping their order. Empty data and null decode to
+// no fields.
+func UnmarshalFields(data []byte) ([]Field, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if t, err := dec.Token(); err != nil {
+		return nil, err
+	} else if t != json.Delim('{') {
+		return nil, fmt.Errorf("fields: want object, got %v", t)
+	}
+	var fields []Field
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, Field{Key: t.(string), Value: value})
+	}
+	return fields, nil
+}
+
+// formatFields renders fields as " key=value key=value", or "" if there
+// are none.
+func formatFields(fields []Field) string {
+	var b strings.Builder
+	for _, f := range fields {
+		b.WriteByte(' ')
+		b.WriteString(f.String())
+	}
+	return b.String()
+}
+
+// formatLineFields is formatFields for a line being printed, with panics
+// in methods of the values styled and linked to where they happened, after
+// which the link returns to outer, the link of the line, unless it is "".
+func formatLineFields(fields []Field, outer string) string {
+	var b strings.Builder
+	for _, f := range fields {
+		b.WriteByte(' ')
+		b.WriteString(f.Key)
+		b.WriteByte('=')
+		b.WriteString(f.value(true, outer))
+	}
+	return b.String()
+}
+
+// Sink receives every entry printed by this package, in addition to it
+// being written to the output. The errors of WriteEntry are returned by
+// TryF and passed to the function set by SetErrorHandler.
+type Sink interface {
+	WriteEntry(e Entry) error
+}
+
+var (
+	sinksMu sync.Mutex
+	sinks   atomic.Pointer[[]Sink]
+)
+
+// AddSink registers s to receive all subsequently printed entries.
+func AddSink(s Sink) {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	var next []Sink
+	if cur := sinks.Load(); cur != nil {
+		next = append(next, *cur...)
+	}
+	next = append(next, s)
+	sinks.Store(&next)
+}
+
+// RemoveSink unregisters a sink added with AddSink.
+func RemoveSink(s Sink) {
+	sinksMu.Lock()
+	defer sinksMu.Unlock()
+	cur := sinks.Load()
+	if cur == nil {
+		return
+	}
+	var next []Sink
+	for _, t := range *cur {
+		if t != s {
+			next = append(next, t)
+		}
+	}
+	sinks.Store(&next)
+}
+
+// hasSinks reports whether any sinks are registered.
+func hasSinks() bool {
+	cur := sinks.Load()
+	return cur != nil && len(*cur) > 0
+}
+
+// dispatch passes e to all registered sinks, with its Build and Global
+// set, and keeps it for Share, returning the errors of the sinks.
+func dispatch(e Entry) error {
+	keepShared(e)
+	cur := sinks.Load()
+	if cur == nil {
+		return nil
+	}
+	if e.Build == nil {
+		e.Build = Build()
+	}
+	if e.Global == nil {
+		e.Global = GlobalFields()
+	}
+	var errs []error
+	for _, s := range *cur {
+		if err := writeFailed(s, s.WriteEntry(e)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// elapsed returns the time from the start time of the calling goroutine's
+// timer to t, or 0 if no timer has been started.
+func elapsed(t time.Time) time.Duration {
+	start := timerStart()
+	if start.IsZero() {
+		return 0
+	}
+	return t.Sub(start)
+}
@@ -0,0 +1,151 @@
+package ps
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Env prints a Started line for each environment variable whose name
+// starts with prefix, sorted by name, with the values aligned. Values of
+// variables named like secrets (see RedactKeys) are redacted:
+//
+//	ps.Env("HYPERLINKED_")
+func Env(prefix string) {
+	std.env(1, prefix)
+}
+
+// Env is like the package-level Env.
+func (p *Printer) Env(prefix string) {
+	p.env(1, prefix)
+}
+
+func (p *Printer) env(skip int, prefix string) {
+	var kvs [][2]string
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok && strings.HasPrefix(k, prefix) {
+			kvs = append(kvs, [2]string{k, v})
+		}
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i][0] < kvs[j][0] })
+	p.printSettings(skip+1, kvs)
+}
+
+// Config prints a Started line for each field of the struct v, or the
+// struct v points to, with the values aligned. Fields of nested structs
+// are printed with dotted names. Values of fields named like secrets (see
+// RedactKeys) or tagged `ps:"redact"` are redacted, and fields tagged
+// `ps:"-"` are skipped:
+//
+//	ps.Config(cfg)
+func Config(v interface{}) {
+	std.config(1, v)
+}
+
+// Config is like the package-level Config.
+func (p *Printer) Config(v interface{}) {
+	p.config(1, v)
+}
+
+func (p *Printer) config(skip int, v interface{}) {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		p.printSettings(skip+1, [][2]string{{"config", "<nil>"}})
+		return
+	}
+	seen := map[uintptr]bool{}
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		seen[rv.Pointer()] = true
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		p.printSettings(skip+1, [][2]string{{reflect.TypeOf(v).String(), fmt.Sprint(rv.Interface())}})
+		return
+	}
+	p.printSettings(skip+1, structFields("", rv, seen))
+}
+
+// structFields returns the names and formatted values of the fields of the
+// struct v, with names prefixed by prefix. Pointers to the structs in seen,
+// those v is in, are shown as "<cycle>" rather than followed.
+func structFields(prefix string, v reflect.Value, seen map[uintptr]bool) [][2]string {
+	var kvs [][2]string
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("ps")
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		name := prefix + f.Name
+		fv := v.Field(i)
+		var followed []uintptr
+		cycle := false
+		for fv.Kind() == reflect.Pointer && !fv.IsNil() && fv.Elem().Kind() == reflect.Struct {
+			if seen[fv.Pointer()] {
+				cycle = true
+				break
+			}
+			followed = append(followed, fv.Pointer())
+			fv = fv.Elem()
+		}
+		switch {
+		case tag == "redact" || redacted(f.Name):
+			kvs = append(kvs, [2]string{name, "[redacted]"})
+		case cycle:
+			kvs = append(kvs, [2]string{name, "<cycle>"})
+		case fv.Kind() == reflect.Struct && !implementsStringer(fv):
+			for _, ptr := range followed {
+				seen[ptr] = true
+			}
+			kvs = append(kvs, structFields(name+".", fv, seen)...)
+			for _, ptr := range followed {
+				delete(seen, ptr)
+			}
+		default:
+			kvs = append(kvs, [2]string{name, fmt.Sprint(fv.Interface())})
+		}
+	}
+	return kvs
+}
+
+// implementsStringer reports whether v formats itself, as time.Time does.
+func implementsStringer(v reflect.Value) bool {
+	_, ok := v.Interface().(fmt.Stringer)
+	return ok
+}
+
+// redacted reports whether name is named like a secret.
+func redacted(name string) bool {
+	name = strings.ToLower(name)
+	for _, k := range cfg().RedactKeys {
+		if strings.Contains(name, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// printSettings prints a Started line "name = value" for each name-value
+// pair, linked to the caller skip frames above printSettings's caller,
+// with the values aligned and those named like secrets redacted.
+func (p *Printer) printSettings(skip int, kvs [][2]string) {
+	if !p.prints(Started) {
+		return
+	}
+	site := p.callSite(skip + 1)
+	width := 0
+	for _, kv := range kvs {
+		width = max(width, textWidth(kv[0]))
+	}
+	for _, kv := range kvs {
+		name, value := kv[0], kv[1]
+		if redacted(name) {
+			value = "[redacted]"
+		}
+		pad := strings.Repeat(" ", width-textWidth(name))
+		p.printAt(site, newEntry(Started), "%s%s = %s\n", []interface{}{name, pad, value})
+	}
+}
@@ -0,0 +1,66 @@
+package ps
+
+import (
+	"io"
+	"testing"
+)
+
+// configLines returns the messages Config prints for v.
+func configLines(t *testing.T, v interface{}) []string {
+	t.Helper()
+	SetOutput(io.Discard)
+	t.Cleanup(func() { SetOutput(nil) })
+	s := sink(t)
+	Config(v)
+	var msgs []string
+	for _, e := range s.es {
+		msgs = append(msgs, e.Msg)
+	}
+	return msgs
+}
+
+type node struct {
+	Name string
+	Next *node
+}
+
+func TestConfig(t *testing.T) {
+	var nilNode *node
+	loop := node{Name: "a"}
+	loop.Next = &loop
+	pair := &node{Name: "a", Next: &node{Name: "b"}}
+	pair.Next.Next = pair
+	for _, tt := range []struct {
+		name string
+		v    interface{}
+		want []string
+	}{
+		{"nil", nil, []string{"config = <nil>"}},
+		{"nil pointer", nilNode, []string{"*ps.node = <nil>"}},
+		{"pointer to nil pointer", &nilNode, []string{"**ps.node = <nil>"}},
+		{"non-struct", 42, []string{"int = 42"}},
+		{"nested", node{Name: "a", Next: &node{Name: "b"}}, []string{
+			"Name      = a",
+			"Next.Name = b",
+			"Next.Next = <nil>",
+		}},
+		{"self-referencing", &loop, []string{"Name = a", "Next = <cycle>"}},
+		{"cycle of two", pair, []string{
+			"Name      = a",
+			"Next.Name = b",
+			"Next.Next = <cycle>",
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := configLines(t, tt.v)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Config printed %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("line %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
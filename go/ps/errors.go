@@ -0,0 +1,91 @@
+package ps
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// WriteError is a failure to write an entry to the output set by SetOutput
+// or to a sink, as returned by TryF and passed to the function set by
+// SetErrorHandler.
+type WriteError struct {
+	// Sink is the sink whose WriteEntry failed, or nil if writing to the
+	// output failed.
+	Sink Sink
+	Err  error
+}
+
+func (e *WriteError) Error() string {
+	if e.Sink == nil {
+		return "ps: writing output: " + e.Err.Error()
+	}
+	return fmt.Sprintf("ps: writing to sink %T: %v", e.Sink, e.Err)
+}
+
+func (e *WriteError) Unwrap() error {
+	return e.Err
+}
+
+// errorHandler holds the function set by SetErrorHandler, if any.
+var errorHandler atomic.Pointer[func(err error)]
+
+// SetErrorHandler sets the function called with each *WriteError, from
+// any line printed, so that applications writing to files or over the
+// network can tell when output is lost: otherwise such errors are
+// ignored, as the functions printing lines return none. f is called after
+// the write, outside the lock serializing output, but must not print to
+// an output that is failing, lest each failure report another. A nil f
+// restores the default.
+func SetErrorHandler(f func(err error)) {
+	if f == nil {
+		errorHandler.Store(nil)
+		return
+	}
+	errorHandler.Store(&f)
+}
+
+// writeFailed returns err, if not nil, as a *WriteError for sink, passing
+// it to the function set by SetErrorHandler.
+func writeFailed(sink Sink, err error) error {
+	if err == nil {
+		return nil
+	}
+	err = &WriteError{Sink: sink, Err: err}
+	if f := errorHandler.Load(); f != nil {
+		(*f)(err)
+	}
+	return err
+}
+
+// TryF is like F, but returns the errors writing the line to the output
+// and to the sinks, as *WriteErrors joined by errors.Join, or nil if all
+// succeeded or the line was not printed.
+func TryF(format string, args ...interface{}) error {
+	return std.printf(1, "", format, args)
+}
+
+// TryLn is like Ln, but returns the errors writing the line, as TryF does.
+func TryLn(args ...interface{}) error {
+	return std.printf(1, "", printFormat(args, true), args)
+}
+
+// TryF is like the package-level TryF.
+func (p *Printer) TryF(format string, args ...interface{}) error {
+	return p.printf(1, "", format, args)
+}
+
+// TryLn is like the package-level TryLn.
+func (p *Printer) TryLn(args ...interface{}) error {
+	return p.printf(1, "", printFormat(args, true), args)
+}
+
+// joinErrors is errors.Join, without allocating if there are no errors.
+func joinErrors(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return errors.Join(errs...)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package ps
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+var (
+	exitMu    sync.Mutex
+	exitHooks []func()
+)
+
+// OnExit registers f to be run by Exit, Shutdown and Recover. Hooks run in
+// the reverse order of registration, each at most once. Go has no atexit:
+// a program that calls os.Exit directly, or dies from an unrecovered
+// panic, runs none of them.
+func OnExit(f func()) {
+	exitMu.Lock()
+	defer exitMu.Unlock()
+	exitHooks = append(exitHooks, f)
+}
+
+// Exit runs the exit hooks and exits with the given code. Lines printed
+// with F and the other printing functions are written to the output before
+// they return, so there is nothing to flush for them. Otherwise, in order:
+//
+//   - if code is not 0, lines recorded with Defer are printed
+//   - the hooks registered with OnExit are run
+//   - registered sinks that implement io.Closer are closed, which sends the
+//     entries buffered by asynchronous sinks such as psloki's
+func Exit(code int) {
+	runExit(code != 0)
+	os.Exit(code)
+}
+
+// Shutdown runs the exit hooks as Exit(0) does, without exiting. Call it
+// when main returns:
+//
+//	func main() {
+//		defer ps.Shutdown()
+//		defer ps.Recover()
+//		...
+//	}
+func Shutdown() {
+	runExit(false)
+}
+
+// Recover, called with defer, prints a Failure line linked to the source
+// of a panic, runs the exit hooks as Exit(1) does and lets the panic
+// continue. It must be deferred directly, not called from a deferred
+// function.
+func Recover() {
+	if r := recover(); r != nil {
+		std.panicked(r)
+	}
+}
+
+// Recover is like the package-level Recover, printing with p.
+func (p *Printer) Recover() {
+	if r := recover(); r != nil {
+		p.panicked(r)
+	}
+}
+
+// panicked handles the panic with value r, recovered by the deferred
+// function calling panicked.
+func (p *Printer) panicked(r interface{}) {
+	if p.prints(Failure) {
+		p.printAt(panicSite(), newEntry(Failure), "panic: %v\n", []interface{}{r})
+	}
+	runExit(true)
+	panic(r)
+}
+
+// panicSite returns the location of the panic being handled by the
+// deferred function calling panicked: the first frame outside the runtime
+// after runtime.gopanic.
+func panicSite() callSite {
+	var pcs [64]uintptr
+	n := runtime.Callers(1, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	panicking := false
+	for {
+		frame, more := frames.Next()
+		if panicking && !strings.HasPrefix(frame.Function, "runtime.") {
+			return callSite{pc: frame.PC, file: frame.File, line: frame.Line, funcName: frame.Function, ok: frame.PC != 0}
+		}
+		if frame.Function == "runtime.gopanic" {
+			panicking = true
+		}
+		if !more {
+			return callSite{}
+		}
+	}
+}
+
+// runExit runs the exit hooks registered so far, first printing the
+// deferred lines if failed, and then summarizes the lines suppressed by
+// the rate cap in the current window and closes the sinks.
+func runExit(failed bool) {
+	if failed {
+		FlushDeferred()
+	}
+	runExitHooks()
+	summarizeSuppressed()
+	closeSinks()
+}
+
+// runExitHooks runs the exit hooks registered so far, in the reverse order
+// of registration, and unregisters them.
+func runExitHooks() {
+	exitMu.Lock()
+	hooks := exitHooks
+	exitHooks = nil
+	exitMu.Unlock()
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
@@ -0,0 +1,174 @@
+package ps
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// callFilter decides which call sites may print, as configured by
+// SetFilter. Decisions are cached per program counter.
+type callFilter struct {
+	spec    string
+	include []filterPattern
+	exclude []filterPattern
+	// includeTags and excludeTags select entries by tag.
+	includeTags []Tag
+	excludeTags []Tag
+	cache       sync.Map // uintptr -> bool
+}
+
+// filterPattern is either a glob matched against the trailing path
+// elements of a source file, or a prefix of a function name.
+type filterPattern struct {
+	glob   string
+	prefix string
+}
+
+// SetFilter restricts output to the call sites matching spec, a
+// comma-separated list of patterns. Patterns ending in ".go" are globs
+// matched against the trailing elements of the source file path, e.g.
+// "server/*.go"; other patterns are prefixes of function names, also
+// matched from any path element, e.g. "worker.(*Pool)" or "pkg/worker".
+// Patterns of the form "tag:name" match entries printed with that Tag.
+// A pattern prefixed with "-" excludes the call sites it matches.
+//
+// If there are any including patterns, a call site must match one of them
+// to print, and likewise an entry must match one of any including tag
+// patterns. A call site or entry matching an excluding pattern never
+// prints. An empty spec removes the filter. Set via HYPERLINKED_FILTER env var:
+//
+//	HYPERLINKED_FILTER=pkg/server/*.go,-pkg/server/metrics.go
+func SetFilter(spec string) error {
+	f, err := parseFilter(spec)
+	if err != nil {
+		return err
+	}
+	Configure(func(s *Settings) { s.filter = f })
+	return nil
+}
+
+// parseFilter parses a filter spec as for SetFilter, returning nil for a
+// spec without patterns.
+func parseFilter(spec string) (*callFilter, error) {
+	f := &callFilter{spec: spec}
+	for _, p := range strings.Split(spec, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		exclude := strings.HasPrefix(p, "-")
+		p = strings.TrimPrefix(p, "-")
+		if name, ok := strings.CutPrefix(p, "tag:"); ok {
+			if exclude {
+				f.excludeTags = append(f.excludeTags, Tag(name))
+			} else {
+				f.includeTags = append(f.includeTags, Tag(name))
+			}
+			continue
+		}
+		var fp filterPattern
+		if strings.HasSuffix(p, ".go") {
+			if _, err := path.Match(p, ""); err != nil {
+				return nil, fmt.Errorf("invalid filter pattern %q: %w", p, err)
+			}
+			fp.glob = p
+		} else {
+			fp.prefix = p
+		}
+		if exclude {
+			f.exclude = append(f.exclude, fp)
+		} else {
+			f.include = append(f.include, fp)
+		}
+	}
+	if len(f.include) == 0 && len(f.exclude) == 0 && len(f.includeTags) == 0 && len(f.excludeTags) == 0 {
+		return nil, nil
+	}
+	return f, nil
+}
+
+// Filter returns the spec set by SetFilter.
+func Filter() string {
+	if f := cfg().filter; f != nil {
+		return f.spec
+	}
+	return ""
+}
+
+// allowed reports whether the call site at pc (in file, within function
+// funcName) passes the filter.
+func allowed(pc uintptr, file, funcName string) bool {
+	f := cfg().filter
+	if f == nil {
+		return true
+	}
+	if ok, cached := f.cache.Load(pc); cached {
+		return ok.(bool)
+	}
+	ok := f.allows(file, funcName)
+	f.cache.Store(pc, ok)
+	return ok
+}
+
+// allowedTag reports whether entries tagged t pass the filter.
+func allowedTag(t Tag) bool {
+	f := cfg().filter
+	if f == nil {
+		return true
+	}
+	if len(f.includeTags) > 0 && !slices.Contains(f.includeTags, t) {
+		return false
+	}
+	return !slices.Contains(f.excludeTags, t)
+}
+
+func (f *callFilter) allows(file, funcName string) bool {
+	if len(f.include) > 0 {
+		found := false
+		for _, p := range f.include {
+			if p.matches(file, funcName) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, p := range f.exclude {
+		if p.matches(file, funcName) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p filterPattern) matches(file, funcName string) bool {
+	if p.glob != "" {
+		return matchSuffix(file, func(s string) bool {
+			ok, _ := path.Match(p.glob, s)
+			return ok
+		})
+	}
+	return matchSuffix(funcName, func(s string) bool {
+		return strings.HasPrefix(s, p.prefix)
+	})
+}
+
+// matchSuffix reports whether match is true for s or for any part of s
+// following a "/".
+func matchSuffix(s string, match func(string) bool) bool {
+	for {
+		if match(s) {
+			return true
+		}
+		i := strings.IndexByte(s, '/')
+		if i < 0 {
+			return false
+		}
+		s = s[i+1:]
+	}
+}
@@ -0,0 +1,89 @@
+package ps
+
+import (
+	"cmp"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// githubDevURL returns the URL opening file at line in the github.dev web
+// editor, for the "githubdev" link format, or false if the repository is
+// not known or file is not in its checkout. The repository, ref and
+// checkout are those of the settings GitHubRepo, GitHubRef and GitHubRoot,
+// or where they are not set, of the git checkout in the working directory.
+func githubDevURL(file string, line int) (string, bool) {
+	repo, ref, root := githubSite()
+	if repo == "" || ref == "" || root == "" {
+		return "", false
+	}
+	return githubBlobURL("https://github.dev/"+repo, ref, root, file, line)
+}
+
+// githubBlobURL returns the URL of file at line in the blob view of the
+// repository with the web URL base, at revision rev, for file in root, a
+// checkout of the repository, or false if file is not under root.
+func githubBlobURL(base, rev, root, file string, line int) (string, bool) {
+	rel, err := filepath.Rel(root, file)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", false
+	}
+	u := url.URL{Path: "/" + rev + "/" + filepath.ToSlash(rel)}
+	return base + "/blob" + u.EscapedPath() + "#L" + strconv.Itoa(line), true
+}
+
+// githubSite returns the repository, as "owner/name", the ref and the root
+// of the checkout to link to with the "githubdev" link format.
+func githubSite() (repo, ref, root string) {
+	s := cfg()
+	repo, ref, root = s.GitHubRepo, s.GitHubRef, s.GitHubRoot
+	if repo == "" || ref == "" || root == "" {
+		g := gitCheckout()
+		repo, ref, root = cmp.Or(repo, g.repo), cmp.Or(ref, g.ref), cmp.Or(root, g.root)
+	}
+	return repo, ref, root
+}
+
+// checkout describes a git checkout of a GitHub repository.
+type checkout struct {
+	repo, ref, root string
+	// commit is the commit checked out.
+	commit string
+}
+
+// gitCheckout returns the git checkout in the working directory, found on
+// first use.
+var gitCheckout = sync.OnceValue(func() checkout {
+	git := func(args ...string) string {
+		out, err := exec.Command("git", args...).Output()
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(out))
+	}
+	var c checkout
+	c.repo = githubRepoName(git("remote", "get-url", "origin"))
+	c.commit = git("rev-parse", "HEAD")
+	c.ref = git("rev-parse", "--abbrev-ref", "HEAD")
+	if c.ref == "HEAD" {
+		c.ref = c.commit
+	}
+	c.root = git("rev-parse", "--show-toplevel")
+	return c
+})
+
+// githubRepoName returns the "owner/name" of the GitHub repository with the
+// git remote URL remote, given over HTTPS or SSH, or "" if it is not on
+// GitHub.
+func githubRepoName(remote string) string {
+	remote = strings.TrimSuffix(remote, ".git")
+	for _, prefix := range []string{"https://github.com/", "ssh://git@github.com/", "git@github.com:"} {
+		if name, ok := strings.CutPrefix(remote, prefix); ok {
+			return name
+		}
+	}
+	return ""
+}
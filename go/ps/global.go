@@ -0,0 +1,60 @@
+package ps
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// globalFields holds the fields set by SetGlobalFields, if any.
+var globalFields atomic.Pointer[[]Field]
+
+// SetGlobalFields sets fields describing the process, such as its service
+// name, version and environment, as OpenTelemetry resource attributes do,
+// from kv, alternating keys and values:
+//
+//	ps.SetGlobalFields("service", "checkout", "version", version, "env", "staging")
+//
+// They are recorded in the Global field of every entry passed to sinks, so
+// that the entries of processes whose output is merged describe
+// themselves, and shown at the end of each line if
+// Settings.ShowGlobalFields is set. Keys that are not strings are
+// formatted with fmt.Sprint; a value missing at the end is recorded with
+// the key "!BADKEY", as by log/slog. Calling SetGlobalFields with no
+// arguments removes the fields.
+func SetGlobalFields(kv ...interface{}) {
+	if len(kv) == 0 {
+		globalFields.Store(nil)
+		return
+	}
+	var fields []Field
+	for i := 0; i < len(kv); i += 2 {
+		if i+1 == len(kv) {
+			fields = append(fields, Field{Key: "!BADKEY", Value: kv[i]})
+			break
+		}
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+		fields = append(fields, Field{Key: key, Value: kv[i+1]})
+	}
+	globalFields.Store(&fields)
+}
+
+// GlobalFields returns the fields set by SetGlobalFields.
+func GlobalFields() []Field {
+	if fields := globalFields.Load(); fields != nil {
+		return *fields
+	}
+	return nil
+}
+
+// formatGlobal renders fields, the global fields of an entry, as shown at
+// the end of a line, or "" if they are not shown.
+func formatGlobal(fields []Field) string {
+	if len(fields) == 0 || !cfg().ShowGlobalFields {
+		return ""
+	}
+	return " " + CurrentTheme().Dim.Render(strings.TrimPrefix(formatFields(fields), " "))
+}
@@ -0,0 +1,75 @@
+package ps
+
+import (
+	"context"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// launched counts the goroutines started by Go, numbering them.
+var launched atomic.Int64
+
+// Go runs f in a new goroutine whose lines show the label
+// "{go=#3@main.go:12}", numbering the goroutines started by Go and giving
+// the location of the call to Go. If f panics, the panic is recovered:
+// a Failure line "panic in goroutine started at main.go:12: boom", linked
+// to where it happened, with the launch site linked too, is printed
+// with the goroutine's stack, its source locations hyperlinked, and the
+// program goes on, where a panic in a bare goroutine would crash it:
+//
+//	ps.Go(func() {
+//		for msg := range msgs {
+//			handle(msg)
+//		}
+//	})
+func Go(f func()) {
+	std.launch(1, f)
+}
+
+// Go is like the package-level Go, printing the Failure line with p.
+func (p *Printer) Go(f func()) {
+	p.launch(1, f)
+}
+
+func (p *Printer) launch(skip int, f func()) {
+	site := p.callSite(skip + 1)
+	name := "#" + strconv.FormatInt(launched.Add(1), 10) + "@" + beginSite(site).String()
+	go func() {
+		_, done := Labeled(context.Background(), "go", name)
+		defer done()
+		defer func() {
+			if r := recover(); r != nil {
+				p.crashed(r, site)
+			}
+		}()
+		f()
+	}()
+}
+
+// crashed prints the panic with value r, recovered by the deferred
+// function calling crashed, of a goroutine started by Go at site.
+func (p *Printer) crashed(r interface{}, site callSite) {
+	if !p.prints(Failure) {
+		return
+	}
+	p.printAt(panicSite(), newEntry(Failure), "panic in goroutine started at %v: %v\n", []interface{}{beginSite(site), r})
+	write(Linkify(panickedStack()))
+}
+
+// panickedStack returns the stack of the calling goroutine, as printed by
+// a panic, from the function that panicked, for the deferred function
+// handling the panic.
+func panickedStack() string {
+	header, frames, _ := strings.Cut(string(debug.Stack()), "\n")
+	// Each frame is a line naming the function and one giving its location.
+	if i := strings.Index(frames, "\npanic("); i >= 0 {
+		rest := frames[i+1:]
+		for n := 0; n < 2; n++ {
+			_, rest, _ = strings.Cut(rest, "\n")
+		}
+		frames = rest
+	}
+	return header + "\n" + frames
+}
@@ -0,0 +1,49 @@
+package ps
+
+import (
+	"context"
+	"runtime/trace"
+	"strconv"
+)
+
+// SetTrace sets whether printing also emits runtime/trace events while an
+// execution trace is being recorded, as by go test -trace or
+// trace.Start, so that go tool trace shows the same instrumentation as the
+// terminal:
+//
+//   - each line printed is a trace.Log event, with the tag, or "ps", as its
+//     category and the message prefixed with file:line
+//   - each Section and each Took is a trace region, ending when the
+//     function they return is called, which must be on the same goroutine
+//
+// Set via HYPERLINKED_TRACE=1.
+func SetTrace(on bool) {
+	Configure(func(s *Settings) { s.Trace = on })
+}
+
+// tracing reports whether lines are to be emitted as trace events.
+func tracing() bool {
+	return cfg().Trace && trace.IsEnabled()
+}
+
+// traceLog emits e as a trace.Log event.
+func traceLog(e Entry) {
+	category := string(e.Tag)
+	if category == "" {
+		category = "ps"
+	}
+	msg := e.Msg
+	if e.File != "" {
+		msg = DisplayPath(e.File) + ":" + strconv.Itoa(e.Line) + ": " + msg
+	}
+	trace.Log(context.Background(), category, msg)
+}
+
+// traceRegion starts a trace region named name if tracing, returning the
+// function ending it.
+func traceRegion(name string) func() {
+	if !tracing() {
+		return func() {}
+	}
+	return trace.StartRegion(context.Background(), name).End
+}
@@ -0,0 +1,270 @@
+package ps
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// guardArgs returns args with the values formatted with %v in format that
+// could expand without bound, such as structs, maps, slices and pointers
+// to them, replaced by guardedArgs, if Settings.MaxDepth or
+// Settings.MaxArgLength is set, and whether there were any. args itself is
+// not modified.
+func guardArgs(format string, args []interface{}) ([]interface{}, bool) {
+	s := cfg()
+	if s.MaxDepth <= 0 && s.MaxArgLength <= 0 {
+		return args, false
+	}
+	var out []interface{}
+	for _, i := range verbArgs(format, "v") {
+		if i >= len(args) || !guarded(args[i]) {
+			continue
+		}
+		if out == nil {
+			out = append([]interface{}(nil), args...)
+		}
+		out[i] = guardedArg{v: args[i], depth: s.MaxDepth, length: s.MaxArgLength}
+	}
+	if out == nil {
+		return args, false
+	}
+	return out, true
+}
+
+// guarded reports whether arg is formatted by guardedArg: whether it is
+// a composite value that formats itself with neither a Format, Error nor
+// String method.
+func guarded(arg interface{}) bool {
+	switch arg.(type) {
+	case nil, fmt.Formatter, error, fmt.Stringer:
+		return false
+	}
+	switch reflect.TypeOf(arg).Kind() {
+	case reflect.Struct, reflect.Pointer, reflect.Map, reflect.Slice, reflect.Array, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+// guardedArg formats its value with %v and %+v as fmt does, but only to
+// depth levels of nesting, if depth is positive, and length bytes, if
+// length is positive, and with each value referring back to one that
+// contains it, as in self-referencing structs, shown as "<cycle>". Unlike
+// fmt, it follows pointers to structs, arrays, slices and maps at any
+// depth, and not only at the top level. %#v is formatted by fmt.
+type guardedArg struct {
+	v             interface{}
+	depth, length int
+}
+
+func (a guardedArg) Format(f fmt.State, verb rune) {
+	if f.Flag('#') {
+		fmt.Fprintf(f, fmt.FormatString(f, verb), a.v)
+		return
+	}
+	g := guard{guardedArg: a, plus: f.Flag('+'), path: map[visit]bool{}}
+	g.value(reflect.ValueOf(a.v), 0)
+	text := g.String()
+	if a.length > 0 && len(text) > a.length {
+		cut := a.length
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut] + "…"
+	}
+	fmt.Fprintf(f, fmt.FormatString(f, 's'), text)
+}
+
+// visit identifies a value referred to by a pointer, map or slice.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// guard renders a guardedArg.
+type guard struct {
+	guardedArg
+	strings.Builder
+	plus bool
+	// path holds the values being rendered, containing the current one.
+	path map[visit]bool
+}
+
+// full reports whether the rendering has reached the length limit, after
+// which the rest is not rendered.
+func (g *guard) full() bool {
+	return g.length > 0 && g.Len() > g.length
+}
+
+// deep reports whether values at depth are too deeply nested to render.
+func (g *guard) deep(depth int) bool {
+	return g.depth > 0 && depth >= g.depth
+}
+
+// value renders v, found at depth levels of nesting.
+func (g *guard) value(v reflect.Value, depth int) {
+	if g.full() {
+		return
+	}
+	if !v.IsValid() {
+		g.WriteString("<nil>")
+		return
+	}
+	if depth > 0 && v.CanInterface() {
+		switch x := v.Interface().(type) {
+		case fmt.Formatter, error, fmt.Stringer:
+			if v.Kind() != reflect.Pointer || !v.IsNil() {
+				g.WriteString(safeSprint(x))
+				return
+			}
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			g.WriteString("<nil>")
+			return
+		}
+		g.value(v.Elem(), depth)
+	case reflect.Pointer:
+		if v.IsNil() {
+			g.WriteString("<nil>")
+			return
+		}
+		switch v.Elem().Kind() {
+		case reflect.Struct, reflect.Array, reflect.Slice, reflect.Map:
+			if g.enter(v) {
+				g.WriteByte('&')
+				g.value(v.Elem(), depth)
+				g.leave(v)
+			}
+		default:
+			fmt.Fprintf(g, "0x%x", v.Pointer())
+		}
+	case reflect.Struct:
+		g.WriteByte('{')
+		if g.deep(depth) {
+			g.WriteString("…}")
+			return
+		}
+		t := v.Type()
+		for i := 0; i < v.NumField() && !g.full(); i++ {
+			if i > 0 {
+				g.WriteByte(' ')
+			}
+			if g.plus {
+				g.WriteString(t.Field(i).Name + ":")
+			}
+			g.value(v.Field(i), depth+1)
+		}
+		g.WriteByte('}')
+	case reflect.Map:
+		if g.deep(depth) && v.Len() > 0 {
+			g.WriteString("map[…]")
+			return
+		}
+		if g.enter(v) {
+			g.WriteString("map[")
+			keys := v.MapKeys()
+			sort.Slice(keys, func(i, j int) bool {
+				return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+			})
+			for i, k := range keys {
+				if g.full() {
+					break
+				}
+				if i > 0 {
+					g.WriteByte(' ')
+				}
+				g.value(k, depth+1)
+				g.WriteByte(':')
+				g.value(v.MapIndex(k), depth+1)
+			}
+			g.leave(v)
+			g.WriteByte(']')
+		}
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			g.WriteString("[]")
+			return
+		}
+		if g.deep(depth) && v.Len() > 0 {
+			g.WriteString("[…]")
+			return
+		}
+		if v.Kind() == reflect.Array || g.enter(v) {
+			g.WriteByte('[')
+			for i := 0; i < v.Len() && !g.full(); i++ {
+				if i > 0 {
+					g.WriteByte(' ')
+				}
+				g.value(v.Index(i), depth+1)
+			}
+			if v.Kind() == reflect.Slice {
+				g.leave(v)
+			}
+			g.WriteByte(']')
+		}
+	default:
+		g.WriteString(basicString(v))
+	}
+}
+
+// enter adds v, a pointer, map or slice, to the path of values being
+// rendered, reporting false, after rendering "<cycle>" in its place, if it
+// is already on it.
+func (g *guard) enter(v reflect.Value) bool {
+	key := visit{v.Pointer(), v.Type()}
+	if v.Kind() == reflect.Slice {
+		// Slices of an array overlap rather than contain each other, so
+		// only a slice containing itself is a cycle.
+		key.typ = reflect.SliceOf(v.Type())
+	}
+	if g.path[key] {
+		g.WriteString("<cycle>")
+		return false
+	}
+	g.path[key] = true
+	return true
+}
+
+// leave removes v, added by enter, from the path.
+func (g *guard) leave(v reflect.Value) {
+	key := visit{v.Pointer(), v.Type()}
+	if v.Kind() == reflect.Slice {
+		key.typ = reflect.SliceOf(v.Type())
+	}
+	delete(g.path, key)
+}
+
+// basicString formats v, a value of a kind without elements, as %v does,
+// including for values of unexported fields, which cannot be passed to fmt.
+func basicString(v reflect.Value) string {
+	if v.CanInterface() {
+		return fmt.Sprint(v.Interface())
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return fmt.Sprint(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return fmt.Sprint(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return fmt.Sprint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return fmt.Sprint(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		return fmt.Sprint(v.Complex())
+	case reflect.String:
+		return v.String()
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		if v.IsNil() {
+			return "<nil>"
+		}
+		return fmt.Sprintf("0x%x", v.Pointer())
+	}
+	return "?"
+}
@@ -0,0 +1,62 @@
+package ps
+
+import (
+	"sync"
+	"time"
+)
+
+// Heartbeat prints a Scheduled line with the status returned by status
+// every interval, linked to the call site of Heartbeat, until the
+// returned function or Close is called. Use it to show that a long
+// operation is still making progress:
+//
+//	stop := ps.Heartbeat(5*time.Second, func() string {
+//		return fmt.Sprintf("%d/%d rows", done.Load(), total)
+//	})
+//	defer stop()
+//
+// stop waits for a status call in progress to return; once it has
+// returned, nothing more is printed. It may be called more than once.
+//
+// An interval of 0 or less, for which time.NewTicker would panic, is
+// reported with a Bad line, and nothing else is printed.
+func Heartbeat(interval time.Duration, status func() string) (stop func()) {
+	return std.heartbeat(1, interval, status)
+}
+
+// Heartbeat is like the package-level Heartbeat.
+func (p *Printer) Heartbeat(interval time.Duration, status func() string) (stop func()) {
+	return p.heartbeat(1, interval, status)
+}
+
+func (p *Printer) heartbeat(skip int, interval time.Duration, status func() string) func() {
+	site := p.callSite(skip + 1)
+	if interval <= 0 {
+		if p.prints(Bad) {
+			p.printAt(site, newEntry(Bad), "heartbeat interval %s is not positive\n", []interface{}{formatDuration(interval)})
+		}
+		return func() {}
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if p.prints(Scheduled) {
+					p.printAt(site, newEntry(Scheduled), "%s\n", []interface{}{status()})
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return track(func() {
+		once.Do(func() { close(done) })
+		<-stopped
+	})
+}
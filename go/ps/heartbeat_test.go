@@ -0,0 +1,22 @@
+package ps
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHeartbeatInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		s := testCaller(t)
+		stop := Heartbeat(interval, func() string { return "alive" })
+		stop()
+		stop()
+		e := s.last()
+		if e.Tag != Bad {
+			t.Errorf("Heartbeat(%s) printed %q, want a Bad line", interval, e.Msg)
+		}
+		if want := "heartbeat interval " + formatDuration(interval) + " is not positive"; e.Msg != want {
+			t.Errorf("Heartbeat(%s) printed %q, want %q", interval, e.Msg, want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package ps
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+// configure changes the settings as Configure does for the duration of
+// tb, restoring them when it ends.
+func configure(tb testing.TB, f func(s *Settings)) {
+	tb.Helper()
+	prev := CurrentSettings()
+	Configure(f)
+	tb.Cleanup(func() { Configure(func(s *Settings) { *s = prev }) })
+}
+
+// entries is a Sink keeping the entries passed to it.
+type entries struct {
+	mu sync.Mutex
+	es []Entry
+}
+
+func (s *entries) WriteEntry(e Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.es = append(s.es, e)
+	return nil
+}
+
+// last returns the last entry passed to s.
+func (s *entries) last() Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.es) == 0 {
+		return Entry{}
+	}
+	return s.es[len(s.es)-1]
+}
+
+// sink adds a Sink keeping the entries printed for the duration of tb.
+func sink(tb testing.TB) *entries {
+	tb.Helper()
+	s := &entries{}
+	AddSink(s)
+	tb.Cleanup(func() { RemoveSink(s) })
+	return s
+}
+
+// syncBuffer is a bytes.Buffer that can be written by several goroutines.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
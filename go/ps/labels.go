@@ -0,0 +1,137 @@
+package ps
+
+import (
+	"context"
+	"runtime/pprof"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var (
+	labelsMu sync.Mutex
+	// goroutineLabels are the labels set by Labeled, by goroutine ID.
+	goroutineLabels = map[int64][]Field{}
+	// hasLabels is whether any goroutine has labels set by Labeled, so
+	// that looking up the goroutine ID can be avoided otherwise.
+	hasLabels atomic.Bool
+)
+
+// ShowLabels selects the keys of the pprof labels shown after the
+// timestamp of each line, as "{key=value}". By default all labels set
+// with Labeled, or attached to a printer with Labels, are shown. Calling
+// ShowLabels with no keys restores the default. Set via
+// HYPERLINKED_LABELS env var, e.g. HYPERLINKED_LABELS=request,worker.
+func ShowLabels(keys ...string) {
+	if len(keys) == 0 {
+		keys = nil
+	} else {
+		keys = slices.Clone(keys)
+	}
+	Configure(func(s *Settings) { s.LabelKeys = keys })
+}
+
+// Labeled adds the pprof labels kv, alternating keys and values, to ctx
+// and to the calling goroutine, as pprof.Do does, so that CPU profiles
+// attribute the goroutine's samples to them. The lines the goroutine
+// prints show them too, until done is called, which restores the labels
+// in effect before:
+//
+//	ctx, done := ps.Labeled(ctx, "request", id)
+//	defer done()
+//
+// Goroutines started by the goroutine inherit its pprof labels but not
+// the labels shown; use Labels(ctx) to print with them.
+func Labeled(ctx context.Context, kv ...string) (_ context.Context, done func()) {
+	prev := ctx
+	ctx = pprof.WithLabels(ctx, pprof.Labels(kv...))
+	pprof.SetGoroutineLabels(ctx)
+
+	g := goroutineID()
+	labelsMu.Lock()
+	saved, had := goroutineLabels[g]
+	goroutineLabels[g] = contextLabels(ctx)
+	hasLabels.Store(true)
+	labelsMu.Unlock()
+
+	return ctx, func() {
+		pprof.SetGoroutineLabels(prev)
+		labelsMu.Lock()
+		defer labelsMu.Unlock()
+		if had {
+			goroutineLabels[g] = saved
+		} else {
+			delete(goroutineLabels, g)
+			hasLabels.Store(len(goroutineLabels) > 0)
+		}
+	}
+}
+
+// Labels returns a printer whose lines show the pprof labels of ctx, such
+// as those set with pprof.Do, in place of those of the goroutine.
+func Labels(ctx context.Context) *Printer {
+	return std.Labels(ctx)
+}
+
+// Labels returns a copy of p whose lines show the pprof labels of ctx.
+// See the package-level Labels.
+func (p *Printer) Labels(ctx context.Context) *Printer {
+	q := *p
+	q.labels = contextLabels(ctx)
+	return &q
+}
+
+// contextLabels returns the pprof labels of ctx, sorted by key.
+func contextLabels(ctx context.Context) []Field {
+	var labels []Field
+	pprof.ForLabels(ctx, func(key, value string) bool {
+		labels = append(labels, Field{Key: key, Value: value})
+		return true
+	})
+	slices.SortFunc(labels, func(a, b Field) int { return strings.Compare(a.Key, b.Key) })
+	return labels
+}
+
+// currentLabels returns the labels set with Labeled for the calling
+// goroutine.
+func currentLabels() []Field {
+	if !hasLabels.Load() {
+		return nil
+	}
+	g := goroutineID()
+	labelsMu.Lock()
+	defer labelsMu.Unlock()
+	return goroutineLabels[g]
+}
+
+// shown returns the labels among labels selected by ShowLabels.
+func shown(labels []Field) []Field {
+	keys := cfg().LabelKeys
+	if keys == nil || len(labels) == 0 {
+		return labels
+	}
+	var sel []Field
+	for _, l := range labels {
+		if slices.Contains(keys, l.Key) {
+			sel = append(sel, l)
+		}
+	}
+	return sel
+}
+
+// appendLabels appends labels as "{key=value key=value} " to b, or nothing
+// if there are none.
+func appendLabels(b []byte, labels []Field) []byte {
+	if len(labels) == 0 {
+		return b
+	}
+	b = append(b, '{')
+	for i, l := range labels {
+		if i > 0 {
+			b = append(b, ' ')
+		}
+		b = append(b, l.String()...)
+	}
+	return append(b, "} "...)
+}
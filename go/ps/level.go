@@ -0,0 +1,73 @@
+package ps
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level is the severity of an entry. Its values match those of log/slog.
+type Level int
+
+const (
+	LevelDebug Level = -4
+	LevelInfo  Level = 0
+	LevelWarn  Level = 4
+	LevelError Level = 8
+)
+
+// String returns the lower-case name of l, e.g. "info".
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+}
+
+// ParseLevel parses a level name as returned by Level.String, ignoring case.
+func ParseLevel(s string) (Level, error) {
+	var l Level
+	err := l.UnmarshalText([]byte(s))
+	return l, err
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (l *Level) UnmarshalText(text []byte) error {
+	switch strings.ToLower(string(text)) {
+	case "debug":
+		*l = LevelDebug
+	case "info":
+		*l = LevelInfo
+	case "warn", "warning":
+		*l = LevelWarn
+	case "error":
+		*l = LevelError
+	default:
+		return fmt.Errorf("unknown level %q", text)
+	}
+	return nil
+}
+
+// SetLevel suppresses entries below level l. It is safe to call
+// concurrently with printing. Set via HYPERLINKED_LEVEL env var, e.g.
+// HYPERLINKED_LEVEL=warn. The default is LevelDebug, printing everything.
+func SetLevel(l Level) {
+	Configure(func(s *Settings) { s.Level = l })
+}
+
+// MinLevel returns the level set by SetLevel.
+func MinLevel() Level {
+	return cfg().Level
+}
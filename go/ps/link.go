@@ -0,0 +1,143 @@
+package ps
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LinkedText is text linked to a URL, as returned by Link.
+type LinkedText struct {
+	Text string
+	URL  string
+}
+
+// Link returns text linked to url, for linking parts of a message to
+// resources other than source files, such as dashboards, trace UIs or
+// stored objects. Passed to F and the other printing functions, it is
+// rendered as a link nested in the line's link to its call site, with the
+// verbs %s, %v or %L:
+//
+//	ps.F("uploaded %s\n", ps.Link(key, consoleURL(key)))
+//
+// Elsewhere it renders as text wrapped in OSC8 escape codes, as
+// FormatOSC8 does.
+func Link(text, url string) LinkedText {
+	return LinkedText{Text: text, URL: url}
+}
+
+// String returns the text wrapped in OSC8 escape codes linking to the URL,
+// or the text alone if there is no URL.
+func (l LinkedText) String() string {
+	if l.URL == "" {
+		return l.Text
+	}
+	return FormatOSC8(l.Text, l.URL)
+}
+
+// Format implements fmt.Formatter, formatting the text as a string with
+// the flags, width and precision given, linked to the URL. The verb %L is
+// accepted as %s.
+func (l LinkedText) Format(f fmt.State, verb rune) {
+	term := cfg().Terminal
+	if l.URL == "" || !term.linkable(l.URL) {
+		fmt.Fprintf(f, fmt.FormatString(f, stringVerb(verb)), l.Text)
+		return
+	}
+	fmt.Fprint(f, term.osc8(l.URL))
+	fmt.Fprintf(f, fmt.FormatString(f, stringVerb(verb)), l.Text)
+	fmt.Fprint(f, osc8End)
+}
+
+// stringVerb returns verb, or 's' for the link verb 'L'.
+func stringVerb(verb rune) rune {
+	if verb == 'L' {
+		return 's'
+	}
+	return verb
+}
+
+// FL is like F, but string arguments formatted with the verb %L are
+// linked: "url|text" renders as text linked to url, and a string without
+// "|" as the URL linked to itself. Arguments returned by Link are linked
+// with any verb, as in F:
+//
+//	ps.FL("slow query, see %L\n", dashboardURL+"|dashboard")
+func FL(format string, args ...interface{}) {
+	std.printf(1, "", format, linkArgs(format, args))
+}
+
+// FL is like the package-level FL.
+func (p *Printer) FL(format string, args ...interface{}) {
+	p.printf(1, "", format, linkArgs(format, args))
+}
+
+// linkArgs returns args with the strings formatted with %L in format
+// replaced by the links they describe. args itself is not modified.
+func linkArgs(format string, args []interface{}) []interface{} {
+	var out []interface{}
+	for _, i := range verbArgs(format, "L") {
+		if i >= len(args) {
+			break
+		}
+		s, ok := args[i].(string)
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = append([]interface{}(nil), args...)
+		}
+		url, text, ok := strings.Cut(s, "|")
+		if !ok {
+			text = url
+		}
+		out[i] = Link(text, url)
+	}
+	if out == nil {
+		return args
+	}
+	return out
+}
+
+// verbArgs returns the indexes of the arguments formatted in format with
+// one of verbs, such as "L", following the rules of package fmt for widths
+// and precisions given by '*' and for explicit argument indexes such as
+// %[2]L.
+func verbArgs(format, verbs string) []int {
+	var indexes []int
+	arg := 0
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		for ; i < len(format); i++ {
+			c := format[i]
+			switch {
+			case c == '[':
+				end := strings.IndexByte(format[i:], ']')
+				if end < 0 {
+					return indexes
+				}
+				var n int
+				if _, err := fmt.Sscanf(format[i+1:i+end], "%d", &n); err == nil && n > 0 {
+					arg = n - 1
+				}
+				i += end
+				continue
+			case c == '*':
+				arg++
+				continue
+			case strings.IndexByte("+-# 0.", c) >= 0 || c >= '0' && c <= '9':
+				continue
+			case c == '%':
+			default:
+				if strings.IndexByte(verbs, c) >= 0 {
+					indexes = append(indexes, arg)
+				}
+				arg++
+			}
+			break
+		}
+	}
+	return indexes
+}
@@ -0,0 +1,120 @@
+package ps
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// probeTimeout bounds the time a probe may take.
+const probeTimeout = 200 * time.Millisecond
+
+// dialWormhole checks that the wormhole server accepts connections.
+func dialWormhole() error {
+	conn, err := net.DialTimeout("tcp", cfg().WormholeAddr, probeTimeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// ParseLinkFormat parses a link format as for LinkFormat, returning the
+// formats it lists in order of preference.
+func ParseLinkFormat(spec string) ([]string, error) {
+	var formats []string
+	for _, f := range strings.Split(spec, ",") {
+		f = strings.TrimSpace(f)
+		if !slices.Contains(LinkFormats, f) {
+			return nil, fmt.Errorf("unknown link format %q", f)
+		}
+		formats = append(formats, f)
+	}
+	return formats, nil
+}
+
+// FormatProbes report whether the editor or service opening links of each
+// format is likely to be available, by link format. They are used to pick
+// from a list of formats in LinkFormat; formats without a probe are
+// always available.
+var FormatProbes = map[string]func() bool{
+	"wormhole": func() bool { return dialWormhole() == nil },
+	"cursor": func() bool {
+		_, err := exec.LookPath("cursor")
+		return err == nil
+	},
+	"vscode": func() bool {
+		_, err := exec.LookPath("code")
+		return err == nil || os.Getenv("TERM_PROGRAM") == "vscode"
+	},
+	"vscode-remote": func() bool { return cfg().Environment.Authority != "" },
+	"githubdev": func() bool {
+		repo, _, _ := githubSite()
+		return repo != ""
+	},
+}
+
+var (
+	resolvedMu sync.Mutex
+	// resolved caches the format picked for each list of formats.
+	resolved = map[string]string{}
+)
+
+// ResolvedLinkFormat returns the link format in use: LinkFormat if it is a
+// single format, or else the first of its formats whose probe succeeds, or
+// the last if none does. Probes are run once for each value of LinkFormat,
+// on first use. An invalid LinkFormat resolves to "cursor". See also
+// Settings.ProbeWormhole.
+func ResolvedLinkFormat() string {
+	s := cfg()
+	spec := s.LinkFormat
+	if !strings.Contains(spec, ",") && !(spec == "wormhole" && s.ProbeWormhole) {
+		return spec
+	}
+
+	resolvedMu.Lock()
+	defer resolvedMu.Unlock()
+	if f, ok := resolved[spec]; ok {
+		return f
+	}
+	f := resolveLinkFormat(spec)
+	resolved[spec] = f
+	return f
+}
+
+// resolveLinkFormat picks a format from the list spec by probing.
+func resolveLinkFormat(spec string) string {
+	if spec == "wormhole" {
+		return resolveWormhole()
+	}
+	formats, err := ParseLinkFormat(spec)
+	if err != nil {
+		return "cursor"
+	}
+	for _, f := range formats {
+		if probe, ok := FormatProbes[f]; !ok || probe() {
+			return f
+		}
+	}
+	return formats[len(formats)-1]
+}
+
+// resolveWormhole returns "wormhole" if the wormhole server accepts
+// connections, and otherwise reports that it does not and returns the
+// format of an installed editor.
+func resolveWormhole() string {
+	err := dialWormhole()
+	if err == nil {
+		return "wormhole"
+	}
+	fallback := "cursor"
+	if !FormatProbes["cursor"]() && FormatProbes["vscode"]() {
+		fallback = "vscode"
+	}
+	fmt.Fprintf(os.Stderr, "hyperlinked: wormhole is not responding (%v); linking with %s instead\n", err, fallback)
+	return fallback
+}
@@ -0,0 +1,45 @@
+package ps
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// locationPattern matches Go source locations such as "file.go:123",
+// "pkg/file.go:123:45" and the "\t/path/to/file.go:123 +0x1d" lines
+// found in panic traces and runtime/debug.Stack output.
+var locationPattern = regexp.MustCompile(`(?:[A-Za-z]:)?[^\s:"'()\[\]<>]*\.go:(\d+)(?::\d+)?(?: \+0x[0-9a-f]+)?`)
+
+// Linkify returns s with every Go source location it contains wrapped in
+// an OSC8 hyperlink to that file and line. Relative paths are resolved
+// against the current working directory.
+func Linkify(s string) string {
+	return locationPattern.ReplaceAllStringFunc(s, func(match string) string {
+		file, line, ok := parseLocation(match)
+		if !ok {
+			return match
+		}
+		return FormatOSC8(match, FormatURL(file, line))
+	})
+}
+
+// parseLocation extracts the file and line from a match of locationPattern.
+func parseLocation(match string) (string, int, bool) {
+	sub := locationPattern.FindStringSubmatchIndex(match)
+	if sub == nil {
+		return "", 0, false
+	}
+	line, err := strconv.Atoi(match[sub[2]:sub[3]])
+	if err != nil {
+		return "", 0, false
+	}
+	// The file is everything before the ":<line>" group.
+	file := match[:sub[2]-1]
+	if !filepath.IsAbs(file) {
+		if abs, err := filepath.Abs(file); err == nil {
+			file = abs
+		}
+	}
+	return file, line, true
+}
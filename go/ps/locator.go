@@ -0,0 +1,101 @@
+package ps
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Locator is implemented by values, typically errors, that know the source
+// location they originated from. When an argument to F implements Locator
+// (directly or anywhere in its error chain), its rendered text is linked to
+// that location instead of to the call site of F.
+type Locator interface {
+	Location() (file string, line int)
+}
+
+// locate returns the Locator for v, looking through wrapped errors.
+func locate(v interface{}) (Locator, bool) {
+	if l, ok := v.(Locator); ok {
+		return l, true
+	}
+	if err, ok := v.(error); ok {
+		var l Locator
+		if errors.As(err, &l) {
+			return l, true
+		}
+	}
+	return nil, false
+}
+
+// locatedArg formats its value as a nested hyperlink to url, then switches
+// the link back to outer (the call site link surrounding the whole line).
+type locatedArg struct {
+	v     interface{}
+	url   string
+	outer string
+}
+
+func (a locatedArg) Format(f fmt.State, verb rune) {
+	term := cfg().Terminal
+	fmt.Fprint(f, term.osc8(a.url))
+	fmt.Fprintf(f, fmt.FormatString(f, verb), a.v)
+	fmt.Fprint(f, term.osc8(a.outer))
+}
+
+// linkText is the text of a link, formatted as a string with the verb %L.
+type linkText string
+
+func (t linkText) Format(f fmt.State, verb rune) {
+	fmt.Fprintf(f, fmt.FormatString(f, stringVerb(verb)), string(t))
+}
+
+// locateArgs returns args with every Locator, and every link returned by
+// Link, replaced by a locatedArg, and whether there were any. args itself
+// is not modified.
+func locateArgs(args []interface{}, outer string) ([]interface{}, bool) {
+	var out []interface{}
+	for i, arg := range args {
+		if link, ok := arg.(LinkedText); ok && link.URL != "" {
+			if out == nil {
+				out = append([]interface{}(nil), args...)
+			}
+			out[i] = locatedArg{v: linkText(link.Text), url: link.URL, outer: outer}
+			continue
+		}
+		l, ok := locate(arg)
+		if !ok {
+			continue
+		}
+		if out == nil {
+			out = append([]interface{}(nil), args...)
+		}
+		file, line := l.Location()
+		out[i] = locatedArg{v: arg, url: FormatURL(file, line), outer: outer}
+	}
+	if out == nil {
+		return args, false
+	}
+	return out, true
+}
+
+// locatedError is an error that records where it was created.
+type locatedError struct {
+	err  error
+	file string
+	line int
+}
+
+func (e *locatedError) Error() string                     { return e.err.Error() }
+func (e *locatedError) Unwrap() error                     { return e.err }
+func (e *locatedError) Location() (file string, line int) { return e.file, e.line }
+
+// Errorf is like fmt.Errorf, but the returned error implements Locator,
+// pointing at the line that called Errorf.
+func Errorf(format string, args ...interface{}) error {
+	err := fmt.Errorf(format, args...)
+	_, file, line, _, ok := caller(1)
+	if !ok {
+		return err
+	}
+	return &locatedError{err: err, file: file, line: line}
+}
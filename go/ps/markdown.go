@@ -0,0 +1,114 @@
+package ps
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// MarkdownOption configures WriteMarkdown.
+type MarkdownOption func(*markdownOptions)
+
+type markdownOptions struct {
+	repo, rev, root string
+}
+
+// GitHubLinks links entries printed from files under root, a checkout of
+// the GitHub repository repo, such as "https://github.com/owner/name", to
+// their lines at revision rev, a commit or tag, so that the links work for
+// anyone reading the Markdown. Entries from other files keep editor links.
+func GitHubLinks(repo, rev, root string) MarkdownOption {
+	return func(o *markdownOptions) {
+		o.repo, o.rev, o.root = strings.TrimSuffix(repo, "/"), rev, root
+	}
+}
+
+// WriteMarkdown writes entries, such as those read by ReadEntries or
+// captured by pstest.Capture, to w as a Markdown list, one item per
+// entry, for pasting a debugging session into an issue or pull request.
+// The first line of each message links to its source location, with the
+// current link format or as set by GitHubLinks; further lines, such as
+// those of dumps, follow in a fenced code block.
+func WriteMarkdown(w io.Writer, entries []Entry, opts ...MarkdownOption) error {
+	var o markdownOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	bw := bufio.NewWriter(w)
+	for _, e := range entries {
+		first, rest, _ := strings.Cut(stripEscapes(e.Msg), "\n")
+		bw.WriteString("- `")
+		bw.Write(appendTimestamp(nil, e.Elapsed))
+		bw.WriteString("` ")
+		bw.WriteString(e.Tag.prefix())
+		text := escapeMarkdown(first)
+		if text == "" {
+			text = "…"
+		}
+		if e.File != "" {
+			text = "[" + text + "](<" + o.url(e.File, e.Line) + ">)"
+		}
+		bw.WriteString(text)
+		if len(e.Fields) > 0 {
+			bw.WriteString(" " + codeSpan(strings.TrimPrefix(formatFields(e.Fields), " ")))
+		}
+		bw.WriteByte('\n')
+		if rest != "" {
+			fence := strings.Repeat("`", max(3, longestRun(rest, '`')+1))
+			bw.WriteString("\n  " + fence + "\n")
+			for _, line := range strings.Split(strings.TrimSuffix(rest, "\n"), "\n") {
+				bw.WriteString("  " + line + "\n")
+			}
+			bw.WriteString("  " + fence + "\n\n")
+		}
+	}
+	return bw.Flush()
+}
+
+// url returns the link to file:line: on GitHub, if set with GitHubLinks
+// and file is under its root, or else in the current link format.
+func (o markdownOptions) url(file string, line int) string {
+	if o.repo != "" {
+		if url, ok := githubBlobURL(o.repo, o.rev, o.root, file, line); ok {
+			return url
+		}
+	}
+	return FormatURL(file, line)
+}
+
+// escapeMarkdown escapes the characters of text that Markdown would take
+// as formatting or as the end of a link.
+func escapeMarkdown(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if strings.ContainsRune("\\`*_[]<>#|~", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// codeSpan returns text as a Markdown code span, delimited by enough
+// backticks that those in text do not end it.
+func codeSpan(text string) string {
+	ticks := strings.Repeat("`", longestRun(text, '`')+1)
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		text = " " + text + " "
+	}
+	return ticks + text + ticks
+}
+
+// longestRun returns the length of the longest run of c in text.
+func longestRun(text string, c byte) int {
+	longest, n := 0, 0
+	for i := 0; i < len(text); i++ {
+		if text[i] == c {
+			n++
+			longest = max(longest, n)
+		} else {
+			n = 0
+		}
+	}
+	return longest
+}
@@ -0,0 +1,138 @@
+package ps
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Source is a named sequence of entries, such as those recorded by one
+// process, as merged by Merge.
+type Source struct {
+	Label   string
+	Entries []Entry
+	// Offset is added to the times of the entries when merging, to
+	// correct for the skew between the source's clock and the others'.
+	Offset time.Duration
+}
+
+// LabeledEntry is an entry merged from a Source, as returned by Merge.
+type LabeledEntry struct {
+	Entry
+	// Label is the label of the source.
+	Label string
+	// Style is the color of the source's label.
+	Style Style
+
+	width int
+}
+
+// sourceStyles are the colors of source labels, in turn.
+var sourceStyles = []Style{"\x1b[36m", "\x1b[35m", "\x1b[34m", "\x1b[32m", "\x1b[33m", "\x1b[96m", "\x1b[95m", "\x1b[94m"}
+
+// Merge interleaves the entries of sources by wall-clock time into one
+// stream, such as the logs of a client and a server, so that a flow
+// across processes reads in order. Entries with the same time keep the
+// order of their sources. Each source's entries are shifted by its
+// Offset; see also AlignSources. The elapsed times of the entries are made
+// relative to the earliest entry, since the sources' timers differ.
+func Merge(sources ...Source) []LabeledEntry {
+	var merged []LabeledEntry
+	width := 0
+	for i, src := range sources {
+		width = max(width, visibleWidth(src.Label))
+		style := sourceStyles[i%len(sourceStyles)]
+		for _, e := range src.Entries {
+			e.Time = e.Time.Add(src.Offset)
+			merged = append(merged, LabeledEntry{Entry: e, Label: src.Label, Style: style})
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Time.Before(merged[j].Time)
+	})
+	for i := range merged {
+		merged[i].width = width
+		if i > 0 {
+			merged[i].Elapsed = merged[i].Time.Sub(merged[0].Time)
+		} else {
+			merged[i].Elapsed = 0
+		}
+	}
+	return merged
+}
+
+// String renders e as Entry.String does, after its label in its color,
+// padded to the width of the longest label merged with it.
+func (e LabeledEntry) String() string {
+	label := e.Label + strings.Repeat(" ", max(0, e.width-visibleWidth(e.Label)))
+	return e.Style.Render(label) + " " + e.Entry.String()
+}
+
+// AlignSources sets the Offset of each source after the first from the
+// entries it shares with the first, those with the same value of the field
+// key, such as a request ID. Each such flow is taken to start in the first
+// source and to be handled within it in the other, as with a client and a
+// server: the offset is the one closest to 0 that puts the other source's
+// entries for each flow between the first and last of the first source's.
+// If the flows disagree, it is the midpoint of the offsets they bound.
+// Sources sharing no values, and those with a nonzero Offset already, are
+// left as they are.
+func AlignSources(key string, sources []Source) {
+	if len(sources) < 2 {
+		return
+	}
+	refFirst, refLast := fieldSpans(key, sources[0].Entries)
+	for i := 1; i < len(sources); i++ {
+		if sources[i].Offset != 0 {
+			continue
+		}
+		first, last := fieldSpans(key, sources[i].Entries)
+		var lo, hi time.Duration
+		shared := false
+		for v, t := range first {
+			rf, ok := refFirst[v]
+			if !ok {
+				continue
+			}
+			l, h := rf.Sub(t), refLast[v].Sub(last[v])
+			if !shared {
+				lo, hi, shared = l, h, true
+				continue
+			}
+			lo, hi = max(lo, l), min(hi, h)
+		}
+		if !shared {
+			continue
+		}
+		switch {
+		case lo > hi:
+			sources[i].Offset = lo + (hi-lo)/2
+		case lo > 0:
+			sources[i].Offset = lo
+		case hi < 0:
+			sources[i].Offset = hi
+		}
+	}
+}
+
+// fieldSpans returns the times of the first and last entries with each
+// value of the field key.
+func fieldSpans(key string, entries []Entry) (first, last map[string]time.Time) {
+	first, last = map[string]time.Time{}, map[string]time.Time{}
+	for _, e := range entries {
+		for _, f := range e.Fields {
+			if f.Key != key {
+				continue
+			}
+			v := fmt.Sprint(f.Value)
+			if t, ok := first[v]; !ok || e.Time.Before(t) {
+				first[v] = e.Time
+			}
+			if t, ok := last[v]; !ok || e.Time.After(t) {
+				last[v] = e.Time
+			}
+		}
+	}
+	return first, last
+}
@@ -0,0 +1,57 @@
+package ps
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// notifiedFailure records whether a failure notification has been sent.
+var notifiedFailure atomic.Bool
+
+// Notify asks the terminal to show a desktop notification with msg, in
+// the style set by NotifyStyle. Terminals without support ignore it.
+func Notify(msg string) {
+	write(notification(msg))
+}
+
+// notification returns the escape sequence for a notification with msg.
+func notification(msg string) string {
+	msg = oneLine(msg)
+	switch cfg().NotifyStyle {
+	case "osc777":
+		return "\x1b]777;notify;hyperlinked;" + strings.ReplaceAll(msg, ";", ",") + "\x1b\\"
+	case "osc99":
+		return "\x1b]99;;" + msg + "\x1b\\"
+	default:
+		return "\x1b]9;" + msg + "\x07"
+	}
+}
+
+// failureNotification returns the notification to attach to e, if any.
+func failureNotification(e Entry) string {
+	if e.Tag != Failure {
+		return ""
+	}
+	switch cfg().NotifyOnFailure {
+	case "all":
+	case "first":
+		if !notifiedFailure.CompareAndSwap(false, true) {
+			return ""
+		}
+	default:
+		return ""
+	}
+	return notification("❌ " + e.Msg)
+}
+
+// oneLine returns msg without escape sequences, and with control
+// characters, such as newlines, replaced by spaces, for use in an escape
+// sequence.
+func oneLine(msg string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return ' '
+		}
+		return r
+	}, stripEscapes(msg))
+}
@@ -0,0 +1,85 @@
+package ps
+
+import (
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// outputWriter holds the writer set by SetOutput.
+type outputWriter struct {
+	w io.Writer
+}
+
+var (
+	output  atomic.Pointer[outputWriter]
+	writeMu sync.Mutex
+)
+
+// SetOutput sets the destination for all output printed by this package.
+// A nil w restores the default, os.Stdout. It is safe to call concurrently
+// with printing; writes are serialized, so w need not be safe for
+// concurrent use.
+func SetOutput(w io.Writer) {
+	if w == nil {
+		output.Store(nil)
+		return
+	}
+	output.Store(&outputWriter{w: w})
+}
+
+// Output returns the destination set by SetOutput, or os.Stdout.
+func Output() io.Writer {
+	if o := output.Load(); o != nil {
+		return o.w
+	}
+	return os.Stdout
+}
+
+// write writes s to the output, returning the error, if any, as passed
+// to the function set by SetErrorHandler.
+func write(s string) error {
+	w := Output()
+	writeMu.Lock()
+	_, err := io.WriteString(w, s)
+	writeMu.Unlock()
+	return writeFailed(nil, err)
+}
+
+// writeBytes is like write for a byte slice.
+func writeBytes(b []byte) error {
+	w := Output()
+	writeMu.Lock()
+	_, err := w.Write(b)
+	writeMu.Unlock()
+	return writeFailed(nil, err)
+}
+
+// bufPool holds the buffers lines are rendered into before being written.
+var bufPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 256)
+		return &b
+	},
+}
+
+// maxPooledBuf is the capacity above which buffers are not returned to
+// bufPool, so that one long line does not pin a large buffer.
+const maxPooledBuf = 64 << 10
+
+// getBuf returns an empty buffer from bufPool.
+func getBuf() *[]byte {
+	bp := bufPool.Get().(*[]byte)
+	*bp = (*bp)[:0]
+	return bp
+}
+
+// putBuf returns bp to bufPool, with b, the buffer it held grown by
+// appending, in its place.
+func putBuf(bp *[]byte, b []byte) {
+	if cap(b) <= maxPooledBuf {
+		*bp = b
+		bufPool.Put(bp)
+	}
+}
@@ -0,0 +1,95 @@
+package ps
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime/debug"
+)
+
+// panicMonitorEnv is set in the environment of the process started by
+// InstallPanicHook to watch for crashes.
+const panicMonitorEnv = "HYPERLINKED_PANIC_MONITOR"
+
+// InstallPanicHook makes the report of a crash of the program, such as an
+// unrecovered panic on any goroutine, be printed again to stderr with its
+// source locations hyperlinked. Go runs nothing in the crashing process
+// once a panic reaches the top of a goroutine's stack, so InstallPanicHook
+// starts a second instance of the program as a monitor, to which the
+// runtime also writes the report, as set up by debug.SetCrashOutput; the
+// monitor prints it linked after the runtime's plain report, and exits
+// silently when the program exits without crashing. Call it first thing
+// in main, so that crashes from the start are reported:
+//
+//	func main() {
+//		if err := ps.InstallPanicHook(); err != nil {
+//			log.Print(err)
+//		}
+//		...
+//	}
+//
+// The monitor is the same executable, so it must not do what the program
+// does. It finds out that it is the monitor as this package is
+// initialized, before the init functions of this package, of the
+// packages importing it and of main run, and does nothing else. The
+// packages initialized before this one, which are those it imports and
+// others that do not import it, as ordered by the toolchain, still run
+// their init functions in the monitor: a program installing the hook must
+// not have such a package whose initialization has side effects, such as
+// writing files, connecting to servers or starting processes.
+//
+// For a panic on the main goroutine, Recover also runs the exit hooks and
+// prints a Failure line linked to where it happened.
+func InstallPanicHook() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("ps: installing panic hook: %w", err)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("ps: installing panic hook: %w", err)
+	}
+	defer w.Close()
+	cmd := exec.Command(exe)
+	cmd.Env = append(os.Environ(), panicMonitorEnv+"=1")
+	cmd.Stdin = r
+	cmd.Stderr = os.Stderr
+	err = cmd.Start()
+	r.Close()
+	if err != nil {
+		return fmt.Errorf("ps: installing panic hook: %w", err)
+	}
+	// The runtime keeps its own copy of w, the monitor's only writer,
+	// whose closing as the program exits ends the monitor.
+	if err := debug.SetCrashOutput(w, debug.CrashOptions{}); err != nil {
+		cmd.Process.Kill()
+		return fmt.Errorf("ps: installing panic hook: %w", err)
+	}
+	return nil
+}
+
+// _ runs the monitor in the process started by InstallPanicHook. As the
+// initializer of a package variable, it runs before any init function of
+// this package.
+var _ = func() bool {
+	if os.Getenv(panicMonitorEnv) == "1" {
+		monitorPanics()
+	}
+	return false
+}()
+
+// monitorPanics runs the monitor started by InstallPanicHook: it prints
+// the crash report written to stdin, if any, linked to stderr, and exits.
+// It runs with the settings given by the environment, as the init
+// function storing them has not run.
+func monitorPanics() {
+	s := envSettings()
+	current.Store(&s)
+	report, _ := io.ReadAll(os.Stdin)
+	if len(report) > 0 {
+		header := CurrentTheme().Levels[LevelError].Render("❌ crash report, linked:")
+		fmt.Fprint(os.Stderr, "\n"+header+"\n"+Linkify(string(report)))
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,241 @@
+package ps
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// fmt recovers from panics in the String, Error, GoString and Format
+// methods of the arguments it formats, rendering them as, for example,
+// "%!v(PANIC=String method: boom)", so that printing never panics.
+// appendAt instead has the arguments with such methods formatted by
+// safeArg, which calls the methods itself, so that it can say what
+// happened plainly and link to where the method panicked, found as it
+// happens. The arguments are also escaped there, as set by
+// Settings.Sanitize (see sanitize.go).
+
+// safeArgs returns args with the values that have methods fmt calls, and
+// the values whose formatting may hold bytes that the terminal acts on if
+// escape, replaced by safeArg values rendering panics linked with the link
+// returning to outer, or not linked if outer is "", and whether there were
+// any. The values linked by locateArgs are replaced inside their links.
+// args itself is not modified.
+func safeArgs(args []interface{}, outer string, escape bool) ([]interface{}, bool) {
+	var out []interface{}
+	for i, arg := range args {
+		var v interface{}
+		switch a := arg.(type) {
+		case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr,
+			float32, float64, complex64, complex128, time.Duration, time.Time, RelativeTime:
+			continue
+		case string:
+			if !escape || !unsafeText(a, false) {
+				continue
+			}
+			v = safeArg{v: arg, escape: true}
+		case []byte:
+			if !escape || !unsafeText(a, false) {
+				continue
+			}
+			v = safeArg{v: arg, escape: true}
+		case LinkedText:
+			if !escape || !unsafeText(a.Text, false) {
+				continue
+			}
+			a.Text = string(appendSanitized(nil, a.Text, false))
+			v = a
+		case styledText:
+			if !escape || !unsafeText(a.text, false) {
+				continue
+			}
+			a.text = string(appendSanitized(nil, a.text, false))
+			v = a
+		case locatedArg:
+			if !escape && !hasMethods(a.v) {
+				continue
+			}
+			a.v = safeArg{v: a.v, outer: a.url, escape: escape}
+			v = a
+		default:
+			if !escape && !hasMethods(arg) {
+				continue
+			}
+			v = safeArg{v: arg, outer: outer, escape: escape}
+		}
+		if out == nil {
+			out = append([]interface{}(nil), args...)
+		}
+		out[i] = v
+	}
+	if out == nil {
+		return args, false
+	}
+	return out, true
+}
+
+// hasMethods reports whether fmt may call methods of v to format it.
+func hasMethods(v interface{}) bool {
+	switch v.(type) {
+	case fmt.Formatter, fmt.GoStringer, error, fmt.Stringer:
+		return true
+	}
+	return false
+}
+
+// safeArg formats its value as fmt does, but with a panic in a method of
+// the value rendered as "<panic in String(): boom>", in the error style of
+// the theme and linked to where it panicked, after which the link returns
+// to outer, unless outer is "". If escape, the bytes that the terminal would act on, escape
+// sequences included, are escaped.
+type safeArg struct {
+	v      interface{}
+	outer  string
+	escape bool
+}
+
+func (a safeArg) Format(f fmt.State, verb rune) {
+	bp := getBuf()
+	b, p := appendSafe(*bp, f, verb, a.v)
+	switch {
+	case p != nil:
+		f.Write([]byte(p.render(a.outer)))
+	case a.escape && unsafeText(b, false):
+		f.Write(appendSanitized(nil, string(b), false))
+	default:
+		f.Write(b)
+	}
+	putBuf(bp, b)
+}
+
+// safeSprint returns v formatted as fmt.Sprint does, with a panic in a
+// method of v rendered plainly, as "<panic in String(): boom>".
+func safeSprint(v interface{}) string {
+	b, p := appendSafe(nil, plainState{}, 'v', v)
+	if p != nil {
+		return p.text()
+	}
+	return string(b)
+}
+
+// panicInfo describes a panic in a method of a formatted value.
+type panicInfo struct {
+	method string
+	value  string
+	file   string
+	line   int
+	ok     bool
+}
+
+// text returns the plain rendering of the panic.
+func (p *panicInfo) text() string {
+	value := p.value
+	if cfg().Sanitize && unsafeText(value, false) {
+		value = string(appendSanitized(nil, value, false))
+	}
+	return "<panic in " + p.method + "(): " + value + ">"
+}
+
+// render returns the rendering of the panic in the error style of the
+// theme, linked to where it happened, after which the link returns to
+// outer, unless outer is "".
+func (p *panicInfo) render(outer string) string {
+	text := CurrentTheme().Levels[LevelError].Render(p.text())
+	if !p.ok || outer == "" {
+		return text
+	}
+	term := cfg().Terminal
+	if url := FormatURL(p.file, p.line); term.linkable(url) {
+		text = term.osc8(url) + text + term.osc8(outer)
+	}
+	return text
+}
+
+// appendSafe appends v, formatted as fmt formats it with verb and the
+// flags, width and precision of f, to b, calling the methods of v that fmt
+// would. If one of them panics, b is returned as it was with a description
+// of the panic, except that, as for fmt, a nil pointer whose method panics
+// is formatted as "<nil>".
+func appendSafe(b []byte, f fmt.State, verb rune, v interface{}) (out []byte, p *panicInfo) {
+	method := ""
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+			out = append(b, "<nil>"...)
+			return
+		}
+		file, line, ok := panicFrame()
+		out = b
+		p = &panicInfo{method: method, value: safeSprint(r), file: file, line: line, ok: ok}
+	}()
+	if x, ok := v.(fmt.Formatter); ok {
+		method = "Format"
+		s := &bufState{State: f, b: b}
+		x.Format(s, verb)
+		return s.b, nil
+	}
+	if f.Flag('#') && verb == 'v' {
+		if x, ok := v.(fmt.GoStringer); ok {
+			method = "GoString"
+			return fmt.Appendf(b, fmt.FormatString(f, 's'), x.GoString()), nil
+		}
+	} else if strings.ContainsRune("vsxXq", verb) {
+		switch x := v.(type) {
+		case error:
+			method = "Error"
+			return fmt.Appendf(b, fmt.FormatString(f, verb), x.Error()), nil
+		case fmt.Stringer:
+			method = "String"
+			return fmt.Appendf(b, fmt.FormatString(f, verb), x.String()), nil
+		}
+	}
+	return fmt.Appendf(b, fmt.FormatString(f, verb), v), nil
+}
+
+// panicFrame returns the location of the panic being recovered from by
+// the deferred function calling panicFrame: that of the first frame below
+// runtime.gopanic outside the runtime.
+func panicFrame() (file string, line int, ok bool) {
+	var pcs [32]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs[:])])
+	panicking := false
+	for {
+		frame, more := frames.Next()
+		switch {
+		case frame.Function == "runtime.gopanic":
+			panicking = true
+		case panicking && !strings.HasPrefix(frame.Function, "runtime."):
+			return frame.File, frame.Line, true
+		}
+		if !more {
+			return "", 0, false
+		}
+	}
+}
+
+// bufState is a fmt.State appending what is written to it to b, with the
+// flags, width and precision of State.
+type bufState struct {
+	fmt.State
+	b []byte
+}
+
+func (s *bufState) Write(b []byte) (int, error) {
+	s.b = append(s.b, b...)
+	return len(b), nil
+}
+
+// plainState is a fmt.State without flags, width or precision, for
+// formatting with appendSafe as fmt.Sprint does. It discards what is
+// written to it.
+type plainState struct{}
+
+func (plainState) Write(b []byte) (int, error) { return len(b), nil }
+func (plainState) Width() (int, bool)          { return 0, false }
+func (plainState) Precision() (int, bool)      { return 0, false }
+func (plainState) Flag(int) bool               { return false }
@@ -0,0 +1,133 @@
+package ps
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// panicky is a value whose methods panic with its message, counting the
+// calls and recording the line of the last panic.
+type panicky struct {
+	msg   string
+	calls *int
+	line  *int
+}
+
+func (v panicky) String() string {
+	*v.calls++
+	_, _, line, _ := runtime.Caller(0)
+	*v.line = line + 2 // the line of the panic
+	panic(v.msg)
+}
+
+// newPanicky returns a panicky panicking with msg.
+func newPanicky(msg string) panicky {
+	return panicky{msg: msg, calls: new(int), line: new(int)}
+}
+
+// panickyError is an error whose Error method panics.
+type panickyError struct{}
+
+func (panickyError) Error() string { panic(errors.New("no message")) }
+
+// panickyFormatter is a fmt.Formatter whose Format method panics after
+// writing.
+type panickyFormatter struct{}
+
+func (panickyFormatter) Format(f fmt.State, verb rune) {
+	fmt.Fprint(f, "half")
+	panic("bad verb")
+}
+
+// nilStringer panics in String when it is nil.
+type nilStringer struct{ s string }
+
+func (v *nilStringer) String() string { return v.s }
+
+func TestSafeArgsPanic(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		format string
+		arg    interface{}
+		want   string
+	}{
+		{"String", "%v", newPanicky("boom"), "<panic in String(): boom>"},
+		{"parentheses", "%v", newPanicky("bad (x) value)"), "<panic in String(): bad (x) value)>"},
+		{"Error", "%v", panickyError{}, "<panic in Error(): no message>"},
+		{"Format", "%d", panickyFormatter{}, "<panic in Format(): bad verb>"},
+		{"width", "[%6s]", newPanicky("x"), "[<panic in String(): x>]"},
+		{"nil pointer", "%v", (*nilStringer)(nil), "<nil>"},
+		{"pointer", "%s", &nilStringer{"ok"}, "ok"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			args, _ := safeArgs([]interface{}{tt.arg}, "", false)
+			if got := stripEscapes(fmt.Sprintf(tt.format, args...)); got != tt.want {
+				t.Errorf("formatted %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPanicCallsMethodOnce(t *testing.T) {
+	s := testCaller(t)
+	v := newPanicky("boom")
+	F("value %v\n", v)
+	if *v.calls != 1 {
+		t.Errorf("String called %d times, want 1", *v.calls)
+	}
+	if got, want := s.last().Msg, "value <panic in String(): boom>"; got != want {
+		t.Errorf("Msg = %q, want %q", got, want)
+	}
+}
+
+func TestPanicLink(t *testing.T) {
+	configure(t, func(s *Settings) { s.Terminal = Terminals["generic"] })
+	var out bytes.Buffer
+	SetOutput(&out)
+	t.Cleanup(func() { SetOutput(nil) })
+	s := sink(t)
+
+	_, file, _, _ := runtime.Caller(0)
+	v := newPanicky("boom")
+	// link is the linked panic, returning to the link of the last line.
+	link := func() string {
+		term := cfg().Terminal
+		return term.osc8(FormatURL(file, *v.line)) + CurrentTheme().Levels[LevelError].Render("<panic in String(): boom>") +
+			term.osc8(siteURL(s.last().File, s.last().Line))
+	}
+
+	F("value %v\n", v)
+	if want := "value " + link(); !strings.Contains(out.String(), want) {
+		t.Errorf("wrote %q, want it to contain %q", out.String(), want)
+	}
+
+	out.Reset()
+	With("k", v).F("field\n")
+	if want := " k=" + link(); !strings.Contains(out.String(), want) {
+		t.Errorf("wrote %q, want it to contain %q", out.String(), want)
+	}
+}
+
+func TestFieldStringPanic(t *testing.T) {
+	f := Field{"k", newPanicky("boom")}
+	if got, want := f.String(), "k=<panic in String(): boom>"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+// unmarshalable is a value json cannot encode, whose String method panics.
+type unmarshalable struct {
+	C chan int
+	panicky
+}
+
+func TestMarshalFieldsPanic(t *testing.T) {
+	v := unmarshalable{panicky: newPanicky("boom")}
+	if got, want := string(MarshalFields([]Field{{"k", v}})), `{"k":"\u003cpanic in String(): boom\u003e"}`; got != want {
+		t.Errorf("MarshalFields() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,54 @@
+package ps
+
+import (
+	"strconv"
+	"time"
+)
+
+// SetPrecision sets the resolution of the timestamp column to
+// time.Millisecond (the default), time.Microsecond or time.Nanosecond;
+// other values are rounded down to one of these. Finer resolutions add a
+// fraction of a millisecond, as in "[   12.345]" for microseconds. Set via
+// HYPERLINKED_PRECISION=ms, us or ns.
+func SetPrecision(d time.Duration) {
+	Configure(func(s *Settings) { s.Precision = d })
+}
+
+// Precision returns the resolution set by SetPrecision, rounded down.
+func Precision() time.Duration {
+	switch d := cfg().Precision; {
+	case d >= time.Millisecond:
+		return time.Millisecond
+	case d >= time.Microsecond:
+		return time.Microsecond
+	default:
+		return time.Nanosecond
+	}
+}
+
+// appendTimestamp appends d as the timestamp column to b: the whole
+// milliseconds as "[%5d]", with the fraction of a millisecond to the
+// resolution set by SetPrecision before the "]".
+func appendTimestamp(b []byte, d time.Duration) []byte {
+	var num [20]byte
+	n := strconv.AppendInt(num[:0], d.Milliseconds(), 10)
+	b = append(b, '[')
+	for i := len(n); i < 5; i++ {
+		b = append(b, ' ')
+	}
+	b = append(b, n...)
+	if p := Precision(); p < time.Millisecond {
+		digits := 3
+		if p == time.Nanosecond {
+			digits = 6
+		}
+		frac := (d % time.Millisecond).Abs() / p
+		n = strconv.AppendInt(num[:0], int64(frac), 10)
+		b = append(b, '.')
+		for i := len(n); i < digits; i++ {
+			b = append(b, '0')
+		}
+		b = append(b, n...)
+	}
+	return append(b, ']')
+}
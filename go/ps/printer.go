@@ -0,0 +1,538 @@
+package ps
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Printer prints like the package-level functions, with its own settings.
+// Printers are created by functions such as Sample and are cheap enough to
+// create at each call:
+//
+//	ps.Sample(0.01).F("got %d\n", n)
+type Printer struct {
+	// off disables the printer entirely.
+	off bool
+	// level is the level of the entries printed.
+	level Level
+	// sample is the fraction of hits at each call site printed, or 0 to
+	// use the global rate.
+	sample float64
+	// fields are attached to the entries printed.
+	fields []Field
+	// skip is the number of additional stack frames to skip when looking
+	// up the caller.
+	skip int
+	// site, if set, is the location linked to instead of the caller.
+	site *callSite
+	// seq, if set, counts the lines printed, as set by Seq.
+	seq *atomic.Uint64
+	// labels, if set, are the pprof labels shown, as set by Labels.
+	labels []Field
+	// urlFunc, if set, replaces FormatURL, as set by WithURLFunc.
+	urlFunc func(file string, line int) string
+	// wrapFunc, if set, replaces FormatOSC8, as set by WithWrapFunc.
+	wrapFunc func(text, url string) string
+	// target, if set, is the link added to the end of lines, as set by
+	// WithTarget.
+	target *LinkedText
+}
+
+// std is the printer used by the package-level functions.
+var std = &Printer{}
+
+// If returns a printer that prints only if cond is true. When cond is
+// false nothing is evaluated beyond the arguments themselves; in
+// particular the caller is not looked up.
+func If(cond bool) *Printer {
+	return std.If(cond)
+}
+
+// At returns a printer that prints entries at level l.
+func At(l Level) *Printer {
+	return std.At(l)
+}
+
+// WithSkip returns a printer that links to the caller n frames further up
+// the stack, for use in wrappers: with n = 1, lines link to the caller of
+// the function calling the printer. With n = Auto, lines link to the first
+// caller outside this module and helper packages. See also Helper.
+func WithSkip(n int) *Printer {
+	return std.WithSkip(n)
+}
+
+// Here returns a printer whose lines link to the caller of Here, wherever
+// they are printed from. Use it for lines printed on behalf of the caller
+// by other goroutines:
+//
+//	p := ps.Here()
+//	go func() {
+//		for msg := range msgs {
+//			p.F("got %s\n", msg)
+//		}
+//	}()
+func Here() *Printer {
+	return std.here(1)
+}
+
+// With returns a printer that attaches the field key=value to the entries
+// it prints. Fields are shown after the message and stored by sinks:
+//
+//	ps.With("order", id).F("saved\n")
+func With(key string, value interface{}) *Printer {
+	return std.With(key, value)
+}
+
+// WithURLFunc returns a printer whose lines link to the URL f returns for
+// their source location, in place of the one FormatURL returns, for
+// embedders with their own link semantics, such as code browsers. A nil f
+// restores FormatURL.
+func WithURLFunc(f func(file string, line int) string) *Printer {
+	return std.WithURLFunc(f)
+}
+
+// WithWrapFunc returns a printer that writes each line as f returns it,
+// given the rendered line, without hyperlinks or trailing newline, and its
+// URL, or "" if the caller is unknown, in place of the line wrapped in OSC8
+// escape codes by FormatOSC8, for embedders that link text their own way,
+// such as notebook frontends. The timestamp, layout and truncation of the
+// line are as usual. A nil f restores FormatOSC8:
+//
+//	p := ps.WithWrapFunc(func(text, url string) string {
+//		return `<a href="` + url + `">` + html.EscapeString(text) + "</a>"
+//	})
+func WithWrapFunc(f func(text, url string) string) *Printer {
+	return std.WithWrapFunc(f)
+}
+
+// WithTarget returns a printer that ends each line with "↗ label" linked
+// to url, such as the trace of the request being handled, while the rest
+// of the line links to the call site as usual. Lines too wide for the
+// terminal are cut before the target, so that it stays visible:
+//
+//	p := ps.WithTarget("trace", jaegerURL+"/trace/"+traceID)
+//	p.F("handled %s\n", req.URL.Path)
+func WithTarget(label, url string) *Printer {
+	return std.WithTarget(label, url)
+}
+
+// Enabled reports whether entries at level l are printed. Use it to guard
+// expensive argument construction:
+//
+//	if ps.Enabled(ps.LevelDebug) {
+//		ps.At(ps.LevelDebug).F("state: %s\n", dump(state))
+//	}
+func Enabled(l Level) bool {
+	return l >= MinLevel()
+}
+
+// If returns a copy of p that prints only if cond is true.
+func (p *Printer) If(cond bool) *Printer {
+	q := *p
+	q.off = q.off || !cond
+	return &q
+}
+
+// At returns a copy of p that prints entries at level l.
+func (p *Printer) At(l Level) *Printer {
+	q := *p
+	q.level = l
+	return &q
+}
+
+// Sample returns a copy of p that prints the given fraction of hits at
+// each call site. See the package-level Sample.
+func (p *Printer) Sample(rate float64) *Printer {
+	q := *p
+	q.sample = rate
+	return &q
+}
+
+// WithSkip returns a copy of p that skips n more stack frames.
+func (p *Printer) WithSkip(n int) *Printer {
+	q := *p
+	if n == Auto || q.skip == Auto {
+		q.skip = Auto
+	} else {
+		q.skip += n
+	}
+	return &q
+}
+
+// WithURLFunc returns a copy of p that links lines to the URLs f returns.
+// See the package-level WithURLFunc.
+func (p *Printer) WithURLFunc(f func(file string, line int) string) *Printer {
+	q := *p
+	q.urlFunc = f
+	return &q
+}
+
+// WithWrapFunc returns a copy of p that writes lines wrapped by f. See the
+// package-level WithWrapFunc.
+func (p *Printer) WithWrapFunc(f func(text, url string) string) *Printer {
+	q := *p
+	q.wrapFunc = f
+	return &q
+}
+
+// WithTarget returns a copy of p that ends lines with a link to url. See
+// the package-level WithTarget.
+func (p *Printer) WithTarget(label, url string) *Printer {
+	q := *p
+	q.target = &LinkedText{Text: label, URL: url}
+	return &q
+}
+
+// Here returns a copy of p whose lines link to the caller of Here, taking
+// the skip of p into account. See the package-level Here.
+func (p *Printer) Here() *Printer {
+	return p.here(1)
+}
+
+func (p *Printer) here(skip int) *Printer {
+	q := *p
+	site := p.callSite(skip + 1)
+	q.site = &site
+	return &q
+}
+
+// With returns a copy of p that also attaches the field key=value.
+func (p *Printer) With(key string, value interface{}) *Printer {
+	q := *p
+	q.fields = append(p.fields[:len(p.fields):len(p.fields)], Field{Key: key, Value: value})
+	return &q
+}
+
+// Enabled reports whether p prints anything at all.
+func (p *Printer) Enabled() bool {
+	return !p.off && Enabled(p.level)
+}
+
+// F is like the package-level F.
+func (p *Printer) F(format string, args ...interface{}) {
+	p.printf(1, "", format, args)
+}
+
+// Ln is like the package-level Ln.
+func (p *Printer) Ln(args ...interface{}) {
+	p.printf(1, "", printFormat(args, true), args)
+}
+
+// printf formats and prints an entry tagged tag for the caller skip frames
+// above printf's caller (0 = printf's caller), returning the errors
+// writing it.
+func (p *Printer) printf(skip int, tag Tag, format string, args []interface{}) error {
+	autoBanner()
+	bp := getBuf()
+	b, e, ok := p.appendEntry(*bp, skip+1, tag, format, args)
+	var err error
+	if ok {
+		err = emitBytes(e, b)
+	}
+	putBuf(bp, b)
+	return err
+}
+
+// format builds the entry and its terminal rendering for the caller skip
+// frames above format's caller, reporting false if the entry is
+// suppressed by the level, filter or sampling.
+func (p *Printer) format(skip int, tag Tag, format string, args []interface{}) (Entry, string, bool) {
+	b, e, ok := p.appendEntry(nil, skip+1, tag, format, args)
+	return e, string(b), ok
+}
+
+// appendEntry is like format, but appends the rendering to b.
+func (p *Printer) appendEntry(b []byte, skip int, tag Tag, format string, args []interface{}) ([]byte, Entry, bool) {
+	if !p.prints(tag) {
+		return b, Entry{}, false
+	}
+	site := p.callSite(skip + 1)
+	return p.appendAt(b, site, newEntry(tag), format, args)
+}
+
+// newEntry returns an entry tagged tag printed now by the calling
+// goroutine, as passed to appendAt.
+func newEntry(tag Tag) Entry {
+	e := Entry{Time: time.Now(), Tag: tag}
+	e.Elapsed = elapsed(e.Time)
+	if hasSinks() || tag == Failure {
+		e.Goroutine = goroutineID()
+	}
+	return e
+}
+
+// printAt prints the entry e printed at site, as rendered by appendAt.
+func (p *Printer) printAt(site callSite, e Entry, format string, args []interface{}) {
+	autoBanner()
+	bp := getBuf()
+	b, e, ok := p.appendAt(*bp, site, e, format, args)
+	if ok {
+		emitBytes(e, b)
+	}
+	putBuf(bp, b)
+}
+
+// prints reports whether p prints entries tagged tag, as far as can be
+// told without looking up the caller.
+func (p *Printer) prints(tag Tag) bool {
+	return !p.off && Enabled(max(p.level, tag.Level())) && allowedTag(tag)
+}
+
+// callSite is a source location looked up by caller.
+type callSite struct {
+	pc       uintptr
+	file     string
+	line     int
+	funcName string
+	ok       bool
+}
+
+// callSite returns the location of the caller skip frames above
+// callSite's caller, taking the skip of p into account, or the location
+// fixed by Here.
+func (p *Printer) callSite(skip int) callSite {
+	defer addSince(&overhead.caller, statsStart())
+	if p.site != nil {
+		return *p.site
+	}
+	skip += 1 + p.skip
+	if p.skip == Auto {
+		skip = Auto
+	}
+	var c callSite
+	c.pc, c.file, c.line, c.funcName, c.ok = caller(skip)
+	return c
+}
+
+// appendAt appends the terminal rendering of an entry printed at site to
+// b, reporting false if it is suppressed by the level, filter or sampling.
+// e holds the time, elapsed time, tag and, if known, goroutine of the
+// entry; the rest is filled in. The entry's Msg is only filled in if a sink or a failure
+// notification needs it, and a single-line message is rendered without
+// intermediate strings, so that a line without arguments printed with
+// Truncate off does not allocate.
+func (p *Printer) appendAt(b []byte, site callSite, e Entry, format string, args []interface{}) ([]byte, Entry, bool) {
+	defer addSince(&overhead.format, statsStart())
+	e.Level = max(p.level, e.Tag.Level())
+	if !p.prints(e.Tag) {
+		return b, Entry{}, false
+	}
+	var dropped int64
+	if site.ok {
+		if !allowed(site.pc, site.file, site.funcName) {
+			return b, Entry{}, false
+		}
+		var keep bool
+		if keep, dropped = p.sampled(site); !keep {
+			return b, Entry{}, false
+		}
+		if !rateAllowed(site) {
+			return b, Entry{}, false
+		}
+	}
+
+	e.Fields = p.fields
+	if p.labels != nil {
+		e.Labels = shown(p.labels)
+	} else {
+		e.Labels = shown(currentLabels())
+	}
+	if p.seq != nil {
+		e.Seq = p.seq.Add(1)
+	}
+	e.Process = ProcessLabel()
+	full := hasSinks() || e.Tag == Failure || tracing() || sharing()
+	if !full {
+		_, _, full = participants(e)
+	}
+
+	url := ""
+	if site.ok {
+		e.File, e.Line, e.Func = site.file, site.line, site.funcName
+		if p.urlFunc != nil {
+			url = p.urlFunc(site.file, site.line)
+		} else {
+			url = siteURL(site.file, site.line)
+		}
+		if p.wrapFunc == nil {
+			if located, ok := locateArgs(args, url); ok {
+				args = located
+			}
+		}
+	}
+	if guarded, ok := guardArgs(format, args); ok {
+		args = guarded
+	}
+	term := cfg().Terminal
+	link := site.ok && p.wrapFunc == nil && term.linkable(url)
+	outer := ""
+	if link {
+		outer = url
+	}
+	if safe, ok := safeArgs(args, outer, cfg().Sanitize); ok {
+		args = safe
+	}
+	textStart := len(b)
+	if link {
+		b = term.appendOSC8(b, url)
+	}
+	width := lineWidth()
+	cols := cfg().Columns
+	trailer := ""
+	if site.ok && cols == nil && deadLink(site.file, site.line) {
+		// In columns, the location column is marked instead.
+		trailer = " " + deadLocation(site.file, site.line)
+	}
+	target := p.target
+	if target == nil {
+		target = traceTarget(p.fields)
+	}
+	if target != nil {
+		trailer += p.trailer(target, url)
+	}
+	if trailer != "" && width > 0 {
+		width = max(1, width-visibleWidth(trailer))
+	}
+	if cols != nil {
+		if e.Goroutine == 0 {
+			e.Goroutine = goroutineID()
+		}
+		msg := fmt.Sprintf(format, args...)
+		if oversized(len(msg)) {
+			msg = spillMessage(msg, url)
+		}
+		if full {
+			e.Msg = stripEscapes(strings.TrimSuffix(msg, "\n"))
+		}
+		msg = addSuffix(msg, formatLineFields(p.fields, outer)+formatGlobal(GlobalFields())+droppedNote(dropped))
+		if cfg().Sanitize && unsafeText(msg, true) {
+			msg = string(appendSanitized(nil, msg, true))
+		}
+		b = append(b, addTrailer(layoutColumns(cols, e, msg, width), trailer)...)
+		if link {
+			b = append(b, osc8End...)
+		}
+		return p.wrap(b, textStart, url), e, true
+	}
+	start := len(b)
+	b = appendLinePrefix(b, e)
+	msgStart := len(b)
+	b = fmt.Appendf(b, format, args...)
+	if oversized(len(b) - msgStart) {
+		b = append(b[:msgStart], spillMessage(string(b[msgStart:]), url)...)
+	}
+	newline := len(b) > msgStart && b[len(b)-1] == '\n'
+	if newline {
+		b = b[:len(b)-1]
+	}
+	if full {
+		e.Msg = stripEscapes(string(b[msgStart:]))
+	}
+	multiline := bytes.IndexByte(b[msgStart:], '\n') >= 0
+
+	b = append(b, formatLineFields(p.fields, outer)...)
+	b = append(b, formatGlobal(GlobalFields())...)
+	if dropped > 0 {
+		b = append(b, droppedNote(dropped)...)
+	}
+	if cfg().Sanitize && unsafeText(b[msgStart:], true) {
+		b = appendSanitized(b[:msgStart], string(b[msgStart:]), true)
+	}
+	if newline {
+		b = append(b, '\n')
+	}
+	if width > 0 || multiline || trailer != "" {
+		text := layoutLines(string(b[start:msgStart]), string(b[msgStart:]), width)
+		b = append(b[:start], addTrailer(text, trailer)...)
+	}
+	if link {
+		b = append(b, osc8End...)
+	}
+	return p.wrap(b, textStart, url), e, true
+}
+
+// trailer returns target, set by WithTarget or found by traceTarget, as
+// rendered at the end of a line: linked to its URL, after which the link
+// returns to outer, the link of the rest of the line.
+func (p *Printer) trailer(target *LinkedText, outer string) string {
+	text := " " + CurrentTheme().Dim.Render("↗ "+target.Text)
+	term := cfg().Terminal
+	if p.wrapFunc != nil || target.URL == "" || !term.linkable(target.URL) {
+		return text
+	}
+	return term.osc8(target.URL) + text + term.osc8(outer)
+}
+
+// addTrailer inserts trailer at the end of the first line of text.
+func addTrailer(text, trailer string) string {
+	if trailer == "" {
+		return text
+	}
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		return text[:i] + trailer + text[i:]
+	}
+	return text + trailer
+}
+
+// wrap replaces the line rendered in b from start with the line returned
+// by the function set by WithWrapFunc, if any, for url.
+func (p *Printer) wrap(b []byte, start int, url string) []byte {
+	if p.wrapFunc == nil {
+		return b
+	}
+	text, newline := strings.CutSuffix(string(b[start:]), "\n")
+	b = append(b[:start], p.wrapFunc(text, url)...)
+	if newline {
+		b = append(b, '\n')
+	}
+	return b
+}
+
+// droppedNote returns the note appended to a line printed after dropped
+// lines were dropped by sampling, or "" if none were.
+func droppedNote(dropped int64) string {
+	if dropped == 0 {
+		return ""
+	}
+	return " " + CurrentTheme().Dim.Render(fmt.Sprintf("(sampled: %d dropped)", dropped))
+}
+
+// addSuffix appends suffix to msg, before its trailing newline if any.
+func addSuffix(msg, suffix string) string {
+	if body, ok := strings.CutSuffix(msg, "\n"); ok {
+		return body + suffix + "\n"
+	}
+	return msg + suffix
+}
+
+// emit writes text, the terminal rendering of e, to the output and passes
+// e to the sinks, returning the errors writing to them.
+func emit(e Entry, text string) error {
+	defer addSince(&overhead.write, statsStart())
+	text += failureNotification(e)
+	countWritten(len(text))
+	werr := write(text)
+	serr := dispatch(e)
+	if tracing() {
+		traceLog(e)
+	}
+	recordMessage(e)
+	return joinErrors(werr, serr)
+}
+
+// emitBytes is like emit for a rendering in a buffer from getBuf.
+func emitBytes(e Entry, b []byte) error {
+	defer addSince(&overhead.write, statsStart())
+	b = append(b, failureNotification(e)...)
+	countWritten(len(b))
+	werr := writeBytes(b)
+	serr := dispatch(e)
+	if tracing() {
+		traceLog(e)
+	}
+	recordMessage(e)
+	return joinErrors(werr, serr)
+}
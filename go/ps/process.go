@@ -0,0 +1,80 @@
+package ps
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// processLabelWidth is the width of the process label column.
+const processLabelWidth = 10
+
+// SetProcessLabel shows label in a fixed-width column at the start of each
+// line, colored by the label, so that the lines of several processes
+// printing to the same terminal, such as those started by a test harness,
+// can be told apart. Labels longer than the column are cut. The label is
+// also recorded in the Process field of entries. Calling SetProcessLabel
+// with "" hides the column. The label defaults to DefaultProcessLabel:
+// that set via HYPERLINKED_LABEL env var, e.g. HYPERLINKED_LABEL=server-1,
+// or else the name of the executable. HYPERLINKED_LABEL= hides the column.
+func SetProcessLabel(label string) {
+	Configure(func(s *Settings) { s.ProcessLabel = label })
+}
+
+// ProcessLabel returns the label set by SetProcessLabel, or "".
+func ProcessLabel() string {
+	return cfg().ProcessLabel
+}
+
+// DefaultProcessLabel returns HYPERLINKED_LABEL, if set, even to "", or
+// else the base name of the executable as given by os.Args[0]. It is the
+// label set when the program starts, to be restored with:
+//
+//	ps.SetProcessLabel(ps.DefaultProcessLabel())
+func DefaultProcessLabel() string {
+	if label, ok := os.LookupEnv("HYPERLINKED_LABEL"); ok {
+		return label
+	}
+	if len(os.Args) == 0 {
+		return ""
+	}
+	return filepath.Base(os.Args[0])
+}
+
+// appendProcessLabel appends label, padded or cut to the width of the
+// column and colored by its hash, followed by a space. It appends nothing
+// if label is "".
+func appendProcessLabel(b []byte, label string) []byte {
+	if label == "" {
+		return b
+	}
+	b = processStyle(label).appendRender(b, func(b []byte) []byte {
+		return appendProcessCell(b, label)
+	})
+	return append(b, ' ')
+}
+
+// appendProcessCell appends label padded or cut to the width of the
+// column.
+func appendProcessCell(b []byte, label string) []byte {
+	w := visibleWidth(label)
+	if w > processLabelWidth {
+		return append(b, truncateToWidth(label, processLabelWidth)...)
+	}
+	b = append(b, label...)
+	for ; w < processLabelWidth; w++ {
+		b = append(b, ' ')
+	}
+	return b
+}
+
+// processStyle returns the color of label. It is chosen by a hash of the
+// label, FNV-1a, since the processes sharing a terminal cannot agree on
+// colors.
+func processStyle(label string) Style {
+	h := uint32(2166136261)
+	for i := 0; i < len(label); i++ {
+		h ^= uint32(label[i])
+		h *= 16777619
+	}
+	return sourceStyles[h%uint32(len(sourceStyles))]
+}
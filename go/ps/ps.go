@@ -2,13 +2,13 @@
 // to the source location where the print was called. Clicking the output
 // in a terminal that supports OSC8 will open your editor at that line.
 //
-// Suggested emoji prefixes for different operations:
+// Tags classify lines by operation; T renders them as emoji prefixes:
 //
 //	⤴ Sent
 //	⬅ Received
 //	⬇ Written / Created
 //	📡 Listening, long-polling
-//	⚙️ State transition
+//	⚙️ Transition (state transition)
 //	🚀 Started
 //	✅ Success
 //	❌ Failure
@@ -16,30 +16,19 @@
 //	🕐 Scheduled task execution
 //	🟢 Good
 //	🔴 Bad
-//	🟡 In progress
+//	🟡 InProgress
 package ps
 
 import (
-	"fmt"
 	"os"
-	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
-
-	"github.com/mattn/go-runewidth"
+	"unicode/utf8"
 )
 
-// LinkFormat controls the URL scheme for hyperlinks.
-// Set via HYPERLINKED_FORMAT env var.
-// Supported: "cursor" (default), "wormhole", "vscode"
-var LinkFormat = getEnvDefault("HYPERLINKED_FORMAT", "cursor")
-
-// Truncate controls whether output is truncated to terminal width.
-// Set HYPERLINKED_NO_TRUNCATE=1 to disable.
-var Truncate = os.Getenv("HYPERLINKED_NO_TRUNCATE") == ""
-
 func getEnvDefault(key, def string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -47,8 +36,28 @@ func getEnvDefault(key, def string) string {
 	return def
 }
 
-// termWidth returns the terminal width, or 0 if it cannot be determined.
+// widthFunc holds the function set by SetWidthFunc, if any.
+var widthFunc atomic.Pointer[func() int]
+
+// SetWidthFunc sets the function giving the width that output is truncated
+// to, in place of the terminal width. Applications laying out their own
+// panes, such as TUIs, can use it to fit output to a pane: the function is
+// called for each line printed, so it may report a width that changes. A
+// width of 0 or less disables truncation. A nil f restores the default.
+func SetWidthFunc(f func() int) {
+	if f == nil {
+		widthFunc.Store(nil)
+		return
+	}
+	widthFunc.Store(&f)
+}
+
+// termWidth returns the width set by SetWidthFunc, or else the terminal
+// width, or 0 if it cannot be determined.
 func termWidth() int {
+	if f := widthFunc.Load(); f != nil {
+		return (*f)()
+	}
 	if cols := os.Getenv("HYPERLINKED_COLUMNS"); cols != "" {
 		if width, err := strconv.Atoi(cols); err == nil && width > 0 {
 			return width
@@ -58,7 +67,11 @@ func termWidth() int {
 }
 
 // truncateToWidth truncates text to fit within the given width.
-// Preserves trailing newline if present. Uses "…" as ellipsis.
+// Preserves trailing newline if present. Marks the cut with the Ellipsis
+// setting.
+// Escape sequences embedded in text (colors, nested hyperlinks) take up no
+// width and are kept even when the text around them is cut, so that they
+// are always terminated.
 func truncateToWidth(text string, width int) string {
 	if width <= 0 {
 		return text
@@ -70,8 +83,7 @@ func truncateToWidth(text string, width int) string {
 		text = text[:len(text)-1]
 	}
 
-	visibleWidth := runewidth.StringWidth(text)
-	if visibleWidth <= width {
+	if visibleWidth(text) <= width {
 		if hasNewline {
 			return text + "\n"
 		}
@@ -84,7 +96,49 @@ func truncateToWidth(text string, width int) string {
 		targetWidth = 0
 	}
 
-	result := runewidth.Truncate(text, targetWidth, "…")
+	// The number of characters cut is not known until the end, so the
+	// room left for the ellipsis is that taken by the largest it could be,
+	// and escape sequences after the cut are kept apart in tail.
+	ellipsis := cfg().Ellipsis
+	var b, tail strings.Builder
+	budget := targetWidth - textWidth(formatEllipsis(ellipsis, utf8.RuneCountInString(text)))
+	cut, hidden := false, 0
+	for text != "" {
+		if n := escapeLen(text); n > 0 {
+			if cut {
+				tail.WriteString(text[:n])
+			} else {
+				b.WriteString(text[:n])
+			}
+			text = text[n:]
+			continue
+		}
+		end := strings.IndexByte(text, '\x1b')
+		if end < 0 {
+			end = len(text)
+		}
+		run := text[:end]
+		text = text[end:]
+		if cut {
+			hidden += utf8.RuneCountInString(run)
+			continue
+		}
+		if w := textWidth(run); w <= budget {
+			b.WriteString(run)
+			budget -= w
+			continue
+		}
+		kept := ""
+		if budget > 0 {
+			kept = truncateText(run, budget)
+			b.WriteString(kept)
+		}
+		hidden += utf8.RuneCountInString(run) - utf8.RuneCountInString(kept)
+		cut = true
+	}
+	b.WriteString(formatEllipsis(ellipsis, hidden))
+	b.WriteString(tail.String())
+	result := b.String()
 
 	if hasNewline {
 		return result + "\n"
@@ -92,13 +146,136 @@ func truncateToWidth(text string, width int) string {
 	return result
 }
 
+// formatEllipsis returns the ellipsis marking a cut of hidden characters,
+// with "%d" in it replaced by their number.
+func formatEllipsis(ellipsis string, hidden int) string {
+	if !strings.Contains(ellipsis, "%d") {
+		return ellipsis
+	}
+	return strings.ReplaceAll(ellipsis, "%d", strconv.Itoa(hidden))
+}
+
+// lineWidth returns the width lines are truncated to, or 0 if they are not.
+func lineWidth() int {
+	if !cfg().Truncate {
+		return 0
+	}
+	return termWidth()
+}
+
+// layoutLines joins prefix and msg, indenting continuation lines of msg if
+// AlignContinuation is set and truncating each line to width if it is
+// positive.
+func layoutLines(prefix, msg string, width int) string {
+	body, hasNewline := strings.CutSuffix(msg, "\n")
+	if !strings.Contains(body, "\n") {
+		if width > 0 {
+			return truncateToWidth(prefix+msg, width)
+		}
+		return prefix + msg
+	}
+	indent := ""
+	if cfg().AlignContinuation {
+		indent = strings.Repeat(" ", visibleWidth(prefix))
+	}
+	lines := strings.Split(body, "\n")
+	for i := range lines {
+		if i == 0 {
+			lines[i] = prefix + lines[i]
+		} else {
+			lines[i] = indent + lines[i]
+		}
+		if width > 0 {
+			lines[i] = truncateToWidth(lines[i], width)
+		}
+	}
+	text := strings.Join(lines, "\n")
+	if hasNewline {
+		text += "\n"
+	}
+	return text
+}
+
+// visibleWidth returns the display width of text, ignoring escape sequences.
+func visibleWidth(text string) int {
+	width := 0
+	for text != "" {
+		if n := escapeLen(text); n > 0 {
+			text = text[n:]
+			continue
+		}
+		end := strings.IndexByte(text, '\x1b')
+		if end < 0 {
+			end = len(text)
+		}
+		width += textWidth(text[:end])
+		text = text[end:]
+	}
+	return width
+}
+
+// stripEscapes returns text without its escape sequences.
+func stripEscapes(text string) string {
+	if strings.IndexByte(text, '\x1b') < 0 {
+		return text
+	}
+	var b strings.Builder
+	for text != "" {
+		if n := escapeLen(text); n > 0 {
+			text = text[n:]
+			continue
+		}
+		end := strings.IndexByte(text, '\x1b')
+		if end < 0 {
+			end = len(text)
+		}
+		b.WriteString(text[:end])
+		text = text[end:]
+	}
+	return b.String()
+}
+
+// escapeLen returns the length of the escape sequence at the start of s,
+// or 0 if s does not start with one. CSI sequences (colors) and OSC
+// sequences (hyperlinks) are recognized; an unterminated sequence extends
+// to the end of s.
+func escapeLen(s string) int {
+	if len(s) == 0 || s[0] != '\x1b' {
+		return 0
+	}
+	if len(s) == 1 {
+		return 1
+	}
+	switch s[1] {
+	case '[':
+		for i := 2; i < len(s); i++ {
+			if s[i] >= 0x40 && s[i] <= 0x7e {
+				return i + 1
+			}
+		}
+		return len(s)
+	case ']':
+		for i := 2; i < len(s); i++ {
+			if s[i] == '\a' {
+				return i + 1
+			}
+			if s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return len(s)
+	default:
+		return 2
+	}
+}
+
 var (
 	startTime time.Time
 	mu        sync.RWMutex
 )
 
 // StartTimer sets the start time for relative timestamps.
-// Call this at the beginning of a test or program.
+// Call this at the beginning of a test or program. See also PushTimer.
 func StartTimer() {
 	mu.Lock()
 	defer mu.Unlock()
@@ -106,152 +283,177 @@ func StartTimer() {
 }
 
 // F prints with a millisecond timestamp prefix (like printf).
-// The output is an OSC8 hyperlink to the call site.
+// The output is an OSC8 hyperlink to the call site. Arguments implementing
+// Locator are linked to their own location instead.
 func F(format string, args ...interface{}) {
-	mu.RLock()
-	start := startTime
-	mu.RUnlock()
-
-	ms := int64(0)
-	if !start.IsZero() {
-		ms = time.Since(start).Milliseconds()
-	}
-
-	text := fmt.Sprintf("[%5d] "+format, append([]interface{}{ms}, args...)...)
-	fmt.Print(Hyperlink(text, 1))
+	std.printf(1, "", format, args)
 }
 
-// Ln prints with a millisecond timestamp prefix (like println).
-// The output is an OSC8 hyperlink to the call site.
-func Ln(msg string) {
-	mu.RLock()
-	start := startTime
-	mu.RUnlock()
-
-	ms := int64(0)
-	if !start.IsZero() {
-		ms = time.Since(start).Milliseconds()
-	}
-
-	text := fmt.Sprintf("[%5d] %s\n", ms, msg)
-	fmt.Print(Hyperlink(text, 1))
+// Ln prints with a millisecond timestamp prefix (like println): its
+// arguments separated by spaces and followed by a newline, as by
+// fmt.Println, as in ps.Ln("value:", v). The output is an OSC8 hyperlink
+// to the call site.
+func Ln(args ...interface{}) {
+	std.printf(1, "", printFormat(args, true), args)
 }
 
-// RelativeMs returns the milliseconds offset of t from the start time.
+// RelativeMs returns the milliseconds offset of t from the start time, or
+// from the start of the calling goroutine's innermost timer pushed with
+// PushTimer.
 // Returns "now" for zero time, or the relative offset like "+1000" or "-500".
+// If no timer has been started, t is formatted as set by NoTimerFormat.
+// See Relative for other units.
 func RelativeMs(t time.Time) string {
-	if t.IsZero() {
-		return "now"
-	}
-
-	mu.RLock()
-	start := startTime
-	mu.RUnlock()
-
-	if start.IsZero() {
-		return t.Format(time.RFC3339Nano)
+	r := Relative(t)
+	n, ok := r.Offset()
+	if !ok {
+		return r.format(true)
 	}
-
-	ms := t.Sub(start).Milliseconds()
-	if ms >= 0 {
-		return fmt.Sprintf("+%d", ms)
+	if n >= 0 {
+		return "+" + strconv.FormatInt(n, 10)
 	}
-	return fmt.Sprintf("%d", ms)
+	return strconv.FormatInt(n, 10)
 }
 
 // Hyperlink wraps text in OSC8 escape codes linking to the caller's source location.
-// skip is the number of stack frames to skip (0 = Hyperlink's caller, 1 = caller's caller, etc.)
-// Truncates text to terminal width if Truncate is true.
+// skip is the number of stack frames to skip (0 = Hyperlink's caller, 1 = caller's caller, etc.),
+// or Auto to link to the first caller outside this module and helper packages.
+// Truncates text to terminal width if Truncate is set.
 func Hyperlink(text string, skip int) string {
-	if Truncate {
+	if cfg().Truncate {
 		text = truncateToWidth(text, termWidth())
 	}
-	_, file, line, ok := runtime.Caller(skip + 1)
+	if skip != Auto {
+		skip++
+	}
+	_, file, line, _, ok := caller(skip)
 	if !ok {
 		return text
 	}
-	url := FormatURL(file, line)
-	return FormatOSC8(text, url)
+	return FormatOSC8(text, siteURL(file, line))
 }
 
 // FormatOSC8 wraps text in OSC8 escape codes to create a clickable hyperlink.
+// Text is returned as is if the Terminal cannot link to url.
 func FormatOSC8(text, url string) string {
-	const osc = "\x1b]"
-	const st = "\x1b\\"
-	return fmt.Sprintf("%s8;;%s%s%s%s8;;%s", osc, url, st, text, osc, st)
+	t := cfg().Terminal
+	if !t.linkable(url) {
+		return text
+	}
+	var buf [256]byte
+	return string(t.appendOSC8(buf[:0], url)) + text + osc8End
+}
+
+// LinkFormats are the supported link formats.
+var LinkFormats = []string{"cursor", "wormhole", "vscode", "vscode-remote", "githubdev", "file"}
+
+// SetLinkFormat sets LinkFormat, as Configure does, after checking that it
+// is valid.
+func SetLinkFormat(format string) error {
+	if _, err := ParseLinkFormat(format); err != nil {
+		return err
+	}
+	Configure(func(s *Settings) { s.LinkFormat = format })
+	return nil
 }
 
 // FormatURL creates a URL for the given file and line based on LinkFormat.
+// Files of the standard library are linked as set by StdlibLinks. The
+// location is resolved by ResolveSource, the path rewritten as set by
+// PathMap, and percent-encoded. If the URL
+// is longer than the limit set by MaxURLLength or the Terminal, a shorter
+// equivalent path is tried.
 func FormatURL(file string, line int) string {
-	switch LinkFormat {
+	format := ResolvedLinkFormat()
+	file, web := stdlibLocation(file, line)
+	if web != "" {
+		return web
+	}
+	file, line = ResolveSource(file, line)
+	file = mapPath(file, cfg().PathMap)
+
+	url := formatURL(format, file, line)
+	s := cfg()
+	if limit := urlLimit(s.Terminal, s.MaxURLLength); limit > 0 && len(url) > limit {
+		if short := shortenPath(file); short != file {
+			url = formatURL(format, short, line)
+		}
+	}
+	return url
+}
+
+func formatURL(format, file string, line int) string {
+	if format == "githubdev" {
+		if url, ok := githubDevURL(file, line); ok {
+			return url
+		}
+		format = "file"
+	}
+	file = escapePath(file)
+	switch format {
 	case "wormhole":
-		return fmt.Sprintf("http://wormhole:7117/file/%s:%d?land-in=editor", file, line)
+		return "http://" + cfg().WormholeAddr + "/file/" + file + ":" + strconv.Itoa(line) + "?land-in=editor"
 	case "vscode":
-		return fmt.Sprintf("vscode://file/%s:%d", file, line)
+		return "vscode://file/" + file + ":" + strconv.Itoa(line)
+	case "vscode-remote":
+		authority := cfg().Environment.Authority
+		if authority == "" {
+			return "vscode://file/" + file + ":" + strconv.Itoa(line)
+		}
+		return "vscode://vscode-remote/" + authority + file + ":" + strconv.Itoa(line)
+	case "file":
+		// file URLs have no way to give the line.
+		return "file://" + file
 	case "cursor":
 		fallthrough
 	default:
-		return fmt.Sprintf("cursor://file/%s:%d", file, line)
+		return "cursor://file/" + file + ":" + strconv.Itoa(line)
 	}
 }
 
-// Stack prints the last n stack frames, each as a hyperlink to its source location.
-// Skips runtime internals and starts from the caller of Stack.
-func Stack(n int) {
-	mu.RLock()
-	start := startTime
-	mu.RUnlock()
-
-	ms := int64(0)
-	if !start.IsZero() {
-		ms = time.Since(start).Milliseconds()
-	}
-
-	// Skip 2: runtime.Callers + Stack
-	pcs := make([]uintptr, n+2)
-	got := runtime.Callers(2, pcs)
-	if got == 0 {
-		return
-	}
-	pcs = pcs[:got]
-
-	width := 0
-	if Truncate {
-		width = termWidth()
-	}
-
-	frames := runtime.CallersFrames(pcs)
-	i := 0
-	for {
-		frame, more := frames.Next()
-		if i >= n {
-			break
-		}
-
-		funcName := frame.Function
-		if idx := lastIndex(funcName, '/'); idx >= 0 {
-			funcName = funcName[idx+1:]
-		}
+// urlKey identifies a URL cached by siteURL.
+type urlKey struct {
+	format    string
+	authority string
+	pathMap   string
+	workspace string
+	stdlib    string
+	github    [3]string
+	file      string
+	line      int
+	limit     int
+}
 
-		text := fmt.Sprintf("[%5d] #%d %s\n", ms, i, funcName)
-		if Truncate && width > 0 {
-			text = truncateToWidth(text, width)
-		}
-		url := FormatURL(frame.File, frame.Line)
-		fmt.Print(FormatOSC8(text, url))
+var (
+	urlsMu sync.RWMutex
+	urls   = map[urlKey]string{}
+)
 
-		i++
-		if !more {
-			break
-		}
+// siteURL is like FormatURL, but caches the URL. It is used for call
+// sites, which recur, so that printing from them does not allocate.
+func siteURL(file string, line int) string {
+	s := cfg()
+	key := urlKey{
+		format:    ResolvedLinkFormat(),
+		authority: s.Environment.Authority,
+		pathMap:   s.PathMap,
+		workspace: s.Workspace,
+		stdlib:    s.StdlibLinks,
+		github:    [3]string{s.GitHubRepo, s.GitHubRef, s.GitHubRoot},
+		file:      file,
+		line:      line,
+		limit:     urlLimit(s.Terminal, s.MaxURLLength),
 	}
-}
 
-func lastIndex(s string, c byte) int {
-	for i := len(s) - 1; i >= 0; i-- {
-		if s[i] == c {
-			return i
-		}
+	urlsMu.RLock()
+	url, ok := urls[key]
+	urlsMu.RUnlock()
+	if ok {
+		return url
 	}
-	return -1
+	url = FormatURL(file, line)
+	urlsMu.Lock()
+	urls[key] = url
+	urlsMu.Unlock()
+	return url
 }
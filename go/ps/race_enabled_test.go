@@ -0,0 +1,7 @@
+//go:build race
+
+package ps
+
+func init() {
+	raceEnabled = true
+}
@@ -0,0 +1,75 @@
+package ps
+
+import (
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestConcurrentConfiguration changes the configuration while other
+// goroutines print, for the race detector to check:
+//
+//	go test -race -run Concurrent ./ps
+func TestConcurrentConfiguration(t *testing.T) {
+	configure(t, func(s *Settings) {})
+	SetOutput(io.Discard)
+	prevTheme := CurrentTheme().Name
+	t.Cleanup(func() {
+		SetTheme(prevTheme)
+		SetOutput(nil)
+	})
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	run := func(f func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; ; i++ {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				f(i)
+			}
+		}()
+	}
+	for range 4 {
+		run(func(i int) { F("line %d\n", i) })
+		run(func(i int) { Ln("line", i) })
+	}
+	run(func(i int) {
+		Configure(func(s *Settings) {
+			s.Truncate = i%2 == 0
+			s.LinkFormat = LinkFormats[i%len(LinkFormats)]
+			s.TagColumn = i % 3
+			s.WatchInterval = time.Duration(i%5+1) * time.Millisecond
+		})
+	})
+	run(func(i int) {
+		if i%2 == 0 {
+			SetOutput(io.Discard)
+		} else {
+			SetOutput(&syncBuffer{})
+		}
+	})
+	run(func(i int) {
+		themes := Themes()
+		SetTheme(themes[i%len(themes)])
+	})
+	run(func(i int) {
+		s := &entries{}
+		AddSink(s)
+		RemoveSink(s)
+	})
+	run(func(i int) {
+		stop := WatchFile(t.TempDir())
+		stop()
+	})
+
+	time.Sleep(100 * time.Millisecond)
+	close(done)
+	wg.Wait()
+}
@@ -0,0 +1,134 @@
+package ps
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateWindow is the window over which Settings.MaxRate is enforced.
+const rateWindow = time.Second
+
+// suppressed counts the lines suppressed at a call site by the rate cap
+// during a burst.
+type suppressed struct {
+	site callSite
+	// start is when the first line of the burst was suppressed.
+	start time.Time
+	// total and window count the lines suppressed during the burst and
+	// during the current window.
+	total, window int64
+}
+
+var rateCap struct {
+	mu sync.Mutex
+	// windowStart and count are the start of the current window and the
+	// lines printed in it.
+	windowStart time.Time
+	count       int
+	// sites are the call sites at which lines are being suppressed, in
+	// the order of their first suppressed line, and bySite indexes them.
+	sites  []*suppressed
+	bySite map[uintptr]*suppressed
+	// scheduled is whether summarizeSuppressed is scheduled to run at the
+	// end of the window.
+	scheduled bool
+}
+
+// rateAllowed records a line printed at site and reports whether it is
+// within the cap set by Settings.MaxRate. Lines beyond it are counted for
+// the summaries printed at the end of each window.
+func rateAllowed(site callSite) bool {
+	limit := cfg().MaxRate
+	if limit <= 0 {
+		return true
+	}
+	now := time.Now()
+	rateCap.mu.Lock()
+	defer rateCap.mu.Unlock()
+	if now.Sub(rateCap.windowStart) >= rateWindow {
+		rateCap.windowStart, rateCap.count = now, 0
+	}
+	rateCap.count++
+	if rateCap.count <= limit {
+		return true
+	}
+	s := rateCap.bySite[site.pc]
+	if s == nil {
+		if rateCap.bySite == nil {
+			rateCap.bySite = map[uintptr]*suppressed{}
+		}
+		s = &suppressed{site: site, start: now}
+		rateCap.bySite[site.pc] = s
+		rateCap.sites = append(rateCap.sites, s)
+	}
+	s.total++
+	s.window++
+	if !rateCap.scheduled {
+		rateCap.scheduled = true
+		time.AfterFunc(rateCap.windowStart.Add(rateWindow).Sub(now), summarizeSuppressed)
+	}
+	return false
+}
+
+// summarizeSuppressed prints a line for each call site at which lines were
+// suppressed in the window just ended, counting those suppressed since the
+// burst at the site began. Sites at which none were suppressed in the
+// window are forgotten: their burst has subsided.
+func summarizeSuppressed() {
+	now := time.Now()
+	var summaries []suppressed
+	rateCap.mu.Lock()
+	rateCap.scheduled = false
+	sites := rateCap.sites[:0]
+	for _, s := range rateCap.sites {
+		if s.window == 0 {
+			delete(rateCap.bySite, s.site.pc)
+			continue
+		}
+		summaries = append(summaries, *s)
+		s.window = 0
+		sites = append(sites, s)
+	}
+	clear(rateCap.sites[len(sites):])
+	rateCap.sites = sites
+	rateCap.mu.Unlock()
+
+	for _, s := range summaries {
+		span := max(now.Sub(s.start), rateWindow).Round(time.Second)
+		lines := "lines"
+		if s.total == 1 {
+			lines = "line"
+		}
+		msg := fmt.Sprintf("%s:%d emitted %s %s in %s — suppressed",
+			DisplayPath(s.site.file), s.site.line, formatCount(s.total), lines, span)
+		emitSiteSummary(s.site, LevelWarn, msg)
+	}
+}
+
+// emitSiteSummary prints msg, a summary of the lines not printed at site,
+// dimmed, at level and linked to site.
+func emitSiteSummary(site callSite, level Level, msg string) {
+	e := newEntry("")
+	e.Level = level
+	e.Msg, e.File, e.Line, e.Func = msg, site.file, site.line, site.funcName
+	e.Process = ProcessLabel()
+	styled := e
+	styled.Msg = CurrentTheme().Dim.Render(msg)
+	emit(e, Render(styled, lineWidth()))
+}
+
+// formatCount formats n with commas between groups of three digits, as in
+// "1,240".
+func formatCount(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	start := 0
+	if n < 0 {
+		start = 1
+	}
+	for i := len(s) - 3; i > start; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
@@ -0,0 +1,145 @@
+package ps
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// RelativeOption configures the offset returned by Relative.
+type RelativeOption func(*RelativeTime)
+
+// Unit sets the unit of the offset, such as time.Microsecond. The default
+// is time.Millisecond. An offset in another unit, such as
+// 10*time.Millisecond, is truncated to whole units and shown in the
+// largest unit of time.Duration dividing it, as in "+30ms" for 3 units.
+func Unit(d time.Duration) RelativeOption {
+	return func(r *RelativeTime) { r.unit = d }
+}
+
+// Styled renders negative offsets in the Negative style of the current
+// theme, so that times before the start of the timer stand out.
+func Styled() RelativeOption {
+	return func(r *RelativeTime) { r.styled = true }
+}
+
+// RelativeTime is the offset of a time from the start of the calling
+// goroutine's timer, as returned by Relative. It formats with %v and %s
+// as the signed offset with its unit, such as "+1500µs", or "now" for the
+// zero time, and with %d as the bare number of units. Widths and the '-'
+// flag pad as for strings.
+type RelativeTime struct {
+	t      time.Time
+	start  time.Time
+	unit   time.Duration
+	styled bool
+}
+
+// Relative returns the offset of t from the start time, or from the start
+// of the calling goroutine's innermost timer pushed with PushTimer, for
+// use in messages:
+//
+//	ps.F("deadline at %v\n", ps.Relative(deadline, ps.Unit(time.Microsecond)))
+//
+// If no timer has been started, t is formatted as set by NoTimerFormat.
+func Relative(t time.Time, opts ...RelativeOption) RelativeTime {
+	r := RelativeTime{t: t, start: timerStart(), unit: time.Millisecond}
+	for _, opt := range opts {
+		opt(&r)
+	}
+	if r.unit <= 0 {
+		r.unit = time.Millisecond
+	}
+	return r
+}
+
+// Offset returns the offset in units, and false if there is none because
+// t is zero or no timer has been started.
+func (r RelativeTime) Offset() (int64, bool) {
+	if r.t.IsZero() || r.start.IsZero() {
+		return 0, false
+	}
+	return int64(r.t.Sub(r.start) / r.unit), true
+}
+
+// String returns the offset as formatted with %v.
+func (r RelativeTime) String() string {
+	s := r.format(true)
+	if n, ok := r.Offset(); ok && n < 0 && r.styled {
+		s = CurrentTheme().Negative.Render(s)
+	}
+	return s
+}
+
+// Format implements fmt.Formatter.
+func (r RelativeTime) Format(f fmt.State, verb rune) {
+	var s string
+	switch verb {
+	case 'v', 's':
+		s = r.String()
+	case 'd':
+		s = r.format(false)
+	default:
+		fmt.Fprintf(f, "%%!%c(ps.RelativeTime=%s)", verb, r.String())
+		return
+	}
+	if w, ok := f.Width(); ok {
+		pad := w - visibleWidth(s)
+		for ; pad > 0; pad-- {
+			if f.Flag('-') {
+				s += " "
+			} else {
+				s = " " + s
+			}
+		}
+	}
+	fmt.Fprint(f, s)
+}
+
+// format renders the offset, with a sign and unit if withUnit is set.
+func (r RelativeTime) format(withUnit bool) string {
+	if r.t.IsZero() {
+		return "now"
+	}
+	n, ok := r.Offset()
+	if !ok {
+		if cfg().NoTimerFormat == "unixms" {
+			return strconv.FormatInt(r.t.UnixMilli(), 10)
+		}
+		return r.t.Format(time.RFC3339Nano)
+	}
+	if !withUnit {
+		return strconv.FormatInt(n, 10)
+	}
+	unit, symbol := plainUnit(r.unit)
+	s := strconv.FormatInt(n*int64(r.unit/unit), 10) + symbol
+	if n >= 0 {
+		s = "+" + s
+	}
+	return s
+}
+
+// plainUnits are the units of time.Duration, largest first, with their
+// symbols.
+var plainUnits = []struct {
+	d      time.Duration
+	symbol string
+}{
+	{time.Hour, "h"},
+	{time.Minute, "m"},
+	{time.Second, "s"},
+	{time.Millisecond, "ms"},
+	{time.Microsecond, "µs"},
+	{time.Nanosecond, "ns"},
+}
+
+// plainUnit returns the largest unit of time.Duration dividing d, and its
+// symbol, such as time.Millisecond and "ms" for 10ms.
+func plainUnit(d time.Duration) (time.Duration, string) {
+	for _, u := range plainUnits {
+		if d%u.d == 0 {
+			return u.d, u.symbol
+		}
+	}
+	return time.Nanosecond, "ns"
+}
@@ -0,0 +1,31 @@
+package ps
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRelativeUnit(t *testing.T) {
+	start := time.Now()
+	for _, tt := range []struct {
+		offset, unit time.Duration
+		want, wantD  string
+	}{
+		{1500 * time.Microsecond, time.Millisecond, "+1ms", "1"},
+		{1500 * time.Microsecond, time.Microsecond, "+1500µs", "1500"},
+		{35 * time.Millisecond, 10 * time.Millisecond, "+30ms", "3"},
+		{-35 * time.Millisecond, 10 * time.Millisecond, "-30ms", "-3"},
+		{3001 * time.Microsecond, 1500 * time.Microsecond, "+3000µs", "2"},
+		{200 * time.Second, 90 * time.Second, "+180s", "2"},
+		{2 * time.Hour, time.Hour, "+2h", "2"},
+	} {
+		r := RelativeTime{t: start.Add(tt.offset), start: start, unit: tt.unit}
+		if got := fmt.Sprintf("%v", r); got != tt.want {
+			t.Errorf("%s in units of %s formatted %q with %%v, want %q", tt.offset, tt.unit, got, tt.want)
+		}
+		if got := fmt.Sprintf("%d", r); got != tt.wantD {
+			t.Errorf("%s in units of %s formatted %q with %%d, want %q", tt.offset, tt.unit, got, tt.wantD)
+		}
+	}
+}
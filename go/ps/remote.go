@@ -0,0 +1,109 @@
+package ps
+
+import (
+	"encoding/hex"
+	"os"
+	"strings"
+)
+
+// Environment kinds, as in Environment.Kind.
+const (
+	EnvLocal        = "local"
+	EnvSSH          = "ssh"
+	EnvCodespaces   = "codespaces"
+	EnvDevContainer = "devcontainer"
+	EnvKubernetes   = "kubernetes"
+)
+
+// Environment describes where the program runs, as far as it matters for
+// linking to its source files from the editor of the person reading the
+// output.
+type Environment struct {
+	// Kind is the kind of environment, one of the Env constants.
+	Kind string
+	// Authority is the VS Code remote authority through which the editor
+	// reaches the environment's files, such as "ssh-remote+devbox", or ""
+	// if there is none, as when the files are local or cannot be reached
+	// by VS Code.
+	Authority string
+}
+
+// DetectEnvironment guesses the environment from variables set by SSH,
+// GitHub Codespaces, dev containers and Kubernetes. Set
+// HYPERLINKED_ENVIRONMENT to one of the Env constants to override the
+// kind, and HYPERLINKED_SSH_HOST to the name of the host in the editor's
+// SSH configuration, if not its host name. The environment in effect is
+// Settings.Environment; a LinkFormat of "vscode-remote" links through its
+// authority, and is the default when it has one.
+func DetectEnvironment() Environment {
+	kind := os.Getenv("HYPERLINKED_ENVIRONMENT")
+	if kind == "" {
+		kind = detectKind()
+	}
+	env := Environment{Kind: kind}
+	switch kind {
+	case EnvSSH:
+		host := os.Getenv("HYPERLINKED_SSH_HOST")
+		if host == "" {
+			host, _ = os.Hostname()
+		}
+		if host != "" {
+			env.Authority = "ssh-remote+" + host
+		}
+	case EnvCodespaces:
+		if name := os.Getenv("CODESPACE_NAME"); name != "" {
+			env.Authority = "codespaces+" + name
+		}
+	case EnvDevContainer:
+		// The authority names the folder the container was opened from,
+		// which the container only knows if devcontainer.json passes it.
+		if folder := os.Getenv("LOCAL_WORKSPACE_FOLDER"); folder != "" {
+			env.Authority = "dev-container+" + hex.EncodeToString([]byte(folder))
+		}
+	}
+	return env
+}
+
+// detectKind returns the kind of environment suggested by the environment
+// variables.
+func detectKind() string {
+	switch {
+	case os.Getenv("CODESPACES") == "true":
+		return EnvCodespaces
+	case os.Getenv("REMOTE_CONTAINERS") == "true" || os.Getenv("DEVCONTAINER") == "true":
+		return EnvDevContainer
+	case os.Getenv("KUBERNETES_SERVICE_HOST") != "":
+		return EnvKubernetes
+	case os.Getenv("SSH_CONNECTION") != "" || os.Getenv("SSH_TTY") != "":
+		return EnvSSH
+	}
+	return EnvLocal
+}
+
+// defaultLinkFormat returns the link format used if HYPERLINKED_FORMAT is
+// not set: "vscode-remote" if env has a remote authority, and otherwise
+// "cursor".
+func defaultLinkFormat(env Environment) string {
+	if env.Authority != "" {
+		return "vscode-remote"
+	}
+	return "cursor"
+}
+
+// mapPath rewrites the start of file by the first mapping of spec, as for
+// Settings.PathMap, whose source is a prefix of it.
+func mapPath(file, spec string) string {
+	if spec == "" {
+		return file
+	}
+	for _, m := range strings.Split(spec, ",") {
+		from, to, ok := strings.Cut(m, "=")
+		if !ok || from == "" {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(file, from); ok && (rest == "" || rest[0] == '/' || strings.HasSuffix(from, "/")) {
+			return to + rest
+		}
+	}
+	return file
+}
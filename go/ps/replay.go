@@ -0,0 +1,103 @@
+package ps
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// ReadEntries reads the entries written by a JSONL sink, such as psfile's
+// with the JSONL format, one per line. Lines that are not entries are
+// skipped.
+func ReadEntries(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 16<<20)
+	for sc.Scan() {
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, sc.Err()
+}
+
+// Replay prints the entries read from r, as written by a JSONL sink, as
+// ReplayEntries does.
+func Replay(ctx context.Context, r io.Reader, speed float64) error {
+	entries, err := ReadEntries(r)
+	if err != nil {
+		return err
+	}
+	return ReplayEntries(ctx, entries, speed)
+}
+
+// ReplayEntries prints entries to the output as they were printed when
+// recorded, but with the current link format, width and theme, so that a
+// session recorded elsewhere links to files on this machine's editor.
+// With speed > 0 it paces the lines by the times they were recorded at,
+// divided by speed: 1 replays in real time, 10 ten times faster. It
+// returns ctx's error if ctx is done first. Replayed entries are not
+// passed to the sinks. As the entries may have been recorded by anyone,
+// the bytes of their text that the terminal would act on, such as escape
+// sequences, are escaped, as set by Settings.Sanitize.
+func ReplayEntries(ctx context.Context, entries []Entry, speed float64) error {
+	var prev time.Time
+	for _, e := range entries {
+		if speed > 0 && !prev.IsZero() && e.Time.After(prev) {
+			timer := time.NewTimer(time.Duration(float64(e.Time.Sub(prev)) / speed))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+		if !e.Time.IsZero() {
+			prev = e.Time
+		}
+		if cfg().Sanitize {
+			e = sanitizeEntry(e)
+		}
+		write(e.String())
+	}
+	return ctx.Err()
+}
+
+// sanitizeEntry returns e with the bytes of its text that the terminal
+// would act on escaped. The values of fields need not be, as Field.String
+// quotes those with control characters.
+func sanitizeEntry(e Entry) Entry {
+	e.Msg = sanitized(e.Msg)
+	e.File = sanitized(e.File)
+	e.Func = sanitized(e.Func)
+	e.Process = sanitized(e.Process)
+	e.Tag = Tag(sanitized(string(e.Tag)))
+	e.Global = sanitizeKeys(e.Global)
+	e.Labels = sanitizeKeys(e.Labels)
+	e.Fields = sanitizeKeys(e.Fields)
+	return e
+}
+
+// sanitizeKeys returns fields with the bytes of their keys that the
+// terminal would act on escaped. fields itself is not modified.
+func sanitizeKeys(fields []Field) []Field {
+	var out []Field
+	for i, f := range fields {
+		key := sanitized(f.Key)
+		if key == f.Key {
+			continue
+		}
+		if out == nil {
+			out = append([]Field(nil), fields...)
+		}
+		out[i].Key = key
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
@@ -0,0 +1,35 @@
+package ps
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestReplaySanitized(t *testing.T) {
+	configure(t, func(s *Settings) {
+		s.Terminal = Terminals["generic"]
+		s.Sanitize = true
+	})
+	var out bytes.Buffer
+	SetOutput(&out)
+	t.Cleanup(func() { SetOutput(nil) })
+
+	const record = `{"time":"2026-01-02T03:04:05Z","elapsed":0,"msg":"copy \u001b]52;c;aGk=\u0007 \u001b[2J","file":"/src/main.go","line":3,"level":"info",` +
+		`"fields":{"k\u001b[31m":"v\u001b[2J"}}` + "\n"
+	if err := Replay(context.Background(), strings.NewReader(record), 0); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	for _, seq := range []string{"\x1b]52", "\x1b[2J", "\x1b[31m", "\a"} {
+		if strings.Contains(got, seq) {
+			t.Errorf("replayed %q, with %q unescaped", got, seq)
+		}
+	}
+	for _, want := range []string{`copy \x1b]52;c;aGk=\x07 \x1b[2J`, `k\x1b[31m="v\x1b[2J"`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("replayed %q, want it to contain %q", got, want)
+		}
+	}
+}
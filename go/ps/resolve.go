@@ -0,0 +1,283 @@
+package ps
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ResolveSource returns the source file and line that links to the
+// location file:line should open, so that they do not dead-end in build
+// output. FormatURL applies it to every location linked.
+//
+// Paths in Bazel's execution root or sandboxes, and the relative paths
+// reported for code built by rules_go, are resolved against
+// Settings.Workspace: files under bazel-out resolve to the workspace
+// source they were copied from if there is one, and otherwise to the
+// bazel-bin symlink in the workspace, and files of external repositories
+// to the bazel-<workspace> symlink. Paths are left as they are if
+// Workspace is not set.
+//
+// Lines in generated Go files are resolved to the generator input named by
+// the nearest //line directive before them. The compiler already applies
+// these directives to the locations the runtime reports, so this matters
+// for locations found in the output of other tools, as by Linkify.
+func ResolveSource(file string, line int) (string, int) {
+	if ws := cfg().Workspace; ws != "" {
+		file = workspacePath(file, ws)
+	}
+	if d, ok := lineDirectiveFor(file, line); ok {
+		return d.file, d.line + line - d.at - 1
+	}
+	return file, line
+}
+
+// workspacePath resolves file, a path in the Bazel workspace ws, in its
+// execution root or relative to either, to a path in the workspace.
+func workspacePath(file, ws string) string {
+	slash := filepath.ToSlash(file)
+	if _, rest, ok := strings.Cut(slash, "/execroot/"); ok {
+		// Drop the name of the workspace, which follows execroot.
+		if _, rel, ok := strings.Cut(rest, "/"); ok {
+			slash = rel
+		}
+	}
+	if path.IsAbs(slash) {
+		return file
+	}
+	if rest, ok := cutBazelOut(slash); ok {
+		if src := filepath.Join(ws, rest); fileExists(src) {
+			return src
+		}
+		return filepath.Join(ws, "bazel-bin", rest)
+	}
+	if strings.HasPrefix(slash, "external/") {
+		return filepath.Join(ws, "bazel-"+filepath.Base(ws), slash)
+	}
+	return filepath.Join(ws, slash)
+}
+
+// cutBazelOut returns the path of a file in bazel-out, such as
+// "bazel-out/k8-fastbuild/bin/pkg/x.pb.go", relative to the output tree of
+// its configuration, as "pkg/x.pb.go".
+func cutBazelOut(file string) (string, bool) {
+	rest, ok := strings.CutPrefix(file, "bazel-out/")
+	if !ok {
+		return "", false
+	}
+	parts := strings.SplitN(rest, "/", 3)
+	if len(parts) < 3 || parts[1] != "bin" && parts[1] != "genfiles" {
+		return "", false
+	}
+	return parts[2], true
+}
+
+// fileExists reports whether file exists.
+func fileExists(file string) bool {
+	_, err := os.Stat(file)
+	return err == nil
+}
+
+// lineDirective is a //line directive of a generated file.
+type lineDirective struct {
+	// at is the line of the directive, which sets the position of the
+	// line after it to file:line.
+	at   int
+	file string
+	line int
+}
+
+var (
+	directivesMu sync.RWMutex
+	// directives caches the //line directives of each file looked up, nil
+	// for files that are not generated or cannot be read.
+	directives = map[string][]lineDirective{}
+)
+
+// lineDirectiveFor returns the last //line directive before line in file,
+// if file is a generated Go file.
+func lineDirectiveFor(file string, line int) (lineDirective, bool) {
+	if !strings.HasSuffix(file, ".go") {
+		return lineDirective{}, false
+	}
+	directivesMu.RLock()
+	ds, ok := directives[file]
+	directivesMu.RUnlock()
+	if !ok {
+		ds = readLineDirectives(file)
+		directivesMu.Lock()
+		directives[file] = ds
+		directivesMu.Unlock()
+	}
+	var found lineDirective
+	has := false
+	for _, d := range ds {
+		if d.at >= line {
+			break
+		}
+		found, has = d, true
+	}
+	return found, has
+}
+
+// generatedPattern matches the comment marking a generated Go file.
+var generatedPattern = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// readLineDirectives returns the //line directives of file, in order, or
+// nil if it is not a generated file.
+func readLineDirectives(file string) []lineDirective {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var ds []lineDirective
+	generated := false
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		text := sc.Text()
+		if generatedPattern.MatchString(text) {
+			generated = true
+			continue
+		}
+		spec, ok := strings.CutPrefix(text, "//line ")
+		if !ok {
+			continue
+		}
+		name, line, ok := parseLineDirective(spec)
+		if !ok {
+			continue
+		}
+		switch {
+		case name == "" && len(ds) > 0:
+			name = ds[len(ds)-1].file
+		case name == "":
+			name = file
+		case !filepath.IsAbs(name):
+			name = filepath.Join(filepath.Dir(file), name)
+		}
+		ds = append(ds, lineDirective{at: n, file: name, line: line})
+	}
+	if !generated {
+		return nil
+	}
+	return ds
+}
+
+// parseLineDirective parses the "filename:line" or "filename:line:col"
+// following "//line ".
+func parseLineDirective(spec string) (string, int, bool) {
+	spec = strings.TrimSpace(spec)
+	name, num, ok := cutLast(spec)
+	if !ok {
+		return "", 0, false
+	}
+	if prefix, n, ok := cutLast(name); ok {
+		// The number cut was the column.
+		name, num = prefix, n
+	}
+	line, err := strconv.Atoi(num)
+	if err != nil || line <= 0 {
+		return "", 0, false
+	}
+	return name, line, true
+}
+
+// cutLast cuts s around its last colon, if what follows it is a number.
+func cutLast(s string) (before, after string, ok bool) {
+	i := strings.LastIndexByte(s, ':')
+	if i < 0 {
+		return "", "", false
+	}
+	if _, err := strconv.Atoi(s[i+1:]); err != nil {
+		return "", "", false
+	}
+	return s[:i], s[i+1:], true
+}
+
+// Strategies for Settings.MissingSource.
+const (
+	// MissingSourceFunction links to the start of the function instead,
+	// if its file exists.
+	MissingSourceFunction = "function"
+	// MissingSourceKeep links to the location anyway.
+	MissingSourceKeep = "keep"
+)
+
+// sourceFor returns the location to link to for code in the function fn
+// that the runtime reports as being at file:line, applying the strategy of
+// Settings.MissingSource if file does not exist. Code generated with
+// //line directives is reported at the location they give, which may not
+// exist: directives may name files relative to the directory the generator
+// ran in, or files the generator deleted, and cgo reports code in files of
+// a temporary build directory. The function's first line is often in the
+// generated file itself. For inlined code, it is the first line of the
+// function the code is inlined into.
+func sourceFor(fn *runtime.Func, file string, line int) (string, int) {
+	s := cfg()
+	if fn == nil || s.MissingSource != MissingSourceFunction || sourceExists(file, s.Workspace) {
+		return file, line
+	}
+	// For code inlined into another function, fn describes the inlined
+	// function but has the entry of the function it is inlined into,
+	// whose first line is found through the latter.
+	if outer := runtime.FuncForPC(fn.Entry()); outer != nil {
+		if f, l := outer.FileLine(outer.Entry()); f != file && sourceExists(f, s.Workspace) {
+			return f, l
+		}
+	}
+	return file, line
+}
+
+// existsKey identifies a file looked up by sourceExists.
+type existsKey struct {
+	file, workspace string
+}
+
+var (
+	existsMu sync.RWMutex
+	// exists caches whether each file looked up by sourceExists exists.
+	exists = map[existsKey]bool{}
+)
+
+// sourceExists reports whether file, as resolved against the workspace ws
+// by ResolveSource, exists. The result is cached.
+func sourceExists(file, ws string) bool {
+	key := existsKey{file, ws}
+	existsMu.RLock()
+	ok, found := exists[key]
+	existsMu.RUnlock()
+	if found {
+		return ok
+	}
+	path := file
+	if ws != "" {
+		path = workspacePath(file, ws)
+	}
+	ok = fileExists(path)
+	existsMu.Lock()
+	exists[key] = ok
+	existsMu.Unlock()
+	return ok
+}
+
+// deadLink reports whether links are checked, as set by
+// Settings.CheckLinks, and the file of file:line does not exist.
+func deadLink(file string, line int) bool {
+	if !cfg().CheckLinks || file == "" {
+		return false
+	}
+	file, _ = ResolveSource(file, line)
+	return !sourceExists(file, "")
+}
+
+// deadLocation returns file:line rendered as the location of a dead link.
+func deadLocation(file string, line int) string {
+	return CurrentTheme().Dim.Render(DisplayPath(file) + ":" + strconv.Itoa(line) + " ⚠")
+}
@@ -0,0 +1,32 @@
+package ps
+
+// Result prints "✅ label" if err is nil and "❌ label: err" otherwise,
+// followed by Settings.ResultStack frames of the caller's stack. It returns err, so
+// that it can wrap a return statement:
+//
+//	return ps.Result("save order", db.Save(order))
+func Result(label string, err error) error {
+	return std.result(1, label, err)
+}
+
+// Result is like the package-level Result.
+func (p *Printer) Result(label string, err error) error {
+	return p.result(1, label, err)
+}
+
+func (p *Printer) result(skip int, label string, err error) error {
+	site := p.callSite(skip + 1)
+	if err == nil {
+		if p.prints(Success) {
+			p.printAt(site, newEntry(Success), "%s\n", []interface{}{CurrentTheme().Tags[Success].styled(label)})
+		}
+		return nil
+	}
+	if p.prints(Failure) {
+		p.printAt(site, newEntry(Failure), "%s: %v\n", []interface{}{CurrentTheme().Tags[Failure].styled(label), err})
+	}
+	if n := cfg().ResultStack; n > 0 {
+		stackAt(skip+1, site, n, StackLocation(), StackAlign())
+	}
+	return err
+}
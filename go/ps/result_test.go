@@ -0,0 +1,77 @@
+package ps
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestResultSanitized(t *testing.T) {
+	configure(t, func(s *Settings) {
+		s.Terminal = Terminals["generic"]
+		s.Sanitize = true
+	})
+	var out bytes.Buffer
+	SetOutput(&out)
+	t.Cleanup(func() { SetOutput(nil) })
+
+	for _, tt := range []struct {
+		label string
+		err   error
+		want  string
+	}{
+		{"save order", nil, "✅ " + CurrentTheme().Tags[Success].Render("save order") + "\n"},
+		{"save order", errors.New("disk full"), "❌ " + CurrentTheme().Tags[Failure].Render("save order") + ": disk full\n"},
+		{"clear\x1b[2J", nil, "✅ " + CurrentTheme().Tags[Success].Render(`clear\x1b[2J`) + "\n"},
+	} {
+		out.Reset()
+		Result(tt.label, tt.err)
+		if !strings.Contains(out.String(), tt.want) {
+			t.Errorf("Result(%q, %v) wrote %q, want it to contain %q", tt.label, tt.err, out.String(), tt.want)
+		}
+	}
+}
+
+// save reports saving through Result, as a helper.
+func save(err error) error {
+	Helper()
+	return Result("save", err)
+}
+
+// saveSkipped reports saving through Result, skipping its own frame.
+func saveSkipped(err error) error {
+	return WithSkip(1).Result("save", err)
+}
+
+func TestResultStack(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		save func(error) error
+	}{
+		{"Helper", save},
+		{"WithSkip", saveSkipped},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			resetHelpers(t, false)
+			configure(t, func(s *Settings) { s.ResultStack = 2 })
+			s := testCaller(t)
+			_, file, line, _ := runtime.Caller(0)
+			tt.save(errors.New("disk full")) // The line after the call to runtime.Caller.
+
+			s.mu.Lock()
+			es := slices.Clone(s.es)
+			s.mu.Unlock()
+			if len(es) != 3 {
+				t.Fatalf("printed %d entries, want the result and 2 frames", len(es))
+			}
+			for _, e := range es[:2] {
+				if e.File != file || e.Line != line+1 {
+					t.Errorf("%q linked to %s:%d, want %s:%d", e.Msg, e.File, e.Line, file, line+1)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package ps
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy configures Retrying.
+type RetryPolicy struct {
+	// Attempts is the maximum number of attempts, or 0 for no limit.
+	Attempts int
+	// Delay is the delay before the second attempt.
+	Delay time.Duration
+	// Multiplier scales the delay after each attempt. Values below 1 are
+	// taken as 1, for a constant delay.
+	Multiplier float64
+	// MaxDelay caps the delay, if positive.
+	MaxDelay time.Duration
+	// Jitter randomizes each delay by up to the given fraction of it,
+	// either way, so that clients retrying together spread out. Values
+	// above 1 are taken as 1, so that delays are never negative.
+	Jitter float64
+}
+
+// DefaultRetryPolicy makes up to 5 attempts, with delays of 100ms, 200ms,
+// 400ms and 800ms.
+var DefaultRetryPolicy = RetryPolicy{Attempts: 5, Delay: 100 * time.Millisecond, Multiplier: 2}
+
+// Retrying calls f, with attempt counting from 1, until it returns nil, the
+// policy's attempts are used up or ctx is done, waiting between attempts
+// as the policy says. Each failed attempt is printed as a Retry line with
+// the error and the delay, and the outcome, even of a first attempt that
+// succeeds, as a Success or Failure line, all linked to the call of
+// Retrying. It is not named Retry, as that is the tag of its lines:
+//
+//	err := ps.Retrying(ctx, ps.DefaultRetryPolicy, func(attempt int) error {
+//		return client.Ping()
+//	})
+//
+// prints:
+//
+//	🔄 attempt 1 failed: connection refused; retrying in 100ms
+//	✅ succeeded on attempt 2 after 103ms
+//
+// It returns the error of the last attempt, or ctx's error if ctx was done
+// first.
+func Retrying(ctx context.Context, policy RetryPolicy, f func(attempt int) error) error {
+	return std.retry(1, ctx, policy, f)
+}
+
+// Retrying is like the package-level Retrying, printing with p.
+func (p *Printer) Retrying(ctx context.Context, policy RetryPolicy, f func(attempt int) error) error {
+	return p.retry(1, ctx, policy, f)
+}
+
+func (p *Printer) retry(skip int, ctx context.Context, policy RetryPolicy, f func(attempt int) error) error {
+	site := p.callSite(skip + 1)
+	print := func(tag Tag, format string, args ...interface{}) {
+		if p.prints(tag) {
+			p.printAt(site, newEntry(tag), format, args)
+		}
+	}
+	start := time.Now()
+	delay := policy.Delay
+	for attempt := 1; ; attempt++ {
+		err := f(attempt)
+		if err == nil {
+			print(Success, "succeeded on attempt %d after %s\n", attempt, formatDuration(time.Since(start)))
+			return nil
+		}
+		if policy.Attempts > 0 && attempt >= policy.Attempts {
+			print(Failure, "failed after %d attempts in %s: %v\n", attempt, formatDuration(time.Since(start)), err)
+			return err
+		}
+
+		wait := jittered(delay, policy.Jitter)
+		print(Retry, "attempt %d failed: %v; retrying in %s\n", attempt, err, formatDuration(wait))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			print(Failure, "gave up after %d attempts in %s: %v (last error: %v)\n", attempt, formatDuration(time.Since(start)), ctx.Err(), err)
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		if policy.Multiplier > 1 {
+			delay = time.Duration(float64(delay) * policy.Multiplier)
+		}
+		if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+			delay = policy.MaxDelay
+		}
+	}
+}
+
+// jittered returns d randomized by up to the fraction jitter of it, either
+// way, with jitter taken as 1 if greater.
+func jittered(d time.Duration, jitter float64) time.Duration {
+	if jitter <= 0 {
+		return d
+	}
+	jitter = min(jitter, 1)
+	return d + time.Duration((rand.Float64()*2-1)*jitter*float64(d))
+}
@@ -0,0 +1,61 @@
+package ps
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetrying(t *testing.T) {
+	errBusy := errors.New("busy")
+	policy := RetryPolicy{Attempts: 3, Delay: time.Millisecond}
+	for _, tt := range []struct {
+		name     string
+		failures int
+		want     []string
+	}{
+		{"first attempt", 0, []string{"succeeded on attempt 1 after"}},
+		{"second attempt", 1, []string{"attempt 1 failed: busy; retrying in 1ms", "succeeded on attempt 2 after"}},
+		{"attempts used up", 3, []string{"attempt 1 failed: busy", "attempt 2 failed: busy", "failed after 3 attempts in"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := testCaller(t)
+			err := Retrying(context.Background(), policy, func(attempt int) error {
+				if attempt <= tt.failures {
+					return errBusy
+				}
+				return nil
+			})
+			if (err != nil) != (tt.failures >= policy.Attempts) {
+				t.Errorf("returned %v", err)
+			}
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if len(s.es) != len(tt.want) {
+				t.Fatalf("printed %d lines, want %d", len(s.es), len(tt.want))
+			}
+			for i, e := range s.es {
+				if !strings.HasPrefix(e.Msg, tt.want[i]) {
+					t.Errorf("line %d is %q, want it to start with %q", i+1, e.Msg, tt.want[i])
+				}
+			}
+			if last := s.es[len(s.es)-1].Tag; last != Success && last != Failure {
+				t.Errorf("outcome tagged %q", last)
+			}
+		})
+	}
+}
+
+func TestJittered(t *testing.T) {
+	const d = time.Second
+	for _, jitter := range []float64{0, 0.5, 1, 5} {
+		limit := time.Duration(min(jitter, 1) * float64(d))
+		for i := 0; i < 1000; i++ {
+			if got := jittered(d, jitter); got < d-limit || got > d+limit {
+				t.Fatalf("jittered(%s, %g) = %s, want within %s of it", d, jitter, got, limit)
+			}
+		}
+	}
+}
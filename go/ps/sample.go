@@ -0,0 +1,103 @@
+package ps
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// SetSampleRate sets the fraction of hits at each call site that are
+// printed, for printers without a rate of their own. A rate of 1 (the
+// default) prints everything. Set via HYPERLINKED_SAMPLE env var, e.g.
+// HYPERLINKED_SAMPLE=0.1.
+func SetSampleRate(rate float64) {
+	Configure(func(s *Settings) { s.SampleRate = rate })
+}
+
+// SampleRate returns the rate set by SetSampleRate.
+func SampleRate() float64 {
+	return cfg().SampleRate
+}
+
+// Sample returns a printer that prints the given fraction of the hits at
+// each of its call sites, e.g. 0.01 for one in a hundred. Sampling is
+// deterministic: the first hit is always printed, then every 1/rate'th.
+// Each printed line notes how many hits were dropped since the previous
+// one. Hits dropped with no line printed after them within a second, as
+// when the hits stop, are counted by a line of their own:
+//
+//	[ 1034] main.go:42 dropped 9 lines by sampling
+//
+// A rate of 0 uses the global rate set by SetSampleRate.
+func Sample(rate float64) *Printer {
+	return std.Sample(rate)
+}
+
+// sampleSummaryDelay is how long after a hit is dropped its call site is
+// summarized, if no line has been printed there since.
+const sampleSummaryDelay = time.Second
+
+// siteCounter counts the hits at a call site.
+type siteCounter struct {
+	site    callSite
+	mu      sync.Mutex
+	hits    int64
+	dropped int64
+	// scheduled is whether summarize is scheduled to run.
+	scheduled bool
+}
+
+var siteCounters sync.Map // uintptr -> *siteCounter
+
+// sampled records a hit at site and reports whether it should be printed
+// and, if so, how many hits were dropped since the last one printed or
+// summarized.
+func (p *Printer) sampled(site callSite) (keep bool, dropped int64) {
+	rate := p.sample
+	if rate == 0 {
+		rate = SampleRate()
+	}
+	if rate >= 1 {
+		return true, 0
+	}
+	v, ok := siteCounters.Load(site.pc)
+	if !ok {
+		v, _ = siteCounters.LoadOrStore(site.pc, &siteCounter{site: site})
+	}
+	c := v.(*siteCounter)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hits++
+	// Print whenever hits*rate crosses an integer, starting with the first hit.
+	if math.Ceil(float64(c.hits)*rate) == math.Ceil(float64(c.hits-1)*rate) {
+		c.dropped++
+		if !c.scheduled {
+			c.scheduled = true
+			time.AfterFunc(sampleSummaryDelay, c.summarize)
+		}
+		return false, 0
+	}
+	dropped, c.dropped = c.dropped, 0
+	return true, dropped
+}
+
+// summarize prints a line counting the hits dropped at the site of c since
+// the last line printed there, if any.
+func (c *siteCounter) summarize() {
+	c.mu.Lock()
+	c.scheduled = false
+	dropped := c.dropped
+	c.dropped = 0
+	c.mu.Unlock()
+	if dropped == 0 {
+		return
+	}
+	lines := "lines"
+	if dropped == 1 {
+		lines = "line"
+	}
+	msg := fmt.Sprintf("%s:%d dropped %s %s by sampling", DisplayPath(c.site.file), c.site.line, formatCount(dropped), lines)
+	emitSiteSummary(c.site, LevelInfo, msg)
+}
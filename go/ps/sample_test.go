@@ -0,0 +1,53 @@
+package ps
+
+import (
+	"fmt"
+	"testing"
+)
+
+// siteCounterAt returns the counter of the call site at line of file.
+func siteCounterAt(t *testing.T, file string, line int) *siteCounter {
+	t.Helper()
+	var c *siteCounter
+	siteCounters.Range(func(_, v any) bool {
+		if s := v.(*siteCounter); s.site.file == file && s.site.line == line {
+			c = s
+		}
+		return c == nil
+	})
+	if c == nil {
+		t.Fatalf("no counter for %s:%d", file, line)
+	}
+	return c
+}
+
+func TestSampleSummary(t *testing.T) {
+	s := testCaller(t)
+	p := Sample(0.25)
+	for i := 0; i < 4; i++ {
+		p.F("hit %d\n", i)
+	}
+	printed := s.last()
+	if printed.Msg != "hit 0" {
+		t.Fatalf("printed %q, want the first hit", printed.Msg)
+	}
+	c := siteCounterAt(t, printed.File, printed.Line)
+	c.mu.Lock()
+	scheduled := c.scheduled
+	c.mu.Unlock()
+	if !scheduled {
+		t.Errorf("no summary scheduled after dropping hits")
+	}
+
+	c.summarize()
+	want := fmt.Sprintf("%s:%d dropped 3 lines by sampling", DisplayPath(printed.File), printed.Line)
+	if e := s.last(); e.Msg != want || e.File != printed.File || e.Line != printed.Line {
+		t.Errorf("summarized as %q at %s:%d, want %q at the call site", e.Msg, e.File, e.Line, want)
+	}
+
+	before := s.last()
+	c.summarize()
+	if !s.last().Time.Equal(before.Time) {
+		t.Errorf("summarized again as %q with no hits dropped since", s.last().Msg)
+	}
+}
@@ -0,0 +1,143 @@
+package ps
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// A message may hold bytes that the terminal acts on rather than shows,
+// such as those of a raw buffer dumped with %s, which can move the cursor,
+// clear the screen, link text or leave the terminal in another mode.
+// appendAt escapes them, as set by Settings.Sanitize, in two steps. The
+// arguments are formatted with every escape sequence escaped, by safeArgs,
+// as they are data, except for the sequences of values of this package's
+// types that it writes itself: the links of Link, the styles of Relative
+// and of the text it styles, such as the label of Result, whose text is
+// escaped. The message is then escaped keeping the newlines and tabs, the
+// SGR sequences that style text and the OSC 8 sequences that link it,
+// which only the format and this package can have written.
+
+// unsafeText reports whether s holds bytes that appendSanitized escapes,
+// keeping SGR and OSC 8 sequences if keep.
+func unsafeText[T ~string | ~[]byte](s T, keep bool) bool {
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\x1b':
+			if !keep {
+				return true
+			}
+			n := keptEscapeLen(s[i:])
+			if n == 0 {
+				return true
+			}
+			i += n
+			continue
+		case c == '\n' || c == '\t':
+		case c < 0x20 || c == 0x7f:
+			return true
+		case c >= 0x80:
+			r, size := decodeRune(s[i:])
+			if r == utf8.RuneError && size == 1 || r >= 0x80 && r <= 0x9f {
+				return true
+			}
+			i += size
+			continue
+		}
+		i++
+	}
+	return false
+}
+
+// appendSanitized appends s to b with the bytes that the terminal would
+// act on, and invalid UTF-8, escaped in hexadecimal: "\x07" for BEL,
+// "\x1b" for an escape that does not start a kept sequence, and "\u009b"
+// for the C1 control CSI. If keep, SGR and OSC 8 sequences are kept.
+func appendSanitized(b []byte, s string, keep bool) []byte {
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\x1b':
+			if n := keptEscapeLen(s[i:]); keep && n > 0 {
+				b = append(b, s[i:i+n]...)
+				i += n
+				continue
+			}
+			b = fmt.Appendf(b, `\x%02x`, c)
+		case c == '\n' || c == '\t':
+			b = append(b, c)
+		case c < 0x20 || c == 0x7f:
+			b = fmt.Appendf(b, `\x%02x`, c)
+		case c >= 0x80:
+			r, size := utf8.DecodeRuneInString(s[i:])
+			switch {
+			case r == utf8.RuneError && size == 1:
+				b = fmt.Appendf(b, `\x%02x`, c)
+			case r >= 0x80 && r <= 0x9f:
+				b = fmt.Appendf(b, `\u%04x`, r)
+			default:
+				b = append(b, s[i:i+size]...)
+			}
+			i += size
+			continue
+		default:
+			b = append(b, c)
+		}
+		i++
+	}
+	return b
+}
+
+// sanitized returns s with the bytes that the terminal would act on
+// escaped as by appendSanitized, escape sequences included.
+func sanitized(s string) string {
+	if !unsafeText(s, false) {
+		return s
+	}
+	return string(appendSanitized(nil, s, false))
+}
+
+// keptEscapeLen returns the length of the escape sequence at the start of
+// s if it is one that sanitizing keeps, an SGR or OSC 8 sequence, or 0.
+func keptEscapeLen[T ~string | ~[]byte](s T) int {
+	if len(s) < 2 || s[0] != '\x1b' {
+		return 0
+	}
+	switch s[1] {
+	case '[':
+		for i := 2; i < len(s); i++ {
+			switch c := s[i]; {
+			case c == 'm':
+				return i + 1
+			case c >= '0' && c <= '9' || c == ';' || c == ':':
+			default:
+				return 0
+			}
+		}
+	case ']':
+		if len(s) < 4 || s[2] != '8' || s[3] != ';' {
+			return 0
+		}
+		for i := 4; i < len(s); i++ {
+			switch c := s[i]; {
+			case c == '\a':
+				return i + 1
+			case c == '\x1b':
+				if i+1 < len(s) && s[i+1] == '\\' {
+					return i + 2
+				}
+				return 0
+			case c < 0x20 || c == 0x7f:
+				return 0
+			}
+		}
+	}
+	return 0
+}
+
+// decodeRune is utf8.DecodeRune for either strings or byte slices.
+func decodeRune[T ~string | ~[]byte](s T) (rune, int) {
+	var buf [utf8.UTFMax]byte
+	n := copy(buf[:], s)
+	return utf8.DecodeRune(buf[:n])
+}
@@ -0,0 +1,63 @@
+package ps
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSafeArgsEscape(t *testing.T) {
+	evil := "\x1b]8;;https://evil.example\x1b\\x\x1b]8;;\x1b\\\x1b[31m"
+	for _, tt := range []struct {
+		name string
+		arg  interface{}
+		want string
+	}{
+		{"plain", "text", "text"},
+		{"number", 42, "42"},
+		{"string", evil, `\x1b]8;;https://evil.example\x1b\x\x1b]8;;\x1b\\x1b[31m`},
+		{"bytes", []byte("\a"), `[7]`},
+		{"Stringer", stringer("\a"), `\x07`},
+		{"error", fmt.Errorf("bad %s", "\x1b[2J"), `bad \x1b[2J`},
+		{"Link text", Link("\x1b[2J", ""), `\x1b[2J`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			args, _ := safeArgs([]interface{}{tt.arg}, "", true)
+			if got := fmt.Sprintf("%v", args...); got != tt.want {
+				t.Errorf("formatted %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSafeArgsKeepsOwnSequences(t *testing.T) {
+	configure(t, func(s *Settings) { s.Terminal = Terminals["generic"] })
+	link := Link("docs", "https://example.com")
+	args, _ := safeArgs([]interface{}{link}, "", true)
+	if got, want := fmt.Sprintf("%v", args...), link.String(); got != want {
+		t.Errorf("formatted %q, want %q", got, want)
+	}
+}
+
+func TestFieldString(t *testing.T) {
+	for _, tt := range []struct {
+		f    Field
+		want string
+	}{
+		{Field{"k", "v"}, "k=v"},
+		{Field{"k", "a b"}, `k="a b"`},
+		{Field{"k", "\x1b[2J"}, `k="\x1b[2J"`},
+	} {
+		if got := tt.f.String(); got != tt.want {
+			t.Errorf("%#v.String() = %q, want %q", tt.f, got, tt.want)
+		}
+		if strings.ContainsRune(tt.f.String(), '\x1b') {
+			t.Errorf("%#v.String() contains an escape", tt.f)
+		}
+	}
+}
+
+// stringer is a fmt.Stringer returning itself.
+type stringer string
+
+func (s stringer) String() string { return string(s) }
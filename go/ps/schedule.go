@@ -0,0 +1,131 @@
+package ps
+
+import (
+	"sync"
+	"time"
+)
+
+// AfterFunc is time.AfterFunc, printing Scheduled lines when f is
+// scheduled and when it fires, with its drift from the intended time, and
+// a Success line when it returns, all linked to the call site of
+// AfterFunc:
+//
+//	[    0] 🕐 scheduled in 2s, at 15:04:05.120
+//	[ 2001] 🕐 fired 1.2ms late
+//	[ 2034] ✅ done in 33ms
+//
+// The intended time is d after the call; it is not moved by Reset on the
+// returned timer.
+func AfterFunc(d time.Duration, f func()) *time.Timer {
+	return std.afterFunc(1, d, f)
+}
+
+// AfterFunc is like the package-level AfterFunc.
+func (p *Printer) AfterFunc(d time.Duration, f func()) *time.Timer {
+	return p.afterFunc(1, d, f)
+}
+
+func (p *Printer) afterFunc(skip int, d time.Duration, f func()) *time.Timer {
+	site := p.callSite(skip + 1)
+	at := time.Now().Add(d)
+	if p.prints(Scheduled) {
+		p.printAt(site, newEntry(Scheduled), "scheduled in %s, at %s\n", []interface{}{formatDuration(d), formatClock(at)})
+	}
+	return time.AfterFunc(d, func() {
+		p.runScheduled(site, at, "", f)
+	})
+}
+
+// Every runs f every interval until the returned function or Close is
+// called, printing the lines AfterFunc prints for each run, numbered,
+// linked to the call site of Every. Unlike the ticks of a time.Ticker, a
+// run that is due while the previous one is still running is not dropped:
+// it starts when that one returns, showing its drift.
+//
+//	stop := ps.Every(time.Minute, flushMetrics)
+//	defer stop()
+//
+// stop waits for a run in progress to return. It may be called more than
+// once.
+func Every(interval time.Duration, f func()) (stop func()) {
+	return std.schedule(1, func(last time.Time) time.Time { return last.Add(interval) }, f)
+}
+
+// Every is like the package-level Every.
+func (p *Printer) Every(interval time.Duration, f func()) (stop func()) {
+	return p.schedule(1, func(last time.Time) time.Time { return last.Add(interval) }, f)
+}
+
+// Schedule runs f at the times returned by next, as a cron-like scheduler
+// would, printing the lines Every prints. next is passed the intended time
+// of the previous run, or the time of the call to Schedule for the first,
+// and returns the intended time of the next run, or the zero time to end
+// the schedule. To run at the start of every hour:
+//
+//	stop := ps.Schedule(func(last time.Time) time.Time {
+//		return last.Truncate(time.Hour).Add(time.Hour)
+//	}, rotateLogs)
+func Schedule(next func(last time.Time) time.Time, f func()) (stop func()) {
+	return std.schedule(1, next, f)
+}
+
+// Schedule is like the package-level Schedule.
+func (p *Printer) Schedule(next func(last time.Time) time.Time, f func()) (stop func()) {
+	return p.schedule(1, next, f)
+}
+
+func (p *Printer) schedule(skip int, next func(time.Time) time.Time, f func()) func() {
+	site := p.callSite(skip + 1)
+	at := next(time.Now())
+	if !at.IsZero() && p.prints(Scheduled) {
+		p.printAt(site, newEntry(Scheduled), "scheduled, first run at %s (in %s)\n", []interface{}{formatClock(at), formatDuration(time.Until(at))})
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for run := int64(1); !at.IsZero(); run++ {
+			timer := time.NewTimer(time.Until(at))
+			select {
+			case <-done:
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			p.runScheduled(site, at, "run "+formatCount(run)+" ", f)
+			at = next(at)
+		}
+	}()
+	var once sync.Once
+	return track(func() {
+		once.Do(func() { close(done) })
+		<-stopped
+	})
+}
+
+// runScheduled runs f, intended to run at at, printing the lines linked to
+// site saying that it fired and that it is done, prefixed with run.
+func (p *Printer) runScheduled(site callSite, at time.Time, run string, f func()) {
+	start := time.Now()
+	if p.prints(Scheduled) {
+		p.printAt(site, newEntry(Scheduled), "%sfired %s\n", []interface{}{run, formatDrift(start.Sub(at))})
+	}
+	f()
+	if p.prints(Success) {
+		p.printAt(site, newEntry(Success), "%sdone in %s\n", []interface{}{run, formatDuration(time.Since(start))})
+	}
+}
+
+// formatDrift formats the drift of a run from its intended time, as
+// "1.2ms late" or "300µs early".
+func formatDrift(d time.Duration) string {
+	if d < 0 {
+		return formatDuration(-d) + " early"
+	}
+	return formatDuration(d) + " late"
+}
+
+// formatClock formats t as the time of day, to the millisecond.
+func formatClock(t time.Time) string {
+	return t.Format("15:04:05.000")
+}
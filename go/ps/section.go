@@ -0,0 +1,58 @@
+package ps
+
+import (
+	"strings"
+	"sync"
+)
+
+// Section prints a "▶ title" line starting a section of output, and
+// returns a function ending it. With Marks set, the terminal records the
+// section so that it can be jumped to, and with Title set, it shows the
+// title, or that a section is running, while the window is in the
+// background.
+//
+//	for _, tc := range cases {
+//		end := ps.Section("case %s", tc.name)
+//		...
+//		end()
+//	}
+func Section(format string, args ...interface{}) (end func()) {
+	return std.section(1, format, args)
+}
+
+// Section is like the package-level Section.
+func (p *Printer) Section(format string, args ...interface{}) (end func()) {
+	return p.section(1, format, args)
+}
+
+func (p *Printer) section(skip int, format string, args []interface{}) func() {
+	e, text, ok := p.format(skip+1, "", "▶ "+format+"\n", args)
+	if !ok {
+		return func() {}
+	}
+	marks := cfg().Marks
+	switch marks {
+	case "osc133":
+		// A marks the start of a "prompt", which terminals jump between;
+		// C the start of its "output".
+		text = "\x1b]133;A\x1b\\" + text + "\x1b]133;C\x1b\\"
+	case "iterm2":
+		text = "\x1b]1337;SetMark\x07" + text
+	}
+	mode := cfg().Title
+	emit(e, titleStart(format, args)+text)
+	endRegion := traceRegion(strings.TrimPrefix(e.Msg, "▶ "))
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			endRegion()
+			end := titleEnd(mode)
+			if marks == "osc133" {
+				end += "\x1b]133;D\x1b\\"
+			}
+			if end != "" {
+				write(end)
+			}
+		})
+	}
+}
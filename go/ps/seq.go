@@ -0,0 +1,33 @@
+package ps
+
+import (
+	"os"
+	"sync/atomic"
+)
+
+// seqFromEnv numbers the lines of the package-level functions if
+// HYPERLINKED_SEQ=1.
+func seqFromEnv() {
+	if os.Getenv("HYPERLINKED_SEQ") == "1" {
+		std.seq = new(atomic.Uint64)
+	}
+}
+
+// Seq returns a printer that numbers the lines it prints 1, 2, 3 and so
+// on, shown as "#1" after the timestamp and stored in Entry.Seq. Unlike
+// timestamps, the numbers never collide, so the order in which lines were
+// printed can be reconstructed from them even when the lines are
+// interleaved or sorted. Printers derived from it, with With, At and so
+// on, share its count. Set HYPERLINKED_SEQ=1 to number the lines printed
+// by the package-level functions.
+func Seq() *Printer {
+	return std.Seq()
+}
+
+// Seq returns a copy of p that numbers the lines it prints, starting from
+// 1. See the package-level Seq.
+func (p *Printer) Seq() *Printer {
+	q := *p
+	q.seq = new(atomic.Uint64)
+	return &q
+}
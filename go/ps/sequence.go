@@ -0,0 +1,125 @@
+package ps
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// message is a Sent or Received entry between two participants.
+type message struct {
+	from, to string
+	tag      Tag
+	text     string
+}
+
+var (
+	messagesMu sync.Mutex
+	messages   []message
+	// messagesDropped counts the messages beyond MaxMessages.
+	messagesDropped int
+)
+
+// participants returns the values of the "from" and "to" fields of e, and
+// whether e is a message: a Sent or Received entry with both.
+func participants(e Entry) (from, to string, ok bool) {
+	if e.Tag != Sent && e.Tag != Received {
+		return "", "", false
+	}
+	var hasFrom, hasTo bool
+	for _, f := range e.Fields {
+		switch f.Key {
+		case "from":
+			from, hasFrom = fmt.Sprint(f.Value), true
+		case "to":
+			to, hasTo = fmt.Sprint(f.Value), true
+		}
+	}
+	return from, to, hasFrom && hasTo
+}
+
+// recordMessage keeps e for WriteSequenceDiagram if it is a message.
+func recordMessage(e Entry) {
+	from, to, ok := participants(e)
+	if !ok {
+		return
+	}
+	text, _, _ := strings.Cut(e.Msg, "\n")
+	messagesMu.Lock()
+	defer messagesMu.Unlock()
+	if len(messages) >= cfg().MaxMessages {
+		messagesDropped++
+		return
+	}
+	messages = append(messages, message{from: from, to: to, tag: e.Tag, text: text})
+}
+
+// WriteSequenceDiagram writes the messages printed so far as a Mermaid
+// sequence diagram. Messages are the lines tagged Sent or Received with
+// "from" and "to" fields naming the participants:
+//
+//	ps.With("from", "client").With("to", "api").T(ps.Sent, "GET /orders\n")
+//	ps.With("from", "api").With("to", "client").T(ps.Received, "200 OK\n")
+//	...
+//	ps.WriteSequenceDiagram(os.Stdout)
+//
+// writes:
+//
+//	sequenceDiagram
+//	    participant p1 as client
+//	    participant p2 as api
+//	    p1->>p2: GET /orders
+//	    p2-->>p1: 200 OK
+//
+// Sent messages are drawn as solid arrows and Received messages as dashed
+// ones. Only the first line of each message is shown.
+func WriteSequenceDiagram(w io.Writer) error {
+	messagesMu.Lock()
+	msgs := append([]message(nil), messages...)
+	dropped := messagesDropped
+	messagesMu.Unlock()
+
+	var b strings.Builder
+	b.WriteString("sequenceDiagram\n")
+	ids := map[string]string{}
+	id := func(name string) string {
+		if id, ok := ids[name]; ok {
+			return id
+		}
+		id := fmt.Sprintf("p%d", len(ids)+1)
+		ids[name] = id
+		fmt.Fprintf(&b, "    participant %s as %s\n", id, mermaidText(name))
+		return id
+	}
+	for _, m := range msgs {
+		id(m.from)
+		id(m.to)
+	}
+	for _, m := range msgs {
+		arrow := "->>"
+		if m.tag == Received {
+			arrow = "-->>"
+		}
+		fmt.Fprintf(&b, "    %s%s%s: %s\n", ids[m.from], arrow, ids[m.to], mermaidText(m.text))
+	}
+	if dropped > 0 {
+		fmt.Fprintf(&b, "    %%%% %d more messages not kept (MaxMessages)\n", dropped)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ResetSequenceDiagram forgets the messages printed so far.
+func ResetSequenceDiagram() {
+	messagesMu.Lock()
+	defer messagesMu.Unlock()
+	messages = nil
+	messagesDropped = 0
+}
+
+// mermaidText escapes the characters of s that end or break a Mermaid
+// statement, using Mermaid's #code; entities.
+func mermaidText(s string) string {
+	return strings.NewReplacer("#", "#35;", ";", "#59;", "\n", " ").Replace(stripEscapes(s))
+}
@@ -0,0 +1,235 @@
+package ps
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// envVars are the environment variables read by this module, with the
+// values they accept, or nil for any.
+var envVars = map[string][]string{
+	"HYPERLINKED_AMBIGUOUS_WIDTH":    {"1", "2"},
+	"HYPERLINKED_AUDIT":              nil,
+	"HYPERLINKED_BANNER":             {"1"},
+	"HYPERLINKED_CHECK_LINKS":        {"1"},
+	"HYPERLINKED_COLUMNS":            nil,
+	"HYPERLINKED_DEBUG":              {"1"},
+	"HYPERLINKED_ELLIPSIS":           nil,
+	"HYPERLINKED_EMOJI_WIDTH":        {"1", "2"},
+	"HYPERLINKED_ENVIRONMENT":        {"local", "ssh", "codespaces", "devcontainer", "kubernetes"},
+	"HYPERLINKED_FILTER":             nil,
+	"HYPERLINKED_FORMAT":             nil,
+	"HYPERLINKED_GITHUB_REF":         nil,
+	"HYPERLINKED_GITHUB_REPO":        nil,
+	"HYPERLINKED_GITHUB_ROOT":        nil,
+	"HYPERLINKED_LABEL":              nil,
+	"HYPERLINKED_LABELS":             nil,
+	"HYPERLINKED_LAYOUT":             {"columns"},
+	"HYPERLINKED_LEVEL":              nil,
+	"HYPERLINKED_MARKS":              {"osc133", "iterm2"},
+	"HYPERLINKED_MAX_ARG_LENGTH":     nil,
+	"HYPERLINKED_MAX_DEFERRED":       nil,
+	"HYPERLINKED_MAX_DEPTH":          nil,
+	"HYPERLINKED_MAX_MESSAGE":        nil,
+	"HYPERLINKED_MAX_RATE":           nil,
+	"HYPERLINKED_MAX_URL":            nil,
+	"HYPERLINKED_MISSING_SOURCE":     {"function", "keep"},
+	"HYPERLINKED_NO_ALIGN":           nil,
+	"HYPERLINKED_NO_SANITIZE":        nil,
+	"HYPERLINKED_NO_TIMER_FORMAT":    {"rfc3339", "unixms"},
+	"HYPERLINKED_NO_TRUNCATE":        nil,
+	"HYPERLINKED_NOTIFY":             {"first", "all"},
+	"HYPERLINKED_NOTIFY_STYLE":       {"osc9", "osc777", "osc99"},
+	"HYPERLINKED_PATH_DISPLAY":       {"base", "short", "full"},
+	"HYPERLINKED_PATH_MAP":           nil,
+	"HYPERLINKED_PRECISION":          {"ms", "us", "ns"},
+	"HYPERLINKED_PROBE":              {"1"},
+	"HYPERLINKED_REMOTE_SINK":        nil,
+	"HYPERLINKED_RESULT_STACK":       nil,
+	"HYPERLINKED_SAMPLE":             nil,
+	"HYPERLINKED_SEQ":                {"1"},
+	"HYPERLINKED_SHARE_LINES":        nil,
+	"HYPERLINKED_SHOW_GLOBAL":        {"1"},
+	"HYPERLINKED_SSH_HOST":           nil,
+	"HYPERLINKED_STATS":              {"1"},
+	"HYPERLINKED_STDLIB_LINKS":       {"local", "web"},
+	"HYPERLINKED_TAG_COLUMN":         nil,
+	"HYPERLINKED_TERMINAL":           nil,
+	"HYPERLINKED_THEME":              nil,
+	"HYPERLINKED_TITLE":              {"title", "progress", "both"},
+	"HYPERLINKED_TRACE":              {"1"},
+	"HYPERLINKED_TRACE_FIELD":        nil,
+	"HYPERLINKED_TRACE_URL_TEMPLATE": nil,
+	"HYPERLINKED_WORKSPACE":          nil,
+	"HYPERLINKED_WORMHOLE":           nil,
+}
+
+// ConfigReport describes the settings in effect, one key=value pair per
+// line, followed by a warning line for each HYPERLINKED_ environment
+// variable that is unknown or has a value that was ignored. Set
+// HYPERLINKED_DEBUG=1 to print it to stderr at startup.
+func ConfigReport() string {
+	s := cfg()
+	width, widthSource := termWidth(), "HYPERLINKED_COLUMNS"
+	if widthFunc.Load() != nil {
+		widthSource = "SetWidthFunc"
+	} else if width == 0 {
+		widthSource = "none"
+	}
+	layout := "free"
+	if s.Columns != nil {
+		layout = "columns"
+	}
+	termSource := "detected"
+	if _, ok := Terminals[os.Getenv("HYPERLINKED_TERMINAL")]; ok {
+		termSource = "HYPERLINKED_TERMINAL"
+	}
+	var sinkTypes []string
+	if cur := sinks.Load(); cur != nil {
+		for _, s := range *cur {
+			sinkTypes = append(sinkTypes, fmt.Sprintf("%T", s))
+		}
+	}
+
+	fields := []Field{
+		{"link_format", s.LinkFormat},
+		{"link_format_resolved", ResolvedLinkFormat()},
+		{"environment", s.Environment.Kind},
+		{"remote_authority", s.Environment.Authority},
+		{"path_map", s.PathMap},
+		{"workspace", s.Workspace},
+		{"missing_source", s.MissingSource},
+		{"check_links", s.CheckLinks},
+		{"path_display", s.PathDisplay},
+		{"stdlib_links", s.StdlibLinks},
+		{"banner", s.Banner},
+		{"stats", s.Stats},
+		{"share_lines", s.ShareLines},
+		{"show_global_fields", s.ShowGlobalFields},
+		{"max_rate", s.MaxRate},
+		{"max_depth", s.MaxDepth},
+		{"max_arg_length", s.MaxArgLength},
+		{"max_message", s.MaxMessageSize},
+		{"github_repo", s.GitHubRepo},
+		{"github_ref", s.GitHubRef},
+		{"github_root", s.GitHubRoot},
+		{"wormhole", s.WormholeAddr},
+		{"probe_wormhole", s.ProbeWormhole},
+		{"terminal", s.Terminal.Name},
+		{"terminal_source", termSource},
+		{"hyperlinks", s.Terminal.Hyperlinks},
+		{"max_url", urlLimit(s.Terminal, s.MaxURLLength)},
+		{"truncate", s.Truncate},
+		{"ellipsis", s.Ellipsis},
+		{"ambiguous_width", s.AmbiguousWidth},
+		{"emoji_width", s.EmojiWidth},
+		{"width", width},
+		{"width_source", widthSource},
+		{"align_continuation", s.AlignContinuation},
+		{"tag_column", s.TagColumn},
+		{"sanitize", s.Sanitize},
+		{"precision", Precision()},
+		{"no_timer_format", s.NoTimerFormat},
+		{"seq", std.seq != nil},
+		{"layout", layout},
+		{"level", s.Level},
+		{"filter", Filter()},
+		{"sample", s.SampleRate},
+		{"theme", CurrentTheme().Name},
+		{"notify", s.NotifyOnFailure},
+		{"notify_style", s.NotifyStyle},
+		{"marks", s.Marks},
+		{"title", s.Title},
+		{"sinks", strings.Join(sinkTypes, ",")},
+		{"trace", s.Trace},
+		{"trace_url_template", s.TraceURLTemplate},
+		{"trace_field", s.TraceField},
+		{"labels", labelKeys(s.LabelKeys)},
+		{"process_label", s.ProcessLabel},
+	}
+	for _, w := range envWarnings() {
+		fields = append(fields, Field{"warning", w})
+	}
+	var b strings.Builder
+	for _, f := range fields {
+		b.WriteString(f.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// labelKeys returns keys, as set by ShowLabels, joined, or "all".
+func labelKeys(keys []string) string {
+	if keys != nil {
+		return strings.Join(keys, ",")
+	}
+	return "all"
+}
+
+// envWarnings returns a description of each HYPERLINKED_ environment
+// variable that is unknown or has a value that is ignored.
+func envWarnings() []string {
+	var warnings []string
+	warn := func(format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+	env := os.Environ()
+	slices.Sort(env)
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		if !strings.HasPrefix(name, "HYPERLINKED_") || value == "" {
+			continue
+		}
+		allowed, known := envVars[name]
+		switch {
+		case !known:
+			warn("%s: unknown variable", name)
+		case allowed != nil && !slices.Contains(allowed, value):
+			warn("%s: %q is not one of %s", name, value, strings.Join(allowed, ", "))
+		}
+		switch name {
+		case "HYPERLINKED_COLUMNS":
+			if n, err := strconv.Atoi(value); err != nil || n <= 0 {
+				warn("%s: %q is not a positive integer", name, value)
+			}
+		case "HYPERLINKED_MAX_ARG_LENGTH", "HYPERLINKED_MAX_DEFERRED", "HYPERLINKED_MAX_DEPTH", "HYPERLINKED_MAX_MESSAGE", "HYPERLINKED_MAX_RATE", "HYPERLINKED_MAX_URL", "HYPERLINKED_RESULT_STACK", "HYPERLINKED_SHARE_LINES", "HYPERLINKED_TAG_COLUMN":
+			if _, err := strconv.Atoi(value); err != nil {
+				warn("%s: %q is not an integer", name, value)
+			}
+		case "HYPERLINKED_FILTER":
+			if _, err := parseFilter(value); err != nil {
+				warn("%s: %v", name, err)
+			}
+		case "HYPERLINKED_FORMAT":
+			if _, err := ParseLinkFormat(value); err != nil {
+				warn("%s: %v", name, err)
+			}
+		case "HYPERLINKED_LEVEL":
+			if _, err := ParseLevel(value); err != nil {
+				warn("%s: %v", name, err)
+			}
+		case "HYPERLINKED_PATH_MAP":
+			for _, m := range strings.Split(value, ",") {
+				if from, _, ok := strings.Cut(m, "="); !ok || from == "" {
+					warn("%s: %q is not a from=to mapping", name, m)
+				}
+			}
+		case "HYPERLINKED_SAMPLE":
+			if r, err := strconv.ParseFloat(value, 64); err != nil || r <= 0 || r > 1 {
+				warn("%s: %q is not a rate in (0, 1]", name, value)
+			}
+		case "HYPERLINKED_TERMINAL":
+			if _, ok := Terminals[value]; !ok {
+				warn("%s: unknown terminal %q", name, value)
+			}
+		case "HYPERLINKED_THEME":
+			if !slices.Contains(Themes(), value) {
+				warn("%s: unknown theme %q", name, value)
+			}
+		}
+	}
+	return warnings
+}
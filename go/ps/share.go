@@ -0,0 +1,102 @@
+package ps
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	sharedMu sync.Mutex
+	// shared is a ring buffer of the entries kept for Share once it holds
+	// ShareLines entries, with the oldest at sharedNext.
+	shared     []Entry
+	sharedNext int
+)
+
+// sharing reports whether entries are kept for Share.
+func sharing() bool {
+	return cfg().ShareLines > 0
+}
+
+// keepShared keeps e for Share, if entries are kept.
+func keepShared(e Entry) {
+	limit := cfg().ShareLines
+	if limit <= 0 {
+		return
+	}
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	if len(shared) != limit && sharedNext != 0 {
+		// ShareLines was changed: unwind the ring before resizing it.
+		shared = append(slices.Clone(shared[sharedNext:]), shared[:sharedNext]...)
+		sharedNext = 0
+	}
+	if len(shared) > limit {
+		shared = slices.Clone(shared[len(shared)-limit:])
+	}
+	if len(shared) < limit {
+		shared = append(shared, e)
+		return
+	}
+	shared[sharedNext] = e
+	sharedNext = (sharedNext + 1) % len(shared)
+}
+
+// Share returns the last n lines printed as plain text ready to paste into
+// an issue or chat, where the OSC8 links of terminal output would be lost:
+// each line is followed by a GitHub permalink to its source location, at
+// the revision the program was built from, or a file:line for files
+// outside the repository. The repository is found as for the "githubdev"
+// link format. Lines are only kept if Settings.ShareLines is set, as with
+// HYPERLINKED_SHARE_LINES=100:
+//
+//	if err != nil {
+//		fmt.Fprintln(os.Stderr, ps.Share(20))
+//	}
+//
+// Share returns all the lines kept if there are fewer than n, and "" if n
+// is 0 or less.
+func Share(n int) string {
+	sharedMu.Lock()
+	entries := append(slices.Clone(shared[sharedNext:]), shared[:sharedNext]...)
+	sharedMu.Unlock()
+	n = max(n, 0)
+	if n < len(entries) {
+		entries = entries[len(entries)-n:]
+	}
+
+	var b strings.Builder
+	for _, e := range entries {
+		first, rest, multiline := strings.Cut(e.Msg, "\n")
+		b.Write(appendTimestamp(nil, e.Elapsed))
+		b.WriteString(" " + e.Tag.prefix() + first + formatFields(e.Fields))
+		if e.File != "" {
+			b.WriteString("  " + shareLocation(e.File, e.Line))
+		}
+		b.WriteByte('\n')
+		if multiline {
+			b.WriteString(strings.TrimSuffix(rest, "\n") + "\n")
+		}
+	}
+	return b.String()
+}
+
+// shareLocation returns the GitHub permalink to file:line, or file:line
+// itself if file is not in the repository.
+func shareLocation(file string, line int) string {
+	repo, ref, root := githubSite()
+	rev := ref
+	if b := Build(); b != nil && b.Revision != "" {
+		rev = b.Revision
+	} else if c := gitCheckout(); c.commit != "" {
+		rev = c.commit
+	}
+	if repo != "" && rev != "" && root != "" {
+		if url, ok := githubBlobURL("https://github.com/"+repo, rev, root, file, line); ok {
+			return url
+		}
+	}
+	return shortPath(file) + ":" + strconv.Itoa(line)
+}
@@ -0,0 +1,66 @@
+package ps
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// resetShared forgets the lines kept for Share, before and after t.
+func resetShared(t *testing.T) {
+	reset := func() {
+		sharedMu.Lock()
+		shared, sharedNext = nil, 0
+		sharedMu.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+// sharedMsgs returns the messages of the lines returned by Share(n).
+func sharedMsgs(n int) []string {
+	var msgs []string
+	for _, line := range strings.Split(strings.TrimSuffix(Share(n), "\n"), "\n") {
+		if _, after, ok := strings.Cut(line, "] "); ok {
+			msg, _, _ := strings.Cut(after, "  ")
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
+
+func TestShare(t *testing.T) {
+	testCaller(t)
+	resetShared(t)
+	configure(t, func(s *Settings) { s.ShareLines = 3 })
+	for i := 1; i <= 5; i++ {
+		F("line %d\n", i)
+	}
+	for _, tt := range []struct {
+		n    int
+		want []string
+	}{
+		{2, []string{"line 4", "line 5"}},
+		{3, []string{"line 3", "line 4", "line 5"}},
+		{10, []string{"line 3", "line 4", "line 5"}},
+		{0, nil},
+		{-1, nil},
+	} {
+		if got := sharedMsgs(tt.n); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("Share(%d) returned %q, want %q", tt.n, got, tt.want)
+		}
+	}
+
+	configure(t, func(s *Settings) { s.ShareLines = 2 })
+	F("line 6\n")
+	if got, want := sharedMsgs(10), []string{"line 5", "line 6"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("after shrinking, Share(10) returned %q, want %q", got, want)
+	}
+	configure(t, func(s *Settings) { s.ShareLines = 4 })
+	F("line 7\n")
+	F("line 8\n")
+	F("line 9\n")
+	if got, want := sharedMsgs(10), []string{"line 6", "line 7", "line 8", "line 9"}; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("after growing, Share(10) returned %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,66 @@
+package ps
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// oversized reports whether a message of n bytes exceeds the limit set by
+// Settings.MaxMessageSize, and is spilled to a file by spillMessage.
+func oversized(n int) bool {
+	limit := cfg().MaxMessageSize
+	return limit > 0 && n > limit
+}
+
+// spillMessage writes msg, without escape codes, to a new temporary file
+// and returns the line printed in its place, such as "⬇ wrote 2.3MB
+// payload to /tmp/ps-1234.txt", with the path linked to the file, after
+// which the link returns to outer. The trailing newline of msg, if any, is
+// kept.
+func spillMessage(msg, outer string) string {
+	body, newline := strings.CutSuffix(msg, "\n")
+	body = stripEscapes(body)
+	note := spillNote(body, outer)
+	if newline {
+		note += "\n"
+	}
+	return note
+}
+
+// spillNote writes body to a new temporary file and returns the line
+// describing it.
+func spillNote(body, outer string) string {
+	size := formatSize(len(body))
+	f, err := os.CreateTemp("", "ps-*.txt")
+	if err != nil {
+		return "⬇ " + size + " payload not shown: " + err.Error()
+	}
+	_, err = f.WriteString(body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return "⬇ " + size + " payload not shown: " + err.Error()
+	}
+	path := f.Name()
+	term := cfg().Terminal
+	if url := FormatURL(path, 1); term.linkable(url) {
+		path = term.osc8(url) + path + term.osc8(outer)
+	}
+	return "⬇ wrote " + size + " payload to " + path
+}
+
+// formatSize formats n bytes in decimal units, as "512B", "48.0KB" or
+// "2.3MB".
+func formatSize(n int) string {
+	switch {
+	case n < 1000:
+		return fmt.Sprintf("%dB", n)
+	case n < 1000*1000:
+		return fmt.Sprintf("%.1fKB", float64(n)/1000)
+	case n < 1000*1000*1000:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1000*1000))
+	}
+	return fmt.Sprintf("%.1fGB", float64(n)/(1000*1000*1000))
+}
@@ -0,0 +1,297 @@
+package ps
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// StackOption configures the output of Stack.
+type StackOption func(*stackConfig)
+
+type stackConfig struct {
+	location bool
+	align    bool
+	color    bool
+}
+
+// StackLocation adds a column with the short file:line of each frame.
+func StackLocation() StackOption {
+	return func(c *stackConfig) { c.location = true }
+}
+
+// StackAlign pads the frame index and function columns so that the
+// columns following them line up.
+func StackAlign() StackOption {
+	return func(c *stackConfig) { c.align = true }
+}
+
+// StackColor dims runtime and standard library frames and highlights the
+// first frame belonging to the main module.
+func StackColor() StackOption {
+	return func(c *stackConfig) { c.color = true }
+}
+
+// Stack prints the last n stack frames, each as a hyperlink to its source location.
+// Skips runtime internals and starts from the caller of Stack.
+// Runs of frames repeated by recursion are collapsed into a single
+// "... N more of fn (file.go:42) ..." line.
+func Stack(n int, opts ...StackOption) {
+	stack(1, n, opts...)
+}
+
+// stack prints the last n stack frames starting from the caller skip frames
+// above stack's caller (0 = stack's caller).
+func stack(skip, n int, opts ...StackOption) {
+	if !Enabled(LevelInfo) || !allowedTag("") {
+		return
+	}
+	printStack(stackFrames(skip+1, n, callSite{}), opts)
+}
+
+// stackAt prints the last n stack frames starting from site, as resolved
+// by Printer.callSite through Helper, HelperPackage and Auto, if it is on
+// the stack above stackAt's caller, and otherwise as stack does.
+func stackAt(skip int, site callSite, n int, opts ...StackOption) {
+	if !Enabled(LevelInfo) || !allowedTag("") {
+		return
+	}
+	printStack(stackFrames(skip+1, n, site), opts)
+}
+
+// maxSiteDepth is how many frames above the caller stackAt looks for the
+// site it starts from.
+const maxSiteDepth = 64
+
+// stackFrames returns the last n stack frames starting from the caller
+// skip frames above stackFrames's caller or, if site is set and among the
+// frames above it, from site.
+func stackFrames(skip, n int, site callSite) []runtime.Frame {
+	depth := n
+	if site.ok {
+		depth += maxSiteDepth
+	}
+	// Skip 2: runtime.Callers + stackFrames
+	pcs := make([]uintptr, depth)
+	got := runtime.Callers(skip+2, pcs)
+	if got == 0 {
+		return nil
+	}
+
+	var frames []runtime.Frame
+	iter := runtime.CallersFrames(pcs[:got])
+	for {
+		frame, more := iter.Next()
+		frame.File, frame.Line = sourceFor(runtime.FuncForPC(frame.PC), frame.File, frame.Line)
+		frames = append(frames, frame)
+		if !more {
+			break
+		}
+	}
+	if site.ok {
+		for i, frame := range frames {
+			if frame.File == site.file && frame.Line == site.line && frame.Function == site.funcName {
+				frames = frames[i:]
+				break
+			}
+		}
+	}
+	return frames[:min(n, len(frames))]
+}
+
+// printStack prints frames as configured by options.
+func printStack(frames []runtime.Frame, options []StackOption) {
+	if len(frames) == 0 {
+		return
+	}
+	var opts stackConfig
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	now := time.Now()
+	since := elapsed(now)
+	goroutine := goroutineID()
+	process := ProcessLabel()
+	labels := shown(currentLabels())
+
+	if !allowed(frames[0].PC, frames[0].File, frames[0].Function) {
+		return
+	}
+
+	indexWidth, funcWidth := 0, 0
+	if opts.align {
+		indexWidth = len(fmt.Sprint(len(frames) - 1))
+		for _, frame := range frames {
+			if w := textWidth(shortFuncName(frame.Function)); w > funcWidth {
+				funcWidth = w
+			}
+		}
+	}
+
+	truncate := cfg().Truncate
+	width := 0
+	if truncate {
+		width = termWidth()
+	}
+
+	// emit prints styled, a rendering of msg, linked to frame, after the
+	// prefix of every line.
+	emit := func(msg, styled string, frame runtime.Frame) {
+		e := Entry{Time: now, Elapsed: since, Msg: msg, File: frame.File, Line: frame.Line, Func: frame.Function,
+			Goroutine: goroutine, Process: process, Labels: labels}
+		text := string(appendLinePrefix(nil, e)) + styled + "\n"
+		if truncate && width > 0 {
+			text = truncateToWidth(text, width)
+		}
+		write(FormatOSC8(text, FormatURL(frame.File, frame.Line)))
+		dispatch(e)
+	}
+
+	theme := CurrentTheme()
+	highlighted := false
+	emitFrame := func(i int) {
+		frame := frames[i]
+		funcName := shortFuncName(frame.Function)
+		text := fmt.Sprintf("#%-*d %s", indexWidth, i, funcName)
+		if opts.location {
+			pad := funcWidth - textWidth(funcName)
+			if pad < 0 {
+				pad = 0
+			}
+			text += strings.Repeat(" ", pad) + "  " + fmt.Sprintf("%s:%d", DisplayPath(frame.File), frame.Line)
+		}
+		styled := text
+		if opts.color {
+			switch {
+			case isStdlibFrame(frame):
+				styled = theme.Dim.Render(text)
+			case !highlighted && isMainModuleFrame(frame):
+				styled = theme.Highlight.Render(text)
+				highlighted = true
+			}
+		}
+		emit(text, styled, frame)
+	}
+
+	for i := 0; i < len(frames); i++ {
+		period, reps := findCycle(frames[i:])
+		if reps == 0 {
+			emitFrame(i)
+			continue
+		}
+		// Print the first repetition of the cycle, then summarize the others.
+		for j := i; j < i+period; j++ {
+			emitFrame(j)
+		}
+		frame := frames[i]
+		text := fmt.Sprintf("... %d more of %s (%s:%d) ...",
+			(reps-1)*period, shortFuncName(frame.Function), DisplayPath(frame.File), frame.Line)
+		styled := text
+		if opts.color {
+			styled = theme.Dim.Render(text)
+		}
+		emit(text, styled, frame)
+		i += reps*period - 1
+	}
+}
+
+// minCycleReps is the number of consecutive repetitions of a sequence of
+// frames from which Stack collapses them.
+const minCycleReps = 3
+
+// maxCyclePeriod is the longest sequence of frames Stack checks for
+// repetition (e.g. 2 for mutual recursion between two functions).
+const maxCyclePeriod = 8
+
+// findCycle looks for a sequence of frames at the start of frames that
+// repeats at least minCycleReps times, returning its length and number of
+// repetitions, or 0, 0 if there is none.
+func findCycle(frames []runtime.Frame) (period, reps int) {
+	for period = 1; period <= maxCyclePeriod && period*minCycleReps <= len(frames); period++ {
+		reps = 1
+		for (reps+1)*period <= len(frames) && sameFrames(frames[:period], frames[reps*period:(reps+1)*period]) {
+			reps++
+		}
+		if reps >= minCycleReps {
+			return period, reps
+		}
+	}
+	return 0, 0
+}
+
+func sameFrames(a, b []runtime.Frame) bool {
+	for i := range a {
+		if a[i].PC != b[i].PC {
+			return false
+		}
+	}
+	return true
+}
+
+// shortFuncName strips the package path from a fully qualified function name,
+// leaving e.g. "ps.Stack" or "http.(*Server).Serve".
+func shortFuncName(funcName string) string {
+	if idx := lastIndex(funcName, '/'); idx >= 0 {
+		return funcName[idx+1:]
+	}
+	return funcName
+}
+
+// framePackage returns the import path of the package containing frame.
+func framePackage(frame runtime.Frame) string {
+	name := frame.Function
+	slash := lastIndex(name, '/')
+	if dot := strings.IndexByte(name[slash+1:], '.'); dot >= 0 {
+		return name[:slash+1+dot]
+	}
+	return name
+}
+
+// isStdlibFrame reports whether frame belongs to the runtime or the
+// standard library.
+func isStdlibFrame(frame runtime.Frame) bool {
+	if goroot := runtime.GOROOT(); goroot != "" && strings.HasPrefix(frame.File, filepath.ToSlash(goroot)+"/src/") {
+		return true
+	}
+	pkg := framePackage(frame)
+	if pkg == "main" || pkg == "" {
+		return false
+	}
+	first, _, _ := strings.Cut(pkg, "/")
+	return !strings.Contains(first, ".")
+}
+
+// mainModule is the path of the main module, or "" if unknown.
+var mainModule = func() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		return info.Main.Path
+	}
+	return ""
+}()
+
+// isMainModuleFrame reports whether frame belongs to the main module. If the
+// main module is unknown (as in tests), any frame outside the standard
+// library and the module cache qualifies.
+func isMainModuleFrame(frame runtime.Frame) bool {
+	pkg := framePackage(frame)
+	if pkg == "main" {
+		return true
+	}
+	if mainModule != "" {
+		return pkg == mainModule || strings.HasPrefix(pkg, mainModule+"/")
+	}
+	return !isStdlibFrame(frame) && !strings.Contains(frame.File, "/pkg/mod/")
+}
+
+func lastIndex(s string, c byte) int {
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] == c {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,27 @@
+package ps
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestStackPrefix(t *testing.T) {
+	prev := Precision()
+	SetPrecision(time.Microsecond)
+	t.Cleanup(func() { SetPrecision(prev) })
+	var out bytes.Buffer
+	SetOutput(&out)
+	t.Cleanup(func() { SetOutput(nil) })
+	s := sink(t)
+
+	Stack(1)
+	want := regexp.MustCompile(`^(\S+ +)?\[ *\d+\.\d{3}\] #0 ps\.TestStackPrefix\n$`)
+	if got := stripEscapes(out.String()); !want.MatchString(got) {
+		t.Errorf("printed %q, want it to match %s", got, want)
+	}
+	if got, want := stripEscapes(out.String()), stripEscapes(linePrefix(s.last()))+s.last().Msg+"\n"; got != want {
+		t.Errorf("printed %q, want the prefix of other lines, %q", got, want)
+	}
+}
@@ -0,0 +1,143 @@
+package ps
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Machine tracks the state of something, such as an order, printing each
+// transition. It is created by StateMachine.
+type Machine struct {
+	p       *Printer
+	name    string
+	allowed map[string][]string
+
+	mu      sync.Mutex
+	state   string
+	history []StateChange
+}
+
+// StateChange is a transition between states attempted with Machine.To.
+type StateChange struct {
+	From, To string
+	Time     time.Time
+	// File and Line are the location of the call of To.
+	File string
+	Line int
+	// Illegal is whether the transition was not allowed, and so rejected.
+	Illegal bool
+
+	site callSite
+}
+
+// StateMachine returns a machine named name, with no state, whose allowed
+// transitions are given by allowed, from each state to the states it may
+// change to. The states allowed first are given by allowed[""]; if there
+// are none, any is. A nil allowed allows every transition.
+//
+//	sm := ps.StateMachine("order", map[string][]string{
+//		"":        {"pending"},
+//		"pending": {"paid", "cancelled"},
+//		"paid":    {"shipped"},
+//	})
+//	sm.To("pending")
+//	sm.To("shipped") // 🔴 order: illegal transition pending → shipped
+func StateMachine(name string, allowed map[string][]string) *Machine {
+	return std.StateMachine(name, allowed)
+}
+
+// StateMachine is like the package-level StateMachine, printing with p.
+func (p *Printer) StateMachine(name string, allowed map[string][]string) *Machine {
+	return &Machine{p: p, name: name, allowed: allowed}
+}
+
+// To changes the state to state, printing a Transition line linked to the
+// call of To. If the transition is not allowed, the state is unchanged, a
+// Bad line listing the allowed states is printed instead, and To returns
+// false.
+func (m *Machine) To(state string) bool {
+	site := m.p.callSite(1)
+	m.mu.Lock()
+	from := m.state
+	next, known := m.allowed[from]
+	legal := m.allowed == nil || slices.Contains(next, state) || from == "" && !known
+	t := StateChange{From: from, To: state, Time: time.Now(), File: site.file, Line: site.line, Illegal: !legal, site: site}
+	m.history = append(m.history, t)
+	if legal {
+		m.state = state
+	}
+	m.mu.Unlock()
+
+	if legal {
+		m.print(t)
+		return true
+	}
+	if m.p.prints(Bad) {
+		allowed := strings.Join(next, ", ")
+		if allowed == "" {
+			allowed = "none"
+		}
+		m.p.printAt(site, newEntry(Bad), "%s: illegal transition %s → %s (allowed: %s)\n",
+			[]interface{}{m.name, stateName(from), state, allowed})
+	}
+	return false
+}
+
+// State returns the current state, or "" if To has not succeeded.
+func (m *Machine) State() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state
+}
+
+// History returns the transitions attempted so far, including the illegal
+// ones, in order.
+func (m *Machine) History() []StateChange {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.history)
+}
+
+// PrintHistory prints the transitions attempted so far again, each with
+// its original timestamp and linked to the call of To that attempted it,
+// with illegal ones as Bad lines.
+// Call it at the end of a test to see the path taken:
+//
+//	t.Cleanup(sm.PrintHistory)
+func (m *Machine) PrintHistory() {
+	for _, t := range m.History() {
+		if t.Illegal {
+			if m.p.prints(Bad) {
+				m.p.printAt(t.site, t.entry(Bad), "%s: illegal transition %s → %s\n",
+					[]interface{}{m.name, stateName(t.From), t.To})
+			}
+			continue
+		}
+		m.print(t)
+	}
+}
+
+// print prints the legal transition t.
+func (m *Machine) print(t StateChange) {
+	if m.p.prints(Transition) {
+		m.p.printAt(t.site, t.entry(Transition), "%s: %s → %s\n", []interface{}{m.name, stateName(t.From), t.To})
+	}
+}
+
+// entry returns the entry tagged tag printed for t, at the time of t.
+func (t StateChange) entry(tag Tag) Entry {
+	e := newEntry(tag)
+	e.Time = t.Time
+	e.Elapsed = elapsed(t.Time)
+	return e
+}
+
+// stateName returns state as printed: "(none)" for the empty state.
+func stateName(state string) string {
+	if state == "" {
+		return "(none)"
+	}
+	return state
+}
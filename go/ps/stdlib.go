@@ -0,0 +1,65 @@
+package ps
+
+import (
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Targets for Settings.StdlibLinks.
+const (
+	// StdlibLinksLocal links to the sources in the local GOROOT.
+	StdlibLinksLocal = "local"
+	// StdlibLinksWeb links to the sources of the Go version the program
+	// was built with on cs.opensource.google.
+	StdlibLinksWeb = "web"
+)
+
+// stdlibLocation returns the file to link to for file, and if it is to be
+// linked on the web, the URL of file:line instead, if file is in the
+// standard library; otherwise it returns file and "".
+func stdlibLocation(file string, line int) (string, string) {
+	rel, ok := stdlibPath(file)
+	if !ok {
+		return file, ""
+	}
+	if cfg().StdlibLinks == StdlibLinksWeb {
+		return file, stdlibWebURL(rel, line)
+	}
+	if goroot := runtime.GOROOT(); goroot != "" {
+		return filepath.Join(goroot, "src", filepath.FromSlash(rel)), ""
+	}
+	return file, ""
+}
+
+// stdlibPath returns the path of file relative to GOROOT/src, if it is a
+// file of the standard library: a path in GOROOT, or a path like
+// "runtime/proc.go", as reported for code built with -trimpath, whose first
+// element, unlike that of a module path, has no dot.
+func stdlibPath(file string) (string, bool) {
+	slash := filepath.ToSlash(file)
+	if goroot := runtime.GOROOT(); goroot != "" {
+		if rel, ok := strings.CutPrefix(slash, filepath.ToSlash(goroot)+"/src/"); ok {
+			return rel, true
+		}
+	}
+	if filepath.IsAbs(file) || strings.HasPrefix(slash, ".") {
+		return "", false
+	}
+	first, _, ok := strings.Cut(slash, "/")
+	if !ok || strings.Contains(first, ".") || mainModule != "" && strings.HasPrefix(slash, mainModule+"/") {
+		return "", false
+	}
+	return slash, true
+}
+
+// stdlibWebURL returns the URL of line of rel, a file in GOROOT/src, on
+// cs.opensource.google, at the Go version the program was built with.
+func stdlibWebURL(rel string, line int) string {
+	ref := "refs/heads/master"
+	if v, _, _ := strings.Cut(runtime.Version(), " "); strings.HasPrefix(v, "go1") {
+		ref = "refs/tags/" + v
+	}
+	return "https://cs.opensource.google/go/go/+/" + ref + ":src/" + escapePath(rel) + ";l=" + strconv.Itoa(line)
+}
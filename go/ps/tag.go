@@ -0,0 +1,94 @@
+package ps
+
+import (
+	"strings"
+)
+
+// Tag classifies an entry by the kind of event it records. Tags are
+// rendered as an emoji prefix in the terminal and recorded by name in
+// structured sinks, and can be selected with SetFilter ("tag:failure").
+type Tag string
+
+// The built-in tags.
+const (
+	Sent       Tag = "sent"
+	Received   Tag = "received"
+	Written    Tag = "written"
+	Listening  Tag = "listening"
+	Transition Tag = "transition"
+	Started    Tag = "started"
+	Success    Tag = "success"
+	Failure    Tag = "failure"
+	Retry      Tag = "retry"
+	Scheduled  Tag = "scheduled"
+	Good       Tag = "good"
+	Bad        Tag = "bad"
+	InProgress Tag = "in-progress"
+)
+
+var tagEmoji = map[Tag]string{
+	Sent:       "⤴",
+	Received:   "⬅",
+	Written:    "⬇",
+	Listening:  "📡",
+	Transition: "⚙️",
+	Started:    "🚀",
+	Success:    "✅",
+	Failure:    "❌",
+	Retry:      "🔄",
+	Scheduled:  "🕐",
+	Good:       "🟢",
+	Bad:        "🔴",
+	InProgress: "🟡",
+}
+
+// Emoji returns the emoji rendered for t, or "" for tags other than the
+// built-in ones.
+func (t Tag) Emoji() string {
+	return tagEmoji[t]
+}
+
+// Level returns the minimum level of entries tagged t: LevelError for
+// Failure, LevelWarn for Bad and LevelInfo otherwise.
+func (t Tag) Level() Level {
+	switch t {
+	case Failure:
+		return LevelError
+	case Bad:
+		return LevelWarn
+	default:
+		return LevelInfo
+	}
+}
+
+// prefix returns the text rendered before the message of an entry tagged t.
+func (t Tag) prefix() string {
+	switch {
+	case t == "":
+		return ""
+	case t.Emoji() != "":
+		return t.Emoji() + " "
+	default:
+		return "[" + string(t) + "] "
+	}
+}
+
+// T prints a line tagged t, with a millisecond timestamp prefix. The
+// format is as for F; a trailing newline is added if missing.
+//
+//	ps.T(ps.Retry, "attempt %d", n)
+func T(t Tag, format string, args ...interface{}) {
+	std.tagf(1, t, format, args)
+}
+
+// T is like the package-level T.
+func (p *Printer) T(t Tag, format string, args ...interface{}) {
+	p.tagf(1, t, format, args)
+}
+
+func (p *Printer) tagf(skip int, t Tag, format string, args []interface{}) {
+	if !strings.HasSuffix(format, "\n") {
+		format += "\n"
+	}
+	p.printf(skip+1, t, format, args)
+}
@@ -0,0 +1,124 @@
+package ps
+
+import (
+	"os"
+	"strconv"
+)
+
+// Terminal describes how a terminal handles OSC8 hyperlinks.
+type Terminal struct {
+	Name string
+	// Hyperlinks is whether the terminal supports OSC8 at all. Without
+	// support, text is printed without the escape codes, which some
+	// terminals would otherwise display.
+	Hyperlinks bool
+	// MaxURL is the length in bytes of the longest URL the terminal accepts,
+	// or 0 for no limit. Text whose link would be longer is printed
+	// unlinked (see also Settings.MaxURLLength).
+	MaxURL int
+	// LinkIDs is whether to add an id parameter to links, so that a link
+	// wrapped across lines is highlighted as a whole on hover. The id is
+	// derived from the URL, so all output of one call site shares it.
+	LinkIDs bool
+}
+
+// Terminals are the known terminal profiles, by name. Profiles not
+// detected from the environment can be selected by setting
+// HYPERLINKED_TERMINAL to their name.
+var Terminals = map[string]Terminal{
+	"generic":          {Name: "generic", Hyperlinks: true, MaxURL: 2083},
+	"none":             {Name: "none"},
+	"kitty":            {Name: "kitty", Hyperlinks: true, LinkIDs: true},
+	"wezterm":          {Name: "wezterm", Hyperlinks: true, LinkIDs: true},
+	"ghostty":          {Name: "ghostty", Hyperlinks: true, LinkIDs: true},
+	"iterm2":           {Name: "iterm2", Hyperlinks: true, MaxURL: 2083},
+	"vscode":           {Name: "vscode", Hyperlinks: true},
+	"vte":              {Name: "vte", Hyperlinks: true, MaxURL: 2083, LinkIDs: true},
+	"windows-terminal": {Name: "windows-terminal", Hyperlinks: true, MaxURL: 2048, LinkIDs: true},
+	"apple-terminal":   {Name: "apple-terminal"},
+}
+
+// detectTerminal returns the profile of the terminal output is written to,
+// as for Settings.Terminal.
+func detectTerminal() Terminal {
+	if t, ok := Terminals[os.Getenv("HYPERLINKED_TERMINAL")]; ok {
+		return t
+	}
+	term := os.Getenv("TERM")
+	switch {
+	case term == "dumb" || term == "linux":
+		return Terminals["none"]
+	case term == "xterm-kitty" || os.Getenv("KITTY_WINDOW_ID") != "":
+		return Terminals["kitty"]
+	case term == "xterm-ghostty":
+		return Terminals["ghostty"]
+	}
+	switch os.Getenv("TERM_PROGRAM") {
+	case "WezTerm":
+		return Terminals["wezterm"]
+	case "ghostty":
+		return Terminals["ghostty"]
+	case "iTerm.app":
+		return Terminals["iterm2"]
+	case "vscode":
+		return Terminals["vscode"]
+	case "Apple_Terminal":
+		return Terminals["apple-terminal"]
+	}
+	switch {
+	case os.Getenv("WT_SESSION") != "":
+		return Terminals["windows-terminal"]
+	case os.Getenv("VTE_VERSION") != "":
+		return Terminals["vte"]
+	}
+	return Terminals["generic"]
+}
+
+// linkable reports whether the terminal can link to url, within the URL
+// length limit of the settings in effect.
+func (t Terminal) linkable(url string) bool {
+	limit := urlLimit(t, cfg().MaxURLLength)
+	return t.Hyperlinks && (limit == 0 || len(url) <= limit)
+}
+
+// osc8End is the escape sequence ending a hyperlink.
+const osc8End = "\x1b]8;;\x1b\\"
+
+// osc8 returns the escape sequence starting a hyperlink to url, or ending
+// the current one if url is "" or cannot be linked.
+func (t Terminal) osc8(url string) string {
+	return string(t.appendOSC8(nil, url))
+}
+
+// appendOSC8 appends the escape sequence returned by osc8 to b.
+func (t Terminal) appendOSC8(b []byte, url string) []byte {
+	if !t.Hyperlinks {
+		return b
+	}
+	if url == "" || !t.linkable(url) {
+		return append(b, osc8End...)
+	}
+	b = append(b, "\x1b]8;"...)
+	if t.LinkIDs {
+		b = append(b, "id="...)
+		b = strconv.AppendUint(b, linkID(url), 36)
+	}
+	b = append(b, ';')
+	b = append(b, sanitizeURL(url)...)
+	return append(b, "\x1b\\"...)
+}
+
+// linkID returns the 64-bit FNV-1a hash of url, from which link ids are
+// derived.
+func linkID(url string) uint64 {
+	const (
+		offset = 14695981039346656037
+		prime  = 1099511628211
+	)
+	h := uint64(offset)
+	for i := 0; i < len(url); i++ {
+		h ^= uint64(url[i])
+		h *= prime
+	}
+	return h
+}
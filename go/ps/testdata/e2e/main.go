@@ -0,0 +1,58 @@
+// Command e2e prints the lines checked by TestEndToEnd, as selected by its
+// argument.
+package main
+
+import (
+	"os"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+var cases = map[string]func(){
+	"f": func() {
+		ps.F("hello %s\n", "world")
+	},
+	"ln": func() {
+		ps.Ln("answer", 42)
+	},
+	"tag": func() {
+		ps.T(ps.Success, "done\n")
+	},
+	"long": func() {
+		ps.F("%s\n", "0123456789abcdefghijklmnopqrstuvwxyz")
+	},
+	"multiline": func() {
+		ps.F("first\nsecond\n")
+	},
+	"bell": func() {
+		ps.F("bell%s\n", "\a \x1b[2J")
+	},
+	"inject": func() {
+		ps.F("dump: %s\n", "\x1b[31mred \x1b]8;;https://evil.example\x1b\\link\x1b]8;;\x1b\\")
+	},
+	"link": func() {
+		ps.F("see %s\n", ps.Link("docs", "https://example.com"))
+	},
+	"crash": func() {
+		if err := ps.InstallPanicHook(); err != nil {
+			panic(err)
+		}
+		panic("boom")
+	},
+}
+
+// init says that it ran, for checking that the panic monitor does not run
+// it.
+func init() {
+	if os.Getenv("E2E_INIT") == "1" {
+		os.Stderr.WriteString("init\n")
+	}
+}
+
+func main() {
+	f, ok := cases[os.Args[1]]
+	if !ok {
+		os.Exit(2)
+	}
+	f()
+}
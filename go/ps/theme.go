@@ -0,0 +1,185 @@
+package ps
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Style is an ANSI SGR escape sequence, such as "\x1b[1;31m", or "" for
+// unstyled text.
+type Style string
+
+// Render returns text in style s.
+func (s Style) Render(text string) string {
+	if s == "" || text == "" {
+		return text
+	}
+	return string(s) + text + styleReset
+}
+
+const styleReset = "\x1b[0m"
+
+// styledText is text in a style, for passing text styled by this package
+// as an argument of the printing functions, which escape the escape
+// sequences of the other arguments, as set by Settings.Sanitize, but not
+// its style.
+type styledText struct {
+	style Style
+	text  string
+}
+
+// styled returns text in style s, as an argument of the printing
+// functions.
+func (s Style) styled(text string) styledText {
+	return styledText{style: s, text: text}
+}
+
+// Format implements fmt.Formatter, formatting the text with the verb,
+// flags, width and precision given, in the style.
+func (t styledText) Format(f fmt.State, verb rune) {
+	if t.style == "" || t.text == "" {
+		fmt.Fprintf(f, fmt.FormatString(f, verb), t.text)
+		return
+	}
+	fmt.Fprint(f, string(t.style))
+	fmt.Fprintf(f, fmt.FormatString(f, verb), t.text)
+	fmt.Fprint(f, styleReset)
+}
+
+// Theme maps levels and tags to the styles used to print them.
+type Theme struct {
+	Name string
+	// Levels styles the timestamp column of lines at each level.
+	Levels map[Level]Style
+	// Tags styles the label printed by Result, and the "[tag]" prefix of
+	// lines with tags that have no emoji.
+	Tags map[Tag]Style
+	// Dim styles secondary text: library stack frames, sampling notes.
+	Dim Style
+	// Highlight styles the stack frames of the main module.
+	Highlight Style
+	// Negative styles negative offsets formatted by Relative with Styled.
+	Negative Style
+}
+
+var (
+	themesMu sync.RWMutex
+	themes   = map[string]Theme{
+		"dark": {
+			Name:      "dark",
+			Levels:    map[Level]Style{LevelDebug: "\x1b[2m", LevelWarn: "\x1b[33m", LevelError: "\x1b[31m"},
+			Tags:      map[Tag]Style{Success: "\x1b[32m", Failure: "\x1b[31m"},
+			Dim:       "\x1b[2m",
+			Highlight: "\x1b[1;33m",
+			Negative:  "\x1b[35m",
+		},
+		"light": {
+			Name:      "light",
+			Levels:    map[Level]Style{LevelDebug: "\x1b[2m", LevelWarn: "\x1b[38;5;130m", LevelError: "\x1b[38;5;160m"},
+			Tags:      map[Tag]Style{Success: "\x1b[38;5;28m", Failure: "\x1b[38;5;160m"},
+			Dim:       "\x1b[2m",
+			Highlight: "\x1b[1;38;5;130m",
+			Negative:  "\x1b[38;5;90m",
+		},
+		"monochrome": {
+			Name:      "monochrome",
+			Levels:    map[Level]Style{LevelDebug: "\x1b[2m", LevelWarn: "\x1b[1m", LevelError: "\x1b[1m"},
+			Tags:      map[Tag]Style{Failure: "\x1b[1m"},
+			Dim:       "\x1b[2m",
+			Highlight: "\x1b[1m",
+			Negative:  "\x1b[4m",
+		},
+	}
+)
+
+// RegisterTheme adds t to the themes selectable by SetTheme and
+// HYPERLINKED_THEME, replacing any theme with the same name.
+func RegisterTheme(t Theme) {
+	themesMu.Lock()
+	defer themesMu.Unlock()
+	themes[t.Name] = t
+}
+
+// SetTheme selects the registered theme with the given name.
+func SetTheme(name string) error {
+	themesMu.RLock()
+	_, ok := themes[name]
+	themesMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown theme %q", name)
+	}
+	Configure(func(s *Settings) { s.Theme = name })
+	return nil
+}
+
+// Themes returns the names of the registered themes.
+func Themes() []string {
+	themesMu.RLock()
+	defer themesMu.RUnlock()
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CurrentTheme returns the selected theme. If HYPERLINKED_THEME names a
+// theme that has not been registered, it is the dark theme.
+func CurrentTheme() Theme {
+	themesMu.RLock()
+	defer themesMu.RUnlock()
+	if t, ok := themes[cfg().Theme]; ok {
+		return t
+	}
+	return themes["dark"]
+}
+
+// linePrefix returns the process label, styled timestamp column and tag
+// prefix of e.
+func linePrefix(e Entry) string {
+	return string(appendLinePrefix(nil, e))
+}
+
+// appendLinePrefix appends the line prefix returned by linePrefix to b.
+func appendLinePrefix(b []byte, e Entry) []byte {
+	t := CurrentTheme()
+	b = appendProcessLabel(b, e.Process)
+	b = t.Levels[e.Level].appendRender(b, func(b []byte) []byte {
+		return appendTimestamp(b, e.Elapsed)
+	})
+	b = append(b, ' ')
+	if e.Seq != 0 {
+		b = append(b, '#')
+		b = strconv.AppendUint(b, e.Seq, 10)
+		b = append(b, ' ')
+	}
+	b = appendLabels(b, e.Labels)
+	prefix := e.Tag.prefix()
+	if e.Tag.Emoji() != "" || prefix == "" {
+		// Emoji bring their own color.
+		b = append(b, prefix...)
+	} else {
+		b = t.Tags[e.Tag].appendRender(b, func(b []byte) []byte {
+			return append(b, prefix...)
+		})
+	}
+	if w := cfg().TagColumn; w > 0 {
+		// The column is followed by a space, as the prefix is.
+		for pad := w + 1 - textWidth(prefix); pad > 0; pad-- {
+			b = append(b, ' ')
+		}
+	}
+	return b
+}
+
+// appendRender appends the text appended by text to b, in style s.
+func (s Style) appendRender(b []byte, text func([]byte) []byte) []byte {
+	if s == "" {
+		return text(b)
+	}
+	b = append(b, s...)
+	return append(text(b), styleReset...)
+}
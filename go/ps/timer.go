@@ -0,0 +1,69 @@
+package ps
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	timersMu sync.Mutex
+	// timers are the start times pushed by PushTimer, by goroutine ID.
+	timers = map[int64][]time.Time{}
+	// hasTimers is whether any goroutine has pushed a timer, so that
+	// looking up the goroutine ID can be avoided otherwise.
+	hasTimers atomic.Bool
+)
+
+// PushTimer starts a timer for the calling goroutine: until the matching
+// PopTimer, its lines show the time since PushTimer instead of since
+// StartTimer. Timers nest, and other goroutines are unaffected:
+//
+//	for _, sc := range scenarios {
+//		ps.PushTimer()
+//		run(sc) // lines start at [    0]
+//		ps.PopTimer()
+//	}
+func PushTimer() {
+	g := goroutineID()
+	now := time.Now()
+	timersMu.Lock()
+	defer timersMu.Unlock()
+	timers[g] = append(timers[g], now)
+	hasTimers.Store(true)
+}
+
+// PopTimer stops the calling goroutine's innermost timer started with
+// PushTimer, restoring the enclosing one. It does nothing if there is none.
+func PopTimer() {
+	g := goroutineID()
+	timersMu.Lock()
+	defer timersMu.Unlock()
+	stack := timers[g]
+	switch len(stack) {
+	case 0:
+	case 1:
+		delete(timers, g)
+		hasTimers.Store(len(timers) > 0)
+	default:
+		timers[g] = stack[:len(stack)-1]
+	}
+}
+
+// timerStart returns the start time of the calling goroutine's innermost
+// timer, or else the time set by StartTimer, which is zero if it has not
+// been called.
+func timerStart() time.Time {
+	if hasTimers.Load() {
+		g := goroutineID()
+		timersMu.Lock()
+		stack := timers[g]
+		timersMu.Unlock()
+		if len(stack) > 0 {
+			return stack[len(stack)-1]
+		}
+	}
+	mu.RLock()
+	defer mu.RUnlock()
+	return startTime
+}
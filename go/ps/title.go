@@ -0,0 +1,76 @@
+package ps
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+)
+
+// Ways of showing the current phase outside the output, for
+// Settings.Title.
+const (
+	// TitleText sets the terminal's window or tab title to the title of
+	// the current Section, restoring the previous title when it ends.
+	TitleText = "title"
+	// TitleProgress shows the taskbar or tab progress indicator of
+	// terminals supporting ConEmu's OSC 9;4, such as Windows Terminal,
+	// while a Section is open, and the percentage set by Progress.
+	TitleProgress = "progress"
+	// TitleBoth does both.
+	TitleBoth = "both"
+)
+
+// openPhases counts the sections open, whose progress indicator is
+// cleared when the last ends.
+var openPhases atomic.Int64
+
+// titleStart returns the escape sequences that show the phase titled by
+// format and args has started, as set by Settings.Title.
+func titleStart(format string, args []interface{}) string {
+	mode := cfg().Title
+	seq := ""
+	if mode == TitleText || mode == TitleBoth {
+		// Push the title, to be popped by titleEnd, then set it.
+		seq += "\x1b[22;0t\x1b]2;" + oneLine(fmt.Sprintf(format, args...)) + "\x07"
+	}
+	if mode == TitleProgress || mode == TitleBoth {
+		openPhases.Add(1)
+		seq += "\x1b]9;4;3\x07"
+	}
+	return seq
+}
+
+// titleEnd returns the escape sequences that show the phase started with
+// the escape sequences titleStart returned for mode has ended.
+func titleEnd(mode string) string {
+	seq := ""
+	if mode == TitleText || mode == TitleBoth {
+		seq += "\x1b[23;0t"
+	}
+	if (mode == TitleProgress || mode == TitleBoth) && openPhases.Add(-1) == 0 {
+		seq += "\x1b]9;4;0\x07"
+	}
+	return seq
+}
+
+// Progress sets the progress indicator of the terminal to percent, from 0
+// to 100, or clears it if percent is negative, if Settings.Title shows
+// progress, so that long runs show how far along they are while the
+// window is in the background:
+//
+//	for i, tc := range cases {
+//		ps.Progress(100 * i / len(cases))
+//		...
+//	}
+//	ps.Progress(-1)
+func Progress(percent int) {
+	mode := cfg().Title
+	if mode != TitleProgress && mode != TitleBoth {
+		return
+	}
+	if percent < 0 {
+		write("\x1b]9;4;0\x07")
+		return
+	}
+	write("\x1b]9;4;1;" + strconv.Itoa(min(percent, 100)) + "\x07")
+}
@@ -0,0 +1,56 @@
+package ps
+
+import (
+	"time"
+)
+
+// Took returns a function printing "✅ label took 340ms", the time since
+// Took was called, linked to the call site of Took. Use it with defer to
+// time a function:
+//
+//	defer ps.Took("load index")()
+func Took(label string) func() {
+	return std.took(1, label)
+}
+
+// Took is like the package-level Took.
+func (p *Printer) Took(label string) func() {
+	return p.took(1, label)
+}
+
+func (p *Printer) took(skip int, label string) func() {
+	if !p.prints(Success) {
+		return func() {}
+	}
+	site := p.callSite(skip + 1)
+	endRegion := traceRegion(label)
+	start := time.Now()
+	return func() {
+		d := time.Since(start)
+		endRegion()
+		p.printAt(site, newEntry(Success), "%s took %s\n", []interface{}{label, formatDuration(d)})
+	}
+}
+
+// Since prints "✅ label took 340ms", the time since t.
+//
+//	t0 := time.Now()
+//	...
+//	ps.Since(t0, "phase 1")
+func Since(t time.Time, label string) {
+	std.printf(1, Success, "%s took %s\n", []interface{}{label, formatDuration(time.Since(t))})
+}
+
+// Since is like the package-level Since.
+func (p *Printer) Since(t time.Time, label string) {
+	p.printf(1, Success, "%s took %s\n", []interface{}{label, formatDuration(time.Since(t))})
+}
+
+// formatDuration formats d rounded to milliseconds, or to microseconds if
+// it is shorter than a millisecond.
+func formatDuration(d time.Duration) string {
+	if d < time.Millisecond && d > -time.Millisecond {
+		return d.Round(time.Microsecond).String()
+	}
+	return d.Round(time.Millisecond).String()
+}
@@ -0,0 +1,39 @@
+package ps
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TraceLink returns traceID linked to its trace in the tracing UI set by
+// TraceURLTemplate, or not linked if it is not set:
+//
+//	ps.F("request failed, trace %s\n", ps.TraceLink(span.SpanContext().TraceID().String()))
+func TraceLink(traceID string) LinkedText {
+	return Link(traceID, traceURL(cfg().TraceURLTemplate, traceID))
+}
+
+// traceURL returns the URL of the trace traceID made from template, or ""
+// if there is no template.
+func traceURL(template, traceID string) string {
+	if template == "" {
+		return ""
+	}
+	return strings.ReplaceAll(template, "{trace_id}", traceID)
+}
+
+// traceTarget returns the link to the trace whose ID is the value of the
+// TraceField field among fields, to be added to the end of their line, or
+// nil if there is none or TraceURLTemplate is not set.
+func traceTarget(fields []Field) *LinkedText {
+	s := cfg()
+	if s.TraceURLTemplate == "" {
+		return nil
+	}
+	for _, f := range fields {
+		if f.Key == s.TraceField {
+			return &LinkedText{Text: "trace", URL: traceURL(s.TraceURLTemplate, fmt.Sprint(f.Value))}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package ps
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// urlLimit returns the effective URL length limit for t, given the limit
+// maxURL set by Settings.MaxURLLength, or 0 for none.
+func urlLimit(t Terminal, maxURL int) int {
+	switch {
+	case maxURL <= 0:
+		return t.MaxURL
+	case t.MaxURL <= 0:
+		return maxURL
+	default:
+		return min(maxURL, t.MaxURL)
+	}
+}
+
+// escapePath percent-encodes every byte of path except unreserved
+// characters, "/" and ":", so that neither the URL nor the OSC8 sequence
+// containing it can be broken by spaces, semicolons, escape characters or
+// other bytes with special meaning.
+func escapePath(path string) string {
+	return percentEncode(filepath.ToSlash(path), func(c byte) bool {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+			return true
+		}
+		return strings.IndexByte("-._~/:", c) >= 0
+	})
+}
+
+// sanitizeURL percent-encodes the bytes of url that cannot appear in an
+// OSC8 sequence: controls, space and non-ASCII bytes.
+func sanitizeURL(url string) string {
+	return percentEncode(url, func(c byte) bool { return ' ' < c && c < 0x7f })
+}
+
+// percentEncode percent-encodes the bytes of s for which keep is false.
+// s is returned as is if there are none.
+func percentEncode(s string, keep func(byte) bool) string {
+	const hex = "0123456789ABCDEF"
+	i := 0
+	for i < len(s) && keep(s[i]) {
+		i++
+	}
+	if i == len(s) {
+		return s
+	}
+	var b strings.Builder
+	b.WriteString(s[:i])
+	for ; i < len(s); i++ {
+		c := s[i]
+		if keep(c) {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0xf])
+	}
+	return b.String()
+}
+
+// shortenPath returns the shortest path equivalent to file: file cleaned
+// of "." and ".." elements, or, if shorter, file with symlinks resolved.
+// Editors need absolute paths, so a path is never made relative.
+func shortenPath(file string) string {
+	short := filepath.Clean(file)
+	if resolved, err := filepath.EvalSymlinks(short); err == nil && filepath.IsAbs(resolved) && len(resolved) < len(short) {
+		short = resolved
+	}
+	return short
+}
@@ -0,0 +1,111 @@
+package ps
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEscapePath(t *testing.T) {
+	for _, tt := range []struct {
+		path, want string
+	}{
+		{"/src/main.go", "/src/main.go"},
+		{"/my src/main.go", "/my%20src/main.go"},
+		{"/src/#1/main.go", "/src/%231/main.go"},
+		{"/src/main.go?x=1", "/src/main.go%3Fx%3D1"},
+		{"/src/100%/main.go", "/src/100%25/main.go"},
+		{"/src/a;b/main.go", "/src/a%3Bb/main.go"},
+		{"/src/\x1b]8;;x\x07/main.go", "/src/%1B%5D8%3B%3Bx%07/main.go"},
+		{"/src/café/main.go", "/src/caf%C3%A9/main.go"},
+		{"/src/\xff\xfe/main.go", "/src/%FF%FE/main.go"},
+		{"C:/src/main.go", "C:/src/main.go"},
+		{"/src/a-b_c.d~e/main.go", "/src/a-b_c.d~e/main.go"},
+	} {
+		if got := escapePath(tt.path); got != tt.want {
+			t.Errorf("escapePath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSanitizeURL(t *testing.T) {
+	for _, tt := range []struct {
+		url, want string
+	}{
+		{"https://example.com/a?b=c#d", "https://example.com/a?b=c#d"},
+		{"https://example.com/a b", "https://example.com/a%20b"},
+		{"https://example.com/\x1b\\", "https://example.com/%1B\\"},
+		{"https://example.com/\x07", "https://example.com/%07"},
+		{"https://example.com/é", "https://example.com/%C3%A9"},
+		{"https://example.com/\xff", "https://example.com/%FF"},
+	} {
+		if got := sanitizeURL(tt.url); got != tt.want {
+			t.Errorf("sanitizeURL(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestFormatURL(t *testing.T) {
+	configure(t, func(s *Settings) {
+		s.LinkFormat = "cursor"
+		s.Environment = Environment{}
+		s.PathMap = ""
+		s.Workspace = ""
+		s.MaxURLLength = 0
+	})
+	for _, tt := range []struct {
+		file, want string
+	}{
+		{"/src/main.go", "cursor://file//src/main.go:7"},
+		{"/my src/#1?x%/main.go", "cursor://file//my%20src/%231%3Fx%25/main.go:7"},
+		{"/src/\xff/main.go", "cursor://file//src/%FF/main.go:7"},
+	} {
+		got := FormatURL(tt.file, 7)
+		if got != tt.want {
+			t.Errorf("FormatURL(%q, 7) = %q, want %q", tt.file, got, tt.want)
+		}
+		for i := 0; i < len(got); i++ {
+			if c := got[i]; c <= ' ' || c >= 0x7f {
+				t.Errorf("FormatURL(%q, 7) = %q, which contains %#x", tt.file, got, c)
+			}
+		}
+	}
+}
+
+func TestFormatURLMaxLength(t *testing.T) {
+	long := "/src/" + strings.Repeat("pkg/../", 100) + "main.go"
+	for _, tt := range []struct {
+		name   string
+		file   string
+		max    int
+		want   string
+		linked bool
+	}{
+		{"short", "/src/main.go", 100, "cursor://file//src/main.go:7", true},
+		{"unlimited", long, 0, "cursor://file/" + long + ":7", true},
+		{"shortened", long, 100, "cursor://file//src/main.go:7", true},
+		{"too long", "/src/" + strings.Repeat("x", 200) + ".go", 100, "cursor://file//src/" + strings.Repeat("x", 200) + ".go:7", false},
+		{"too long escaped", "/src/" + strings.Repeat(" ", 40) + ".go", 100, "cursor://file//src/" + strings.Repeat("%20", 40) + ".go:7", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			configure(t, func(s *Settings) {
+				s.LinkFormat = "cursor"
+				s.Environment = Environment{}
+				s.PathMap = ""
+				s.Workspace = ""
+				s.MaxURLLength = tt.max
+				s.Terminal = Terminals["generic"]
+			})
+			url := FormatURL(tt.file, 7)
+			if url != tt.want {
+				t.Errorf("FormatURL = %q, want %q", url, tt.want)
+			}
+			want := "text"
+			if tt.linked {
+				want = "\x1b]8;;" + url + "\x1b\\text" + osc8End
+			}
+			if got := FormatOSC8("text", url); got != want {
+				t.Errorf("FormatOSC8 = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package ps
+
+import (
+	"runtime"
+	"time"
+)
+
+// Watchdog returns a function canceling a watchdog: if it is not called
+// within d, a Bad line "label still running after 30s", linked to the call
+// site of Watchdog, is printed, followed by the stacks of all goroutines
+// with their source locations hyperlinked. Use it with defer so that a
+// hang diagnoses itself instead of timing out silently:
+//
+//	defer ps.Watchdog(30*time.Second, "migration")()
+func Watchdog(d time.Duration, label string) (cancel func()) {
+	return std.watchdog(1, d, label)
+}
+
+// Watchdog is like the package-level Watchdog.
+func (p *Printer) Watchdog(d time.Duration, label string) (cancel func()) {
+	return p.watchdog(1, d, label)
+}
+
+func (p *Printer) watchdog(skip int, d time.Duration, label string) func() {
+	site := p.callSite(skip + 1)
+	t := time.AfterFunc(d, func() {
+		if !p.prints(Bad) {
+			return
+		}
+		p.printAt(site, newEntry(Bad), "%s still running after %s\n", []interface{}{label, formatDuration(d)})
+		write(Linkify(allStacks()))
+	})
+	return func() { t.Stop() }
+}
+
+// allStacks returns the stacks of all goroutines, as printed by a panic.
+func allStacks() string {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return string(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
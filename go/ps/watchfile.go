@@ -0,0 +1,110 @@
+package ps
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// defaultWatchInterval is the default of Settings.WatchInterval.
+const defaultWatchInterval = 100 * time.Millisecond
+
+// fileState is what WatchFile knows about a file.
+type fileState struct {
+	exists bool
+	size   int64
+	mtime  time.Time
+	hash   uint64
+}
+
+// WatchFile prints a Written line, linked to the call site of WatchFile,
+// whenever the file at path is created, changed or removed, until the
+// returned function or Close is called. Changes are noticed by polling
+// the size and modification time every Settings.WatchInterval; the line
+// shows both and a hash of the contents:
+//
+//	defer ps.WatchFile("testdata/state.json")()
+func WatchFile(path string) (stop func()) {
+	return std.watchFile(1, path)
+}
+
+// WatchFile is like the package-level WatchFile.
+func (p *Printer) WatchFile(path string) (stop func()) {
+	return p.watchFile(1, path)
+}
+
+func (p *Printer) watchFile(skip int, path string) func() {
+	site := p.callSite(skip + 1)
+	last := statFile(path, fileState{})
+	interval := cfg().WatchInterval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			cur := statFile(path, last)
+			if cur == last {
+				continue
+			}
+			if p.prints(Written) {
+				p.printAt(site, newEntry(Written), "%s\n", []interface{}{describeChange(path, last, cur)})
+			}
+			last = cur
+		}
+	}()
+	var once sync.Once
+	return track(func() {
+		once.Do(func() { close(done) })
+		<-stopped
+	})
+}
+
+// statFile returns the state of the file at path. The contents are only
+// hashed if the size or modification time differ from those of last.
+func statFile(path string, last fileState) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	s := fileState{exists: true, size: info.Size(), mtime: info.ModTime()}
+	if last.exists && s.size == last.size && s.mtime.Equal(last.mtime) {
+		s.mtime = last.mtime
+		s.hash = last.hash
+		return s
+	}
+	if f, err := os.Open(path); err == nil {
+		h := fnv.New64a()
+		io.Copy(h, f)
+		f.Close()
+		s.hash = h.Sum64()
+	}
+	return s
+}
+
+// describeChange describes the change of the file at path from last to
+// cur.
+func describeChange(path string, last, cur fileState) string {
+	switch {
+	case !cur.exists:
+		return path + " removed"
+	case !last.exists:
+		return fmt.Sprintf("%s created: %dB, mtime %s, hash %016x", path, cur.size, cur.mtime.Format("15:04:05.000"), cur.hash)
+	case cur.hash == last.hash:
+		return fmt.Sprintf("%s touched: %dB, mtime %s, contents unchanged", path, cur.size, cur.mtime.Format("15:04:05.000"))
+	default:
+		return fmt.Sprintf("%s changed: %dB → %dB, mtime %s, hash %016x", path, last.size, cur.size, cur.mtime.Format("15:04:05.000"), cur.hash)
+	}
+}
@@ -0,0 +1,138 @@
+package ps
+
+import (
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/rivo/uniseg"
+)
+
+// Text is measured by the grapheme clusters that terminals draw as one
+// character, rather than rune by rune: a rune followed by the emoji
+// variation selector U+FE0F, as in the "⚙️" of Transition, is an emoji
+// two columns wide, although the rune alone is one, and so are a pair of
+// regional indicators forming a flag and a sequence of emoji joined by
+// U+200D. How wide terminals draw such emoji, and the characters whose
+// width Unicode leaves ambiguous, such as "…" and "→", varies, and is set
+// by Settings.EmojiWidth and Settings.AmbiguousWidth.
+
+// widthConditions measure runes with ambiguous characters one column
+// wide, at index 0, and two, at index 1.
+var widthConditions = func() (conds [2]*runewidth.Condition) {
+	for i := range conds {
+		conds[i] = runewidth.NewCondition()
+		conds[i].EastAsianWidth = i == 1
+	}
+	return conds
+}()
+
+// textWidth returns the number of columns text, without escape sequences,
+// takes up in the terminal.
+func textWidth(text string) int {
+	if width, ok := asciiWidth(text); ok {
+		return width
+	}
+	s := cfg()
+	width := 0
+	state := -1
+	for text != "" {
+		var cluster string
+		cluster, text, _, state = uniseg.FirstGraphemeClusterInString(text, state)
+		width += clusterWidth(cluster, s)
+	}
+	return width
+}
+
+// truncateText returns the longest start of text, without escape
+// sequences, that takes up at most width columns, cut between grapheme
+// clusters.
+func truncateText(text string, width int) string {
+	if _, ok := asciiWidth(text); ok {
+		// Each byte, other than a control character, takes up a column.
+		return truncateASCII(text, width)
+	}
+	s := cfg()
+	rest := text
+	state := -1
+	for rest != "" {
+		cluster, next, _, nextState := uniseg.FirstGraphemeClusterInString(rest, state)
+		w := clusterWidth(cluster, s)
+		if w > width {
+			break
+		}
+		width -= w
+		rest, state = next, nextState
+	}
+	return text[:len(text)-len(rest)]
+}
+
+// asciiWidth returns the width of text if it is ASCII.
+func asciiWidth(text string) (int, bool) {
+	width := 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		if c >= 0x80 {
+			return 0, false
+		}
+		if c >= 0x20 && c != 0x7f {
+			width++
+		}
+	}
+	return width, true
+}
+
+// truncateASCII is truncateText for ASCII text.
+func truncateASCII(text string, width int) string {
+	for i := 0; i < len(text); i++ {
+		if c := text[i]; c >= 0x20 && c != 0x7f {
+			if width == 0 {
+				return text[:i]
+			}
+			width--
+		}
+	}
+	return text
+}
+
+// clusterWidth returns the width of the grapheme cluster c under the
+// settings s.
+func clusterWidth(c string, s *Settings) int {
+	cond := widthConditions[0]
+	if s.AmbiguousWidth == 2 {
+		cond = widthConditions[1]
+	}
+	emoji := 2
+	if s.EmojiWidth == 1 {
+		emoji = 1
+	}
+	switch {
+	case strings.Contains(c, "\ufe0f"):
+		// Emoji presentation.
+		return emoji
+	case strings.Contains(c, "\ufe0e"):
+		// Text presentation.
+		return 1
+	case strings.Contains(c, "\u200d"), isFlag(c):
+		return emoji
+	}
+	for _, r := range c {
+		// Combining marks and other runes after the first draw over it.
+		if w := cond.RuneWidth(r); w > 0 {
+			return w
+		}
+	}
+	return 0
+}
+
+// isFlag reports whether the grapheme cluster c is a flag: a pair of
+// regional indicators.
+func isFlag(c string) bool {
+	n := 0
+	for _, r := range c {
+		if r < 0x1f1e6 || r > 0x1f1ff {
+			return false
+		}
+		n++
+	}
+	return n == 2
+}
@@ -0,0 +1,118 @@
+package ps
+
+import (
+	"fmt"
+	"testing"
+)
+
+// widthSettings are the combinations of AmbiguousWidth and EmojiWidth the
+// width tests run under, indexing the widths they want.
+var widthSettings = [4]struct{ ambiguous, emoji int }{{1, 2}, {2, 2}, {1, 1}, {2, 1}}
+
+func TestTextWidth(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		text string
+		want [4]int
+	}{
+		{"ascii", "abc", [4]int{3, 3, 3, 3}},
+		{"Sent", tagEmoji[Sent], [4]int{1, 1, 1, 1}},
+		{"Received", tagEmoji[Received], [4]int{1, 1, 1, 1}},
+		{"Written", tagEmoji[Written], [4]int{1, 1, 1, 1}},
+		{"Listening", tagEmoji[Listening], [4]int{2, 2, 2, 2}},
+		{"Transition", tagEmoji[Transition], [4]int{2, 2, 1, 1}},
+		{"Started", tagEmoji[Started], [4]int{2, 2, 2, 2}},
+		{"Success", tagEmoji[Success], [4]int{2, 2, 2, 2}},
+		{"Failure", tagEmoji[Failure], [4]int{2, 2, 2, 2}},
+		{"Retry", tagEmoji[Retry], [4]int{2, 2, 2, 2}},
+		{"Scheduled", tagEmoji[Scheduled], [4]int{2, 2, 2, 2}},
+		{"Good", tagEmoji[Good], [4]int{2, 2, 2, 2}},
+		{"Bad", tagEmoji[Bad], [4]int{2, 2, 2, 2}},
+		{"InProgress", tagEmoji[InProgress], [4]int{2, 2, 2, 2}},
+		{"gear", "⚙", [4]int{1, 1, 1, 1}},
+		{"gear VS16", "⚙️", [4]int{2, 2, 1, 1}},
+		{"gear VS15", "⚙︎", [4]int{1, 1, 1, 1}},
+		{"flag", "🇺🇸", [4]int{2, 2, 1, 1}},
+		{"flags", "🇯🇵🇫🇷", [4]int{4, 4, 2, 2}},
+		{"lone regional indicator", "🇺", [4]int{1, 1, 1, 1}},
+		{"ZWJ", "👩‍💻", [4]int{2, 2, 1, 1}},
+		{"ZWJ family", "👨‍👩‍👧", [4]int{2, 2, 1, 1}},
+		{"ZWJ with VS16", "🏳️‍🌈", [4]int{2, 2, 1, 1}},
+		{"ellipsis", "…", [4]int{1, 2, 1, 2}},
+		{"arrow", "→", [4]int{1, 2, 1, 2}},
+		{"combining", "é", [4]int{1, 1, 1, 1}},
+		{"CJK", "中文", [4]int{4, 4, 4, 4}},
+		{"mixed", "a ⚙️ b…", [4]int{7, 8, 6, 7}},
+	} {
+		for i, s := range widthSettings {
+			t.Run(fmt.Sprintf("%s/ambiguous=%d,emoji=%d", tt.name, s.ambiguous, s.emoji), func(t *testing.T) {
+				configure(t, func(c *Settings) {
+					c.AmbiguousWidth = s.ambiguous
+					c.EmojiWidth = s.emoji
+				})
+				if got := textWidth(tt.text); got != tt.want[i] {
+					t.Errorf("textWidth(%q) = %d, want %d", tt.text, got, tt.want[i])
+				}
+			})
+		}
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	for _, tt := range []struct {
+		text  string
+		width int
+		want  [4]string
+	}{
+		{"abcdef", 3, [4]string{"abc", "abc", "abc", "abc"}},
+		{"⚙️x", 1, [4]string{"", "", "⚙️", "⚙️"}},
+		{"⚙️x", 2, [4]string{"⚙️", "⚙️", "⚙️x", "⚙️x"}},
+		{"a🇺🇸b", 2, [4]string{"a", "a", "a🇺🇸", "a🇺🇸"}},
+		{"a🇺🇸b", 3, [4]string{"a🇺🇸", "a🇺🇸", "a🇺🇸b", "a🇺🇸b"}},
+		{"a👩‍💻b", 2, [4]string{"a", "a", "a👩‍💻", "a👩‍💻"}},
+		{"a👨‍👩‍👧b", 3, [4]string{"a👨‍👩‍👧", "a👨‍👩‍👧", "a👨‍👩‍👧b", "a👨‍👩‍👧b"}},
+		{"…→x", 2, [4]string{"…→", "…", "…→", "…"}},
+		{"✅🚀", 3, [4]string{"✅", "✅", "✅", "✅"}},
+		{"éx", 1, [4]string{"é", "é", "é", "é"}},
+	} {
+		for i, s := range widthSettings {
+			t.Run(fmt.Sprintf("%s/%d/ambiguous=%d,emoji=%d", tt.text, tt.width, s.ambiguous, s.emoji), func(t *testing.T) {
+				configure(t, func(c *Settings) {
+					c.AmbiguousWidth = s.ambiguous
+					c.EmojiWidth = s.emoji
+				})
+				if got := truncateText(tt.text, tt.width); got != tt.want[i] {
+					t.Errorf("truncateText(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want[i])
+				}
+			})
+		}
+	}
+}
+
+// TestTagColumnAlignment checks that with TagColumn set, the messages of
+// lines with every tag, or none, start at the same column.
+func TestTagColumnAlignment(t *testing.T) {
+	tags := []Tag{""}
+	for tag := range tagEmoji {
+		tags = append(tags, tag)
+	}
+	for _, s := range widthSettings {
+		t.Run(fmt.Sprintf("ambiguous=%d,emoji=%d", s.ambiguous, s.emoji), func(t *testing.T) {
+			configure(t, func(c *Settings) {
+				c.AmbiguousWidth = s.ambiguous
+				c.EmojiWidth = s.emoji
+				c.TagColumn = 2
+			})
+			want := -1
+			for _, tag := range tags {
+				got := textWidth(stripEscapes(linePrefix(Entry{Tag: tag})))
+				if want < 0 {
+					want = got
+				}
+				if got != want {
+					t.Errorf("prefix of %q lines is %d columns wide, want %d", tag, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+// Package pscast records the output of the ps package as an asciinema v2
+// cast file, keeping the OSC8 hyperlinks and the timing of each write, so
+// that a debugging session can be replayed and shared with asciinema
+// players, including those that support links:
+//
+//	rec, err := pscast.Record("session.cast")
+//	if err != nil {
+//		return err
+//	}
+//	defer rec.Close()
+//
+// Output is still written to where it was going, usually the terminal.
+package pscast
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+// Option configures a Recorder.
+type Option func(*Recorder)
+
+// Size sets the terminal size in the header of the cast, which players
+// size their screen by. The default is 80 columns, or HYPERLINKED_COLUMNS
+// if set, by 24 rows.
+func Size(width, height int) Option {
+	return func(r *Recorder) { r.width, r.height = width, height }
+}
+
+// Title sets the title in the header of the cast.
+func Title(title string) Option {
+	return func(r *Recorder) { r.title = title }
+}
+
+// Tee writes the output recorded to w too.
+func Tee(w io.Writer) Option {
+	return func(r *Recorder) { r.out = w }
+}
+
+// Recorder is an io.Writer recording what is written to it as the output
+// events of a cast.
+type Recorder struct {
+	width, height int
+	title         string
+	out           io.Writer
+
+	mu     sync.Mutex
+	w      io.Writer
+	start  time.Time
+	err    error
+	closed bool
+	// prev is the output replaced by Record, restored by Close.
+	prev io.Writer
+}
+
+// New returns a recorder writing a cast to w, starting with its header.
+// Set it as the output of the ps package with ps.SetOutput, or use Record.
+// If w is an io.Closer, Close closes it.
+func New(w io.Writer, opts ...Option) *Recorder {
+	r := &Recorder{w: w, width: 80, height: 24, start: time.Now()}
+	if n, err := strconv.Atoi(os.Getenv("HYPERLINKED_COLUMNS")); err == nil && n > 0 {
+		r.width = n
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.writeHeader()
+	return r
+}
+
+// Record creates the cast file at path and records the output of the ps
+// package to it, teeing it to the output set before, until Close is
+// called. Close is also registered with ps.OnExit.
+func Record(path string, opts ...Option) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	prev := ps.Output()
+	r := New(f, append([]Option{Tee(prev)}, opts...)...)
+	r.prev = prev
+	ps.SetOutput(r)
+	ps.OnExit(func() { r.Close() })
+	return r, nil
+}
+
+// header is the first line of a cast file.
+type header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+func (r *Recorder) writeHeader() {
+	h := header{Version: 2, Width: r.width, Height: r.height, Timestamp: r.start.Unix(), Title: r.title}
+	if term := os.Getenv("TERM"); term != "" {
+		h.Env = map[string]string{"TERM": term}
+	}
+	b, err := json.Marshal(h)
+	if err != nil {
+		r.err = err
+		return
+	}
+	_, r.err = r.w.Write(append(b, '\n'))
+}
+
+// Write implements io.Writer, recording b as an output event at the time
+// since the recorder was created, and writing it to the writer set by Tee.
+// Newlines are recorded as CRLF, as a terminal would receive them.
+func (r *Recorder) Write(b []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.out != nil {
+		r.out.Write(b)
+	}
+	if r.err != nil || r.closed || len(b) == 0 {
+		return len(b), r.err
+	}
+	data := strings.ReplaceAll(strings.ReplaceAll(string(b), "\r\n", "\n"), "\n", "\r\n")
+	text, err := json.Marshal(data)
+	if err != nil {
+		r.err = err
+		return len(b), err
+	}
+	secs := time.Since(r.start).Seconds()
+	event := make([]byte, 0, len(text)+24)
+	event = append(event, '[')
+	event = strconv.AppendFloat(event, secs, 'f', 6, 64)
+	event = append(event, `, "o", `...)
+	event = append(event, text...)
+	event = append(event, "]\n"...)
+	if _, err := r.w.Write(event); err != nil {
+		r.err = err
+	}
+	return len(b), r.err
+}
+
+// Err returns the first error writing the cast, if any.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// Close stops recording, restoring the output replaced by Record, and
+// closes the writer of the cast if it is an io.Closer. It returns the
+// first error writing the cast, if any.
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return r.err
+	}
+	r.closed = true
+	prev := r.prev
+	r.mu.Unlock()
+
+	if prev != nil {
+		ps.SetOutput(prev)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.w.(io.Closer); ok {
+		if err := c.Close(); err != nil && r.err == nil {
+			r.err = err
+		}
+	}
+	return r.err
+}
@@ -0,0 +1,99 @@
+package pscast
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+// event is an event line of a cast.
+type event struct {
+	secs float64
+	kind string
+	data string
+}
+
+// readCast parses a cast into its header and events.
+func readCast(t *testing.T, b []byte) (header, []event) {
+	t.Helper()
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	var h header
+	if !sc.Scan() {
+		t.Fatal("no header")
+	}
+	if err := json.Unmarshal(sc.Bytes(), &h); err != nil {
+		t.Fatalf("header %q: %v", sc.Bytes(), err)
+	}
+	var events []event
+	for sc.Scan() {
+		var raw []interface{}
+		if err := json.Unmarshal(sc.Bytes(), &raw); err != nil || len(raw) != 3 {
+			t.Fatalf("event %q: %v", sc.Bytes(), err)
+		}
+		secs, _ := raw[0].(float64)
+		kind, _ := raw[1].(string)
+		data, _ := raw[2].(string)
+		events = append(events, event{secs, kind, data})
+	}
+	return h, events
+}
+
+func TestRecorder(t *testing.T) {
+	var cast, tee bytes.Buffer
+	r := New(&cast, Size(100, 30), Title("session"), Tee(&tee))
+	r.Write([]byte("first\nsecond\r\n"))
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r.Write([]byte("after close\n"))
+
+	h, events := readCast(t, cast.Bytes())
+	if h.Version != 2 || h.Width != 100 || h.Height != 30 || h.Title != "session" {
+		t.Errorf("header %+v, want version 2, 100x30 and the title", h)
+	}
+	if len(events) != 1 {
+		t.Fatalf("recorded %d events, want 1: %+v", len(events), events)
+	}
+	if e := events[0]; e.kind != "o" || e.data != "first\r\nsecond\r\n" || e.secs < 0 {
+		t.Errorf("event %+v, want the output with CRLF line endings", e)
+	}
+	if got, want := tee.String(), "first\nsecond\r\nafter close\n"; got != want {
+		t.Errorf("teed %q, want %q, as written", got, want)
+	}
+}
+
+func TestRecord(t *testing.T) {
+	var out bytes.Buffer
+	ps.SetOutput(&out)
+	t.Cleanup(func() { ps.SetOutput(nil) })
+	path := filepath.Join(t.TempDir(), "session.cast")
+	r, err := Record(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ps.F("hello\n")
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if ps.Output() != &out {
+		t.Error("Close did not restore the output")
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, events := readCast(t, b)
+	if len(events) != 1 || !strings.Contains(events[0].data, "hello\r\n") {
+		t.Errorf("recorded %+v, want the line printed", events)
+	}
+	if !strings.Contains(out.String(), "hello\n") {
+		t.Errorf("wrote %q to the output, want the line printed", out.String())
+	}
+}
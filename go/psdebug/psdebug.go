@@ -0,0 +1,106 @@
+// Package psdebug serves an HTTP endpoint for changing the ps package's
+// configuration while a program is running.
+//
+// Importing it registers the handler at /debug/ps/config on
+// http.DefaultServeMux, in the manner of net/http/pprof:
+//
+//	import _ "github.com/dandavison/hyperlinked/go/psdebug"
+//
+//	go http.ListenAndServe("localhost:6060", nil)
+//
+// GET returns the current configuration; POST changes the settings given
+// as form values and returns the result:
+//
+//	curl localhost:6060/debug/ps/config -d 'level=debug&filter=pkg/worker'
+//
+// Settings are:
+//
+//	level   minimum level (debug, info, warn, error)
+//	filter  call-site filter, as for ps.SetFilter
+//	sample  sampling rate, as for ps.SetSampleRate
+//	format  link format, as for ps.SetLinkFormat
+package psdebug
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+func init() {
+	http.Handle("/debug/ps/config", Handler())
+}
+
+// Handler returns the configuration handler, for registering on a mux
+// other than http.DefaultServeMux.
+func Handler() http.Handler {
+	return http.HandlerFunc(serveConfig)
+}
+
+func serveConfig(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := apply(r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "level=%s\n", ps.MinLevel())
+	fmt.Fprintf(w, "filter=%s\n", ps.Filter())
+	fmt.Fprintf(w, "sample=%g\n", ps.SampleRate())
+	fmt.Fprintf(w, "format=%s\n", ps.CurrentSettings().LinkFormat)
+}
+
+// apply changes the settings present in r's form. If any setting is
+// invalid, none is changed.
+func apply(r *http.Request) error {
+	level, setLevel := r.PostForm["level"]
+	var l ps.Level
+	if setLevel {
+		var err error
+		if l, err = ps.ParseLevel(level[0]); err != nil {
+			return err
+		}
+	}
+	sample, setSample := r.PostForm["sample"]
+	var rate float64
+	if setSample {
+		var err error
+		if rate, err = strconv.ParseFloat(sample[0], 64); err != nil || rate <= 0 || rate > 1 {
+			return fmt.Errorf("invalid sampling rate %q: want 0 < rate <= 1", sample[0])
+		}
+	}
+	format, setFormat := r.PostForm["format"]
+	if setFormat {
+		if _, err := ps.ParseLinkFormat(format[0]); err != nil {
+			return err
+		}
+	}
+	if filter, ok := r.PostForm["filter"]; ok {
+		if err := ps.SetFilter(filter[0]); err != nil {
+			return err
+		}
+	}
+	if setLevel {
+		ps.SetLevel(l)
+	}
+	if setSample {
+		ps.SetSampleRate(rate)
+	}
+	if setFormat {
+		ps.SetLinkFormat(format[0])
+	}
+	return nil
+}
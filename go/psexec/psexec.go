@@ -0,0 +1,147 @@
+// Package psexec runs commands like os/exec, printing their output through
+// the ps package, each line prefixed with the command name and linked to
+// the code that started the command:
+//
+//	err := psexec.Command("go", "build", "./...").Run()
+//
+// prints:
+//
+//	🚀 go build ./...
+//	[go] main.go:3:2: undefined: foo
+//	❌ go: exit status 1 after 1.2s
+//
+// Lines written to stderr are printed at ps.LevelWarn.
+package psexec
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+// Cmd is an exec.Cmd whose output is printed. Stdout and Stderr may still
+// be set, in which case the output is written there as well.
+type Cmd struct {
+	*exec.Cmd
+	name   string
+	start  time.Time
+	p      *ps.Printer
+	stdout *lineWriter
+	stderr *lineWriter
+}
+
+// Command returns a Cmd running name with args, as exec.Command does.
+func Command(name string, args ...string) *Cmd {
+	return &Cmd{Cmd: exec.Command(name, args...), name: filepath.Base(name)}
+}
+
+// CommandContext is like Command, with a context, as exec.CommandContext.
+func CommandContext(ctx context.Context, name string, args ...string) *Cmd {
+	return &Cmd{Cmd: exec.CommandContext(ctx, name, args...), name: filepath.Base(name)}
+}
+
+// Start starts the command, printing a Started line with the command line.
+func (c *Cmd) Start() error {
+	c.p = ps.WithSkip(ps.Auto).Here()
+	prefix := "[" + c.name + "] "
+	c.stdout = &lineWriter{p: c.p, prefix: prefix}
+	c.stderr = &lineWriter{p: c.p.At(ps.LevelWarn), prefix: prefix}
+	c.Stdout = tee(c.Stdout, c.stdout)
+	c.Stderr = tee(c.Stderr, c.stderr)
+
+	c.p.T(ps.Started, "%s", strings.Join(c.Args, " "))
+	c.start = time.Now()
+	if err := c.Cmd.Start(); err != nil {
+		c.p.T(ps.Failure, "%s: %v", c.name, err)
+		return err
+	}
+	return nil
+}
+
+// Wait waits for the command to exit, as exec.Cmd.Wait does, printing any
+// unterminated last line of output and then a Success or Failure line with
+// the exit status and the time the command ran.
+func (c *Cmd) Wait() error {
+	err := c.Cmd.Wait()
+	c.stdout.flush()
+	c.stderr.flush()
+	took := time.Since(c.start).Round(time.Millisecond)
+	if err != nil {
+		c.p.T(ps.Failure, "%s: %v after %s", c.name, err, took)
+	} else {
+		c.p.T(ps.Success, "%s exited 0 after %s", c.name, took)
+	}
+	return err
+}
+
+// Run starts the command and waits for it to exit.
+func (c *Cmd) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+// Output runs the command and returns its standard output, which is also
+// printed.
+func (c *Cmd) Output() ([]byte, error) {
+	var b bytes.Buffer
+	c.Stdout = &b
+	err := c.Run()
+	return b.Bytes(), err
+}
+
+// tee returns a writer writing to both w, which may be nil, and lw.
+func tee(w io.Writer, lw *lineWriter) io.Writer {
+	if w == nil {
+		return lw
+	}
+	return io.MultiWriter(w, lw)
+}
+
+// lineWriter prints the lines written to it, each prefixed with prefix.
+type lineWriter struct {
+	p      *ps.Printer
+	prefix string
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// Write implements io.Writer, printing the complete lines in b and
+// keeping the rest until the next write or flush.
+func (w *lineWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, b...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.p.F("%s%s\n", w.prefix, w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	return len(b), nil
+}
+
+// flush prints the unterminated line kept by Write, if any. w may be nil,
+// if the command was not started.
+func (w *lineWriter) flush() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.p.F("%s%s\n", w.prefix, w.buf)
+		w.buf = nil
+	}
+}
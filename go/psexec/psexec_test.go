@@ -0,0 +1,61 @@
+package psexec
+
+import (
+	"io"
+	"os/exec"
+	"testing"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+	"github.com/dandavison/hyperlinked/go/pstest"
+)
+
+func TestRun(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+	ps.SetOutput(io.Discard)
+	t.Cleanup(func() { ps.SetOutput(nil) })
+	c := pstest.Capture(t)
+
+	script := "echo out; echo err >&2; printf partial"
+	if err := Command("sh", "-c", script).Run(); err != nil {
+		t.Fatal(err)
+	}
+	levels := map[string]ps.Level{}
+	var tags []ps.Tag
+	for _, e := range c.Entries() {
+		levels[e.Msg] = e.Level
+		tags = append(tags, e.Tag)
+	}
+	for msg, want := range map[string]ps.Level{
+		"[sh] out":     ps.LevelInfo,
+		"[sh] err":     ps.LevelWarn,
+		"[sh] partial": ps.LevelInfo,
+	} {
+		if got, ok := levels[msg]; !ok {
+			t.Errorf("%q not printed; printed %q", msg, levels)
+		} else if got != want {
+			t.Errorf("%q printed at %v, want %v", msg, got, want)
+		}
+	}
+	if len(tags) == 0 || tags[0] != ps.Started || tags[len(tags)-1] != ps.Success {
+		t.Errorf("tags %q, want Started first and Success last", tags)
+	}
+}
+
+func TestRunFailure(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not found")
+	}
+	ps.SetOutput(io.Discard)
+	t.Cleanup(func() { ps.SetOutput(nil) })
+	c := pstest.Capture(t)
+
+	if err := Command("sh", "-c", "exit 3").Run(); err == nil {
+		t.Fatal("exit 3 succeeded")
+	}
+	c.ExpectSequence(
+		pstest.Tagged(ps.Started).Containing("sh -c exit 3"),
+		pstest.Tagged(ps.Failure).Containing("exit status 3"),
+	)
+}
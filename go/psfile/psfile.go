@@ -0,0 +1,206 @@
+// Package psfile provides a ps sink that writes entries to a file, rotating
+// it when it grows too large.
+//
+//	ps.AddSink(psfile.New("debug.log", psfile.MaxSize(10<<20), psfile.Compress()))
+//
+// Rotated files are named path.1, path.2, ... (path.1.gz, ... when
+// compressed), with path.1 the most recent.
+package psfile
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+// Format selects how entries are stored.
+type Format int
+
+const (
+	// Raw stores entries as they are printed to the terminal, including
+	// the OSC8 hyperlinks, so that the file can be viewed with cat or less -R,
+	// but not truncated to the width of the terminal.
+	Raw Format = iota
+	// JSONL stores one JSON-encoded entry per line.
+	JSONL
+)
+
+// Option configures a Sink.
+type Option func(*Sink)
+
+// MaxSize sets the size in bytes at which the file is rotated.
+// A size of 0 disables rotation. The default is 10 MiB.
+func MaxSize(bytes int64) Option {
+	return func(s *Sink) { s.maxSize = bytes }
+}
+
+// MaxFiles sets the number of rotated files to keep. The default is 5.
+func MaxFiles(n int) Option {
+	return func(s *Sink) { s.maxFiles = n }
+}
+
+// Compress gzips rotated files.
+func Compress() Option {
+	return func(s *Sink) { s.compress = true }
+}
+
+// WithFormat sets the format in which entries are stored. The default is Raw.
+func WithFormat(f Format) Option {
+	return func(s *Sink) { s.format = f }
+}
+
+// Sink is a ps.Sink writing to a rotating file.
+type Sink struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+	compress bool
+	format   Format
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// New returns a sink appending to the file at path. The file is opened on
+// the first write.
+func New(path string, opts ...Option) *Sink {
+	s := &Sink{
+		path:     path,
+		maxSize:  10 << 20,
+		maxFiles: 5,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// WriteEntry implements ps.Sink.
+func (s *Sink) WriteEntry(e ps.Entry) error {
+	var line []byte
+	switch s.format {
+	case JSONL:
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		line = append(b, '\n')
+	default:
+		line = []byte(ps.Render(e, 0))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file != nil && s.maxSize > 0 && s.size+int64(len(line)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	if s.file == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+	return err
+}
+
+// Close closes the file. A later write reopens it.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+func (s *Sink) open() error {
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.file = f
+	s.size = info.Size()
+	return nil
+}
+
+// rotate closes the current file and shifts it and the older files up by
+// one, dropping those beyond maxFiles.
+func (s *Sink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	s.file = nil
+
+	if s.maxFiles <= 0 {
+		return os.Remove(s.path)
+	}
+	os.Remove(s.rotated(s.maxFiles, false))
+	os.Remove(s.rotated(s.maxFiles, true))
+	for i := s.maxFiles - 1; i >= 1; i-- {
+		for _, gz := range []bool{false, true} {
+			if err := os.Rename(s.rotated(i, gz), s.rotated(i+1, gz)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	if err := os.Rename(s.path, s.rotated(1, false)); err != nil {
+		return err
+	}
+	if s.compress {
+		return gzipFile(s.rotated(1, false), s.rotated(1, true))
+	}
+	return nil
+}
+
+// rotated returns the name of the i'th rotated file.
+func (s *Sink) rotated(i int, gz bool) string {
+	name := fmt.Sprintf("%s.%d", s.path, i)
+	if gz {
+		name += ".gz"
+	}
+	return name
+}
+
+// gzipFile compresses src to dst and removes src.
+func gzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
@@ -0,0 +1,102 @@
+package psfile
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+// entry returns an entry with message msg.
+func entry(msg string) ps.Entry {
+	return ps.Entry{Time: time.Now(), Msg: msg, File: "main.go", Line: 1}
+}
+
+// readFile returns the content of the file at path, gunzipped if its name
+// ends in ".gz".
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = zr
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestRawNotTruncated(t *testing.T) {
+	ps.SetWidthFunc(func() int { return 20 })
+	t.Cleanup(func() { ps.SetWidthFunc(nil) })
+	path := filepath.Join(t.TempDir(), "debug.log")
+	s := New(path)
+	defer s.Close()
+
+	msg := strings.Repeat("0123456789", 5)
+	if err := s.WriteEntry(entry(msg)); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, path); !strings.Contains(got, msg+"\n") {
+		t.Errorf("wrote %q, want the whole message %q", got, msg)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		name := "plain"
+		var opts []Option
+		if compress {
+			name = "compressed"
+			opts = append(opts, Compress())
+		}
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "debug.log")
+			// Each line is longer than MaxSize, so that each file holds one.
+			s := New(path, append(opts, WithFormat(JSONL), MaxSize(80), MaxFiles(2))...)
+			defer s.Close()
+			for _, msg := range []string{"first", "second", "third", "fourth"} {
+				if err := s.WriteEntry(entry(strings.Repeat(msg, 4))); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			rotated := func(i int) string {
+				name := s.rotated(i, compress)
+				if _, err := os.Stat(s.rotated(i, !compress)); err == nil {
+					t.Errorf("%s exists alongside %s", s.rotated(i, !compress), name)
+				}
+				return name
+			}
+			for i, want := range []string{"fourth", "third", "second"} {
+				file := path
+				if i > 0 {
+					file = rotated(i)
+				}
+				if got := readFile(t, file); strings.Count(got, "\n") != 1 || !strings.Contains(got, want) {
+					t.Errorf("%s holds %q, want the %s entry only", file, got, want)
+				}
+			}
+			for _, gz := range []bool{false, true} {
+				if _, err := os.Stat(s.rotated(3, gz)); err == nil {
+					t.Errorf("%s kept beyond MaxFiles(2)", s.rotated(3, gz))
+				}
+			}
+		})
+	}
+}
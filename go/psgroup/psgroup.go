@@ -0,0 +1,146 @@
+// Package psgroup provides a Group like that of golang.org/x/sync/errgroup
+// that prints each task's lifecycle through the ps package, linked to the
+// call of Go that started it:
+//
+//	g, ctx := psgroup.WithContext(ctx)
+//	for _, url := range urls {
+//		g.Go(func() error { return fetch(ctx, url) })
+//	}
+//	err := g.Wait()
+//
+// prints:
+//
+//	🚀 task 1
+//	🚀 task 2
+//	✅ task 2 done after 120ms
+//	❌ task 1 failed after 340ms: 404 Not Found
+//	❌ group failed: 404 Not Found (task 1)
+//
+// where the last line also links to the call of Go that started the task
+// returning the first error.
+package psgroup
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+// Group is a collection of goroutines working on subtasks of a common
+// task, as errgroup.Group is. A zero Group is valid, has no limit on the
+// number of active goroutines, and does not cancel on error.
+type Group struct {
+	cancel func(error)
+	wg     sync.WaitGroup
+	sem    chan struct{}
+	tasks  atomic.Int64
+
+	errOnce sync.Once
+	err     error
+	// failed prints the line reporting err, linked to the call of Go that
+	// started the task returning it.
+	failed *ps.Printer
+	task   int64
+}
+
+// WithContext returns a new Group and a context derived from ctx, which
+// is canceled the first time a function passed to Go returns an error or
+// Wait returns, whichever occurs first.
+func WithContext(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Group{cancel: cancel}, ctx
+}
+
+// Go calls f in a new goroutine, printing a Started line, and then a
+// Success line or a Failure line with the error when f returns, with the
+// time it took, all linked to the call of Go. It blocks until the new
+// goroutine can be added without exceeding the limit set by SetLimit.
+// The first call to return an error cancels the group's context, if it
+// was created by WithContext, and its error is returned by Wait.
+func (g *Group) Go(f func() error) {
+	p := ps.WithSkip(ps.Auto).Here()
+	if g.sem != nil {
+		g.sem <- struct{}{}
+	}
+	g.start(p, f)
+}
+
+// TryGo calls f in a new goroutine as Go does, only if the number of
+// active goroutines is below the limit set by SetLimit, reporting whether
+// it did.
+func (g *Group) TryGo(f func() error) bool {
+	p := ps.WithSkip(ps.Auto).Here()
+	if g.sem != nil {
+		select {
+		case g.sem <- struct{}{}:
+		default:
+			return false
+		}
+	}
+	g.start(p, f)
+	return true
+}
+
+// start runs f in a new goroutine, printing its lifecycle with p.
+func (g *Group) start(p *ps.Printer, f func() error) {
+	n := g.tasks.Add(1)
+	g.wg.Add(1)
+	p.T(ps.Started, "task %d", n)
+	go func() {
+		defer g.done()
+		start := time.Now()
+		err := f()
+		took := time.Since(start).Round(time.Millisecond)
+		if err == nil {
+			p.T(ps.Success, "task %d done after %s", n, took)
+			return
+		}
+		p.T(ps.Failure, "task %d failed after %s: %v", n, took, err)
+		g.errOnce.Do(func() {
+			g.err, g.failed, g.task = err, p, n
+			if g.cancel != nil {
+				g.cancel(err)
+			}
+		})
+	}()
+}
+
+// done releases the slot of a goroutine that has returned.
+func (g *Group) done() {
+	if g.sem != nil {
+		<-g.sem
+	}
+	g.wg.Done()
+}
+
+// Wait blocks until all function calls from the Go method have returned,
+// then returns the first non-nil error, if any, printing it in a Failure
+// line linked to the call of Go that started the task returning it.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	if g.cancel != nil {
+		g.cancel(g.err)
+	}
+	if g.err != nil {
+		g.failed.T(ps.Failure, "group failed: %v (task %d)", g.err, g.task)
+	}
+	return g.err
+}
+
+// SetLimit limits the number of active goroutines in the group to at most
+// n, as errgroup.Group.SetLimit does. A negative n means no limit. The
+// limit must not be changed while goroutines are active.
+func (g *Group) SetLimit(n int) {
+	if n < 0 {
+		g.sem = nil
+		return
+	}
+	if len(g.sem) != 0 {
+		panic(fmt.Errorf("psgroup: modify limit while %v goroutines in the group are still active", len(g.sem)))
+	}
+	g.sem = make(chan struct{}, n)
+}
@@ -0,0 +1,64 @@
+package psgroup
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+	"github.com/dandavison/hyperlinked/go/pstest"
+)
+
+func TestWait(t *testing.T) {
+	ps.SetOutput(io.Discard)
+	t.Cleanup(func() { ps.SetOutput(nil) })
+	c := pstest.Capture(t)
+
+	boom := errors.New("boom")
+	g, ctx := WithContext(context.Background())
+	g.Go(func() error { return boom })
+	g.Go(func() error {
+		<-ctx.Done()
+		return nil
+	})
+	if err := g.Wait(); err != boom {
+		t.Errorf("Wait() = %v, want %v", err, boom)
+	}
+	if err := context.Cause(ctx); err != boom {
+		t.Errorf("context canceled by %v, want %v", err, boom)
+	}
+	c.ExpectSequence(
+		pstest.Tagged(ps.Started).Containing("task 1"),
+		pstest.Tagged(ps.Started).Containing("task 2"),
+		pstest.Tagged(ps.Success).Containing("task 2 done"),
+		pstest.Tagged(ps.Failure).Containing("group failed: boom (task 1)"),
+	)
+	c.ExpectSequence(pstest.Tagged(ps.Failure).Containing("task 1 failed after"))
+}
+
+func TestTryGo(t *testing.T) {
+	ps.SetOutput(io.Discard)
+	t.Cleanup(func() { ps.SetOutput(nil) })
+
+	var g Group
+	g.SetLimit(1)
+	release := make(chan struct{})
+	g.Go(func() error {
+		<-release
+		return nil
+	})
+	if g.TryGo(func() error { return nil }) {
+		t.Error("TryGo started a task beyond the limit")
+	}
+	close(release)
+	if err := g.Wait(); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	if !g.TryGo(func() error { return nil }) {
+		t.Error("TryGo did not start a task within the limit")
+	}
+	if err := g.Wait(); err != nil {
+		t.Errorf("Wait() = %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+// Package pshttp provides an http.RoundTripper that prints the requests an
+// HTTP client makes and the responses it receives, each line linked to the
+// code that made the request:
+//
+//	client := &http.Client{Transport: pshttp.Transport(nil, pshttp.Headers())}
+//
+// prints, for each request:
+//
+//	⤴ GET https://example.com/api/orders
+//	⬅ 200 OK GET https://example.com/api/orders 34ms 1234B
+//
+// Frames of net/http are skipped when looking up the caller, so the lines
+// link to the call of Client.Do, Client.Get and so on.
+//
+// In tests, Record serves a request with a handler, printing both sides,
+// and checks the response.
+package pshttp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+func init() {
+	ps.HelperPackage("net/http")
+}
+
+// Option configures a transport.
+type Option func(*transport)
+
+// Headers prints the request and response headers.
+func Headers() Option {
+	return func(t *transport) { t.headers = true }
+}
+
+// Bodies prints up to limit bytes of the request and response bodies.
+func Bodies(limit int) Option {
+	return func(t *transport) { t.bodyLimit = limit }
+}
+
+// Redact replaces the values of the named headers with "[redacted]" when
+// printing headers. Authorization, Proxy-Authorization, Cookie and
+// Set-Cookie are always redacted.
+func Redact(names ...string) Option {
+	return func(t *transport) {
+		for _, name := range names {
+			t.redact[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+}
+
+// transport is the http.RoundTripper returned by Transport.
+type transport struct {
+	base      http.RoundTripper
+	headers   bool
+	bodyLimit int
+	redact    map[string]bool
+	p         *ps.Printer
+}
+
+// Transport returns an http.RoundTripper that sends requests with base,
+// printing a Sent line for each request and a Received line for each
+// response, or a Failure line if there is none. A nil base uses
+// http.DefaultTransport.
+func Transport(base http.RoundTripper, opts ...Option) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &transport{
+		base: base,
+		redact: map[string]bool{
+			"Authorization":       true,
+			"Proxy-Authorization": true,
+			"Cookie":              true,
+			"Set-Cookie":          true,
+		},
+		p: ps.WithSkip(ps.Auto),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	target := req.Method + " " + req.URL.Redacted()
+	msg := target
+	if t.headers {
+		msg += formatHeaders(req.Header, t.redact)
+	}
+	if t.bodyLimit > 0 && req.Body != nil && req.Body != http.NoBody {
+		body, rest := peek(req.Body, t.bodyLimit)
+		req = req.Clone(req.Context())
+		req.Body = rest
+		msg += formatBody(body, t.bodyLimit)
+	}
+	t.p.T(ps.Sent, "%s", msg)
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	took := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		t.p.T(ps.Failure, "%s: %v %s", target, err, took)
+		return nil, err
+	}
+
+	msg = fmt.Sprintf("%s %s %s", resp.Status, target, took)
+	if resp.ContentLength >= 0 {
+		msg += fmt.Sprintf(" %dB", resp.ContentLength)
+	}
+	if t.headers {
+		msg += formatHeaders(resp.Header, t.redact)
+	}
+	if t.bodyLimit > 0 && resp.Body != nil && resp.Body != http.NoBody {
+		var body []byte
+		body, resp.Body = peek(resp.Body, t.bodyLimit)
+		msg += formatBody(body, t.bodyLimit)
+	}
+	t.p.T(ps.Received, "%s", msg)
+	return resp, nil
+}
+
+// formatHeaders renders h as "\nName: value" lines sorted by name, with
+// the values of the headers in redact replaced.
+func formatHeaders(h http.Header, redact map[string]bool) string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		for _, v := range h[name] {
+			if redact[name] {
+				v = "[redacted]"
+			}
+			fmt.Fprintf(&b, "\n%s: %s", name, v)
+		}
+	}
+	return b.String()
+}
+
+// formatBody renders up to limit bytes of body after a blank line, noting
+// if it was cut.
+func formatBody(body []byte, limit int) string {
+	if len(body) == 0 {
+		return ""
+	}
+	cut := len(body) > limit
+	if cut {
+		body = body[:limit]
+	}
+	text := "\n\n" + strings.TrimRight(string(body), "\n")
+	if cut {
+		text += "\n…"
+	}
+	return text
+}
+
+// peek reads up to limit+1 bytes from body, so that formatBody can tell
+// whether there are more than limit, and returns them together with a body
+// that reads them again followed by the rest.
+func peek(body io.ReadCloser, limit int) ([]byte, io.ReadCloser) {
+	b, _ := io.ReadAll(io.LimitReader(body, int64(limit)+1))
+	return b, readCloser{io.MultiReader(bytes.NewReader(b), body), body}
+}
+
+// readCloser combines a reader with the closer of the body it reads.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,89 @@
+package pshttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+	"github.com/dandavison/hyperlinked/go/pstest"
+)
+
+// echo is a handler responding with the body of the request.
+var echo = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Set-Cookie", "session=secret")
+	io.Copy(w, r.Body)
+})
+
+func TestTransport(t *testing.T) {
+	ps.SetOutput(io.Discard)
+	t.Cleanup(func() { ps.SetOutput(nil) })
+	c := pstest.Capture(t)
+	srv := httptest.NewServer(echo)
+	defer srv.Close()
+
+	client := &http.Client{Transport: Transport(nil, Headers(), Bodies(5), Redact("X-Api-Key"))}
+	req, err := http.NewRequest("POST", srv.URL+"/orders", strings.NewReader("hello world"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("X-Api-Key", "key")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The bodies are read again in full after being printed.
+	if string(body) != "hello world" {
+		t.Errorf("response body %q, want %q", body, "hello world")
+	}
+
+	c.ExpectSequence(
+		pstest.Tagged(ps.Sent).Containing("POST "+srv.URL+"/orders\nAuthorization: [redacted]\n"),
+		pstest.Tagged(ps.Received).Containing("200 OK POST "+srv.URL+"/orders"),
+	)
+	for _, e := range c.Entries() {
+		if strings.Contains(e.Msg, "secret") || strings.Contains(e.Msg, "key\n") || strings.Contains(e.Msg, "token") {
+			t.Errorf("printed a redacted header value: %q", e.Msg)
+		}
+		if want := "\n\nhello\n…"; !strings.HasSuffix(e.Msg, want) {
+			t.Errorf("printed %q, want the body cut to %q", e.Msg, want)
+		}
+	}
+}
+
+func TestTransportFailure(t *testing.T) {
+	ps.SetOutput(io.Discard)
+	t.Cleanup(func() { ps.SetOutput(nil) })
+	c := pstest.Capture(t)
+	srv := httptest.NewServer(echo)
+	url := srv.URL
+	srv.Close()
+
+	client := &http.Client{Transport: Transport(nil)}
+	if _, err := client.Get(url); err == nil {
+		t.Fatal("request to a closed server succeeded")
+	}
+	c.ExpectSequence(pstest.Tagged(ps.Sent), pstest.Tagged(ps.Failure).Containing("GET "+url))
+}
+
+func TestRecord(t *testing.T) {
+	ps.SetOutput(io.Discard)
+	t.Cleanup(func() { ps.SetOutput(nil) })
+	c := pstest.Capture(t)
+
+	r := Record(t, echo, httptest.NewRequest("POST", "/orders", strings.NewReader(`{"id":1}`)))
+	r.AssertStatus(http.StatusOK)
+	r.AssertBody(`{ "id": 1 }`)
+	c.ExpectSequence(
+		pstest.Tagged(ps.Sent).Containing("POST /orders"),
+		pstest.Tagged(ps.Received).Containing("200 OK"),
+	)
+}
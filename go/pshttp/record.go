@@ -0,0 +1,147 @@
+package pshttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+// Recorded is the response of a handler served by Record.
+type Recorded struct {
+	t      testing.TB
+	Code   int
+	Header http.Header
+	Body   []byte
+}
+
+// Record serves req with h, printing the request and the response as Sent
+// and Received lines linked to the call of Record. Headers are printed,
+// and bodies in full, indented if they are JSON. The response is returned
+// for assertions:
+//
+//	rec := pshttp.Record(t, handler, httptest.NewRequest("GET", "/orders/1", nil))
+//	rec.AssertStatus(http.StatusOK)
+//	rec.AssertBody(`{"id": 1}`)
+func Record(t testing.TB, h http.Handler, req *http.Request) *Recorded {
+	t.Helper()
+	p := ps.WithSkip(1)
+
+	var reqBody []byte
+	if req.Body != nil {
+		reqBody, _ = io.ReadAll(req.Body)
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+	p.T(ps.Sent, "%s", req.Method+" "+req.URL.RequestURI()+formatHeaders(req.Header, nil)+formatFullBody(reqBody))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	resp := w.Result()
+	body := w.Body.Bytes()
+	p.T(ps.Received, "%s", resp.Status+formatHeaders(resp.Header, nil)+formatFullBody(body))
+
+	return &Recorded{t: t, Code: resp.StatusCode, Header: resp.Header, Body: body}
+}
+
+// AssertStatus checks that the status code is want.
+func (r *Recorded) AssertStatus(want int) bool {
+	r.t.Helper()
+	if r.Code == want {
+		return true
+	}
+	r.fail("status",
+		fmt.Sprintf("     got: %d %s", r.Code, http.StatusText(r.Code)),
+		fmt.Sprintf("    want: %d %s", want, http.StatusText(want)))
+	return false
+}
+
+// AssertBody checks that the body is want, reporting the differing lines
+// if it is not. If both are JSON they are compared as indented JSON, so
+// that formatting differences are ignored.
+func (r *Recorded) AssertBody(want string) bool {
+	r.t.Helper()
+	got := indentJSON(r.Body)
+	w := indentJSON([]byte(want))
+	if bytes.Equal(got, w) {
+		return true
+	}
+	r.fail("body (-want +got)", diffLines(string(w), string(got))...)
+	return false
+}
+
+// fail reports a failed assertion made by the caller of fail's caller.
+func (r *Recorded) fail(what string, details ...string) {
+	r.t.Helper()
+	header := "❌ " + what
+	if _, file, line, ok := runtime.Caller(2); ok {
+		header = ps.FormatOSC8(header, ps.FormatURL(file, line))
+	}
+	r.t.Error(header + "\n" + strings.Join(details, "\n"))
+}
+
+// formatFullBody renders body after a blank line, indented if it is JSON.
+func formatFullBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	return "\n\n" + strings.TrimRight(string(indentJSON(body)), "\n")
+}
+
+// indentJSON returns b indented if it is JSON, and as is otherwise.
+func indentJSON(b []byte) []byte {
+	if !json.Valid(b) {
+		return b
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, b, "", "  "); err != nil {
+		return b
+	}
+	return buf.Bytes()
+}
+
+// diffLines returns the lines of want and got as a diff: common lines are
+// prefixed with "  ", lines only in want with "- " and lines only in got
+// with "+ ".
+func diffLines(want, got string) []string {
+	a := strings.Split(want, "\n")
+	b := strings.Split(got, "\n")
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var out []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			out = append(out, "  "+a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, "- "+a[i])
+			i++
+		default:
+			out = append(out, "+ "+b[j])
+			j++
+		}
+	}
+	return out
+}
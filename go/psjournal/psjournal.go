@@ -0,0 +1,111 @@
+//go:build linux
+
+// Package psjournal provides a ps sink that sends entries to
+// systemd-journald using its native protocol.
+//
+// The captured caller is recorded in the CODE_FILE, CODE_LINE and CODE_FUNC
+// fields, so that it is available to journalctl (e.g. with -o verbose)
+// even though the OSC8 hyperlink is not.
+package psjournal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+// SocketPath is the journald native protocol socket.
+const SocketPath = "/run/systemd/journal/socket"
+
+// Syslog priorities, as used in the journal's PRIORITY field.
+const (
+	PriErr     = 3
+	PriWarning = 4
+	PriInfo    = 6
+	PriDebug   = 7
+)
+
+// priority returns the syslog priority for a ps level.
+func priority(l ps.Level) int {
+	switch {
+	case l >= ps.LevelError:
+		return PriErr
+	case l >= ps.LevelWarn:
+		return PriWarning
+	case l >= ps.LevelInfo:
+		return PriInfo
+	default:
+		return PriDebug
+	}
+}
+
+// Sink is a ps.Sink writing to the journal.
+type Sink struct {
+	conn       *net.UnixConn
+	identifier string
+}
+
+// New connects to the journal. Entries are logged with SYSLOG_IDENTIFIER
+// set to identifier, or the program name if it is empty.
+func New(identifier string) (*Sink, error) {
+	if identifier == "" {
+		identifier = filepath.Base(os.Args[0])
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: SocketPath, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &Sink{conn: conn, identifier: identifier}, nil
+}
+
+// Available reports whether the journal socket exists.
+func Available() bool {
+	_, err := os.Stat(SocketPath)
+	return err == nil
+}
+
+// WriteEntry implements ps.Sink.
+func (s *Sink) WriteEntry(e ps.Entry) error {
+	var b bytes.Buffer
+	writeField(&b, "MESSAGE", e.Msg)
+	writeField(&b, "PRIORITY", strconv.Itoa(priority(e.Level)))
+	writeField(&b, "SYSLOG_IDENTIFIER", s.identifier)
+	if e.File != "" {
+		writeField(&b, "CODE_FILE", e.File)
+		writeField(&b, "CODE_LINE", strconv.Itoa(e.Line))
+	}
+	if e.Func != "" {
+		writeField(&b, "CODE_FUNC", e.Func)
+	}
+	// Entries too large for a single datagram are rejected by the kernel;
+	// the journal's file descriptor passing fallback is not implemented.
+	_, err := s.conn.Write(b.Bytes())
+	return err
+}
+
+// Close closes the connection to the journal.
+func (s *Sink) Close() error {
+	return s.conn.Close()
+}
+
+// writeField appends a field in the journal's native format. Values
+// containing newlines use the length-prefixed binary form.
+func writeField(b *bytes.Buffer, key, value string) {
+	b.WriteString(key)
+	if !strings.Contains(value, "\n") {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+	b.WriteByte('\n')
+	binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
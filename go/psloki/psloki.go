@@ -0,0 +1,237 @@
+// Package psloki provides a ps sink that pushes entries to Grafana Loki.
+//
+//	s := psloki.New("http://loki:3100", psloki.Label("env", "staging"))
+//	ps.AddSink(s)
+//	defer s.Close()
+//
+// Entries are batched and pushed by a background goroutine. Each entry is
+// stored as a JSON line (query it with `| json`), timestamped with the
+// wall-clock time at which it was printed, and labelled with app, level,
+// file and (if present) tag.
+package psloki
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+// Option configures a Sink.
+type Option func(*Sink)
+
+// App sets the value of the app label. The default is the program name.
+func App(name string) Option {
+	return func(s *Sink) { s.app = name }
+}
+
+// Label adds a label with a fixed value to all entries.
+func Label(name, value string) Option {
+	return func(s *Sink) { s.labels[name] = value }
+}
+
+// TenantID sets the X-Scope-OrgID header for multi-tenant Loki.
+func TenantID(id string) Option {
+	return func(s *Sink) { s.tenant = id }
+}
+
+// BatchSize sets the number of entries after which a batch is pushed.
+// The default is 500.
+func BatchSize(n int) Option {
+	return func(s *Sink) { s.batchSize = n }
+}
+
+// BatchWait sets the maximum time an entry waits before being pushed.
+// The default is 1 second.
+func BatchWait(d time.Duration) Option {
+	return func(s *Sink) { s.batchWait = d }
+}
+
+// Client sets the HTTP client used for pushing. The default has a 10
+// second timeout.
+func Client(c *http.Client) Option {
+	return func(s *Sink) { s.client = c }
+}
+
+// Sink is a ps.Sink pushing to Loki.
+type Sink struct {
+	url       string
+	app       string
+	labels    map[string]string
+	tenant    string
+	batchSize int
+	batchWait time.Duration
+	client    *http.Client
+
+	mu      sync.Mutex
+	pending []ps.Entry
+	err     error
+	closed  bool
+	flush   chan struct{}
+	done    chan struct{}
+}
+
+// New returns a sink pushing to the Loki instance at baseURL
+// (e.g. "http://localhost:3100") and starts its background pusher.
+func New(baseURL string, opts ...Option) *Sink {
+	s := &Sink{
+		url:       baseURL + "/loki/api/v1/push",
+		app:       filepath.Base(os.Args[0]),
+		labels:    map[string]string{},
+		batchSize: 500,
+		batchWait: time.Second,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		flush:     make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	go s.run()
+	return s
+}
+
+// WriteEntry implements ps.Sink. It never blocks on the network; errors
+// from pushing are reported by Err.
+func (s *Sink) WriteEntry(e ps.Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return fmt.Errorf("psloki: sink closed")
+	}
+	s.pending = append(s.pending, e)
+	if len(s.pending) >= s.batchSize {
+		select {
+		case s.flush <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Err returns the error from the most recent failed push, if any.
+func (s *Sink) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close pushes any pending entries and stops the pusher.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+	close(s.flush)
+	<-s.done
+	return s.Err()
+}
+
+func (s *Sink) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.batchWait)
+	defer ticker.Stop()
+	for {
+		select {
+		case _, ok := <-s.flush:
+			s.pushPending()
+			if !ok {
+				return
+			}
+		case <-ticker.C:
+			s.pushPending()
+		}
+	}
+}
+
+func (s *Sink) pushPending() {
+	s.mu.Lock()
+	batch := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+	err := s.push(batch)
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+type stream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// push sends batch to Loki, grouping entries into streams by label set.
+func (s *Sink) push(batch []ps.Entry) error {
+	var streams []*stream
+	byKey := map[string]*stream{}
+	for _, e := range batch {
+		labels := s.entryLabels(e)
+		key := fmt.Sprint(labels)
+		st, ok := byKey[key]
+		if !ok {
+			st = &stream{Stream: labels}
+			byKey[key] = st
+			streams = append(streams, st)
+		}
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		ts := e.Time
+		if ts.IsZero() {
+			ts = time.Now()
+		}
+		st.Values = append(st.Values, [2]string{strconv.FormatInt(ts.UnixNano(), 10), string(line)})
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"streams": streams})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.tenant != "" {
+		req.Header.Set("X-Scope-OrgID", s.tenant)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("psloki: push: %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *Sink) entryLabels(e ps.Entry) map[string]string {
+	labels := make(map[string]string, len(s.labels)+3)
+	for k, v := range s.labels {
+		labels[k] = v
+	}
+	labels["app"] = s.app
+	labels["level"] = e.Level.String()
+	if e.File != "" {
+		labels["file"] = filepath.Base(e.File)
+	}
+	if e.Tag != "" {
+		labels["tag"] = string(e.Tag)
+	}
+	return labels
+}
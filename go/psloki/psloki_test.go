@@ -0,0 +1,111 @@
+package psloki
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+// loki is a fake Loki recording the streams pushed to it.
+type loki struct {
+	mu      sync.Mutex
+	tenants []string
+	streams []stream
+	status  int
+}
+
+func (l *loki) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/loki/api/v1/push" {
+		http.NotFound(w, r)
+		return
+	}
+	var body struct{ Streams []stream }
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tenants = append(l.tenants, r.Header.Get("X-Scope-OrgID"))
+	l.streams = append(l.streams, body.Streams...)
+	if l.status != 0 {
+		w.WriteHeader(l.status)
+	}
+}
+
+func TestPush(t *testing.T) {
+	l := &loki{}
+	srv := httptest.NewServer(l)
+	defer srv.Close()
+	s := New(srv.URL, App("test"), Label("env", "ci"), TenantID("team"), BatchWait(time.Hour))
+
+	start := time.Now()
+	for _, e := range []ps.Entry{
+		{Time: start, Msg: "first", File: "/src/main.go", Level: ps.LevelInfo},
+		{Time: start.Add(time.Millisecond), Msg: "second", File: "/src/main.go", Level: ps.LevelInfo},
+		{Time: start, Msg: "done", Level: ps.LevelWarn, Tag: ps.Success},
+	} {
+		if err := s.WriteEntry(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() = %v", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.tenants) != 1 || l.tenants[0] != "team" {
+		t.Errorf("pushed with tenants %q, want one push for team", l.tenants)
+	}
+	if len(l.streams) != 2 {
+		t.Fatalf("pushed %d streams, want 2: %+v", len(l.streams), l.streams)
+	}
+	info, warn := l.streams[0], l.streams[1]
+	for _, tt := range []struct {
+		st     stream
+		labels string
+		n      int
+	}{
+		{info, "map[app:test env:ci file:main.go level:info]", 2},
+		{warn, "map[app:test env:ci level:warn tag:success]", 1},
+	} {
+		if got := fmt.Sprint(tt.st.Stream); got != tt.labels {
+			t.Errorf("stream labels %v, want %s", tt.st.Stream, tt.labels)
+		}
+		if len(tt.st.Values) != tt.n {
+			t.Errorf("stream %v has %d values, want %d", tt.st.Stream, len(tt.st.Values), tt.n)
+		}
+	}
+	if got, want := info.Values[1][0], strconv.FormatInt(start.Add(time.Millisecond).UnixNano(), 10); got != want {
+		t.Errorf("timestamp %s, want %s, the time of the entry", got, want)
+	}
+	if !strings.Contains(info.Values[1][1], `"msg":"second"`) {
+		t.Errorf("line %s, want the entry as JSON", info.Values[1][1])
+	}
+}
+
+func TestPushError(t *testing.T) {
+	l := &loki{status: http.StatusTooManyRequests}
+	srv := httptest.NewServer(l)
+	defer srv.Close()
+	s := New(srv.URL, BatchSize(1), BatchWait(time.Hour))
+	if err := s.WriteEntry(ps.Entry{Msg: "dropped"}); err != nil {
+		t.Fatal(err)
+	}
+	err := s.Close()
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Errorf("Close() = %v, want the status of the failed push", err)
+	}
+	if err := s.WriteEntry(ps.Entry{Msg: "late"}); err == nil {
+		t.Error("WriteEntry after Close succeeded")
+	}
+}
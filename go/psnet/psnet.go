@@ -0,0 +1,249 @@
+// Package psnet provides a ps sink that streams JSON-encoded entries, one
+// per line, to a TCP or unix socket.
+//
+// Entries are buffered in memory and sent by a background goroutine, which
+// reconnects with exponential backoff when the connection fails. When the
+// buffer is full the oldest entries are dropped, so a slow or unreachable
+// collector never blocks the program being observed.
+//
+// Importing this package for its side effects registers a sink for the
+// address in HYPERLINKED_REMOTE_SINK, if set:
+//
+//	import _ "github.com/dandavison/hyperlinked/go/psnet"
+//
+//	HYPERLINKED_REMOTE_SINK=tcp://collector:7000 go test ./...
+package psnet
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+func init() {
+	if addr := os.Getenv("HYPERLINKED_REMOTE_SINK"); addr != "" {
+		s, err := New(addr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "psnet: HYPERLINKED_REMOTE_SINK: %v\n", err)
+			return
+		}
+		ps.AddSink(s)
+	}
+}
+
+// Option configures a Sink.
+type Option func(*Sink)
+
+// BufferSize sets the number of entries buffered while the connection is
+// slow or down. The default is 10000.
+func BufferSize(n int) Option {
+	return func(s *Sink) { s.size = n }
+}
+
+// MaxBackoff sets the maximum delay between reconnection attempts.
+// The default is 30 seconds.
+func MaxBackoff(d time.Duration) Option {
+	return func(s *Sink) { s.maxBackoff = d }
+}
+
+// DrainTimeout sets how long Close waits for buffered entries to be sent.
+// The default is 1 second. A timeout of 0 discards them.
+func DrainTimeout(d time.Duration) Option {
+	return func(s *Sink) { s.drainTimeout = d }
+}
+
+// Sink is a ps.Sink streaming entries to a socket.
+type Sink struct {
+	network      string
+	addr         string
+	size         int
+	maxBackoff   time.Duration
+	drainTimeout time.Duration
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   [][]byte
+	dropped int
+	closed  bool
+	// drainUntil is the time until which entries buffered when the sink
+	// was closed are still sent.
+	drainUntil time.Time
+	done       chan struct{}
+}
+
+// New returns a sink sending to addr, given as tcp://host:port or
+// unix:///path/to/socket, and starts its background sender.
+func New(addr string, opts ...Option) (*Sink, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Sink{
+		size:         10000,
+		maxBackoff:   30 * time.Second,
+		drainTimeout: time.Second,
+		done:         make(chan struct{}),
+	}
+	switch u.Scheme {
+	case "tcp", "tcp4", "tcp6":
+		s.network, s.addr = u.Scheme, u.Host
+	case "unix":
+		s.network, s.addr = u.Scheme, u.Path
+	default:
+		return nil, fmt.Errorf("unsupported address %q: want tcp://host:port or unix:///path", addr)
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.cond = sync.NewCond(&s.mu)
+	go s.run()
+	return s, nil
+}
+
+// WriteEntry implements ps.Sink. It never blocks on the network.
+func (s *Sink) WriteEntry(e ps.Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return fmt.Errorf("psnet: sink closed")
+	}
+	if len(s.queue) >= s.size {
+		s.queue = s.queue[1:]
+		s.dropped++
+	}
+	s.queue = append(s.queue, b)
+	s.cond.Signal()
+	return nil
+}
+
+// Dropped returns the number of entries dropped because the buffer was full.
+func (s *Sink) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Close stops the sender once the entries still buffered have been sent,
+// waiting at most the drain timeout; entries not sent by then are
+// discarded, as are all of them if the connection is down.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.drainUntil = time.Now().Add(s.drainTimeout)
+	s.cond.Broadcast()
+	s.mu.Unlock()
+	<-s.done
+	return nil
+}
+
+// next blocks until an entry is available and returns it without removing
+// it from the queue, or returns nil if the sink is closed and drained or
+// past its drain timeout.
+func (s *Sink) next() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.queue) == 0 && !s.closed {
+		s.cond.Wait()
+	}
+	if s.closed && (len(s.queue) == 0 || !time.Now().Before(s.drainUntil)) {
+		return nil
+	}
+	return s.queue[0]
+}
+
+// deadline returns the time d from now, or the end of the drain timeout if
+// the sink is closed and that is earlier.
+func (s *Sink) deadline(d time.Duration) time.Time {
+	t := time.Now().Add(d)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed && s.drainUntil.Before(t) {
+		return s.drainUntil
+	}
+	return t
+}
+
+// sent removes b from the front of the queue, unless it has meanwhile been
+// dropped to make room.
+func (s *Sink) sent(b []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) > 0 && &s.queue[0][0] == &b[0] {
+		s.queue = s.queue[1:]
+	}
+}
+
+func (s *Sink) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func (s *Sink) run() {
+	defer close(s.done)
+	var conn net.Conn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	backoff := 100 * time.Millisecond
+	for {
+		b := s.next()
+		if b == nil {
+			return
+		}
+		if conn == nil {
+			timeout := time.Until(s.deadline(5 * time.Second))
+			if timeout <= 0 {
+				return
+			}
+			c, err := net.DialTimeout(s.network, s.addr, timeout)
+			if err != nil {
+				if !s.sleep(backoff) {
+					return
+				}
+				backoff = min(2*backoff, s.maxBackoff)
+				continue
+			}
+			conn = c
+			backoff = 100 * time.Millisecond
+		}
+		conn.SetWriteDeadline(s.deadline(5 * time.Second))
+		if _, err := conn.Write(b); err != nil {
+			conn.Close()
+			conn = nil
+			continue
+		}
+		s.sent(b)
+	}
+}
+
+// sleep waits for d, returning false early if the sink is closed.
+func (s *Sink) sleep(d time.Duration) bool {
+	deadline := time.Now().Add(d)
+	for time.Now().Before(deadline) {
+		if s.isClosed() {
+			return false
+		}
+		time.Sleep(min(50*time.Millisecond, time.Until(deadline)))
+	}
+	return !s.isClosed()
+}
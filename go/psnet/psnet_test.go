@@ -0,0 +1,106 @@
+package psnet
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+func TestSend(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	s, err := New("tcp://" + ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for _, msg := range []string{"first", "second"} {
+		if err := s.WriteEntry(ps.Entry{Msg: msg}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	sc := bufio.NewScanner(conn)
+	for _, want := range []string{"first", "second"} {
+		if !sc.Scan() {
+			t.Fatalf("read %v before %q", sc.Err(), want)
+		}
+		var e struct{ Msg string }
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("line %q: %v", sc.Bytes(), err)
+		}
+		if e.Msg != want {
+			t.Errorf("received %q, want %q", e.Msg, want)
+		}
+	}
+}
+
+func TestDropOldest(t *testing.T) {
+	// An address nobody listens on, so that the entries stay buffered.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	s, err := New("tcp://"+addr, BufferSize(2), DrainTimeout(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range []string{"first", "second", "third"} {
+		if err := s.WriteEntry(ps.Entry{Msg: msg}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := s.Dropped(); n != 1 {
+		t.Errorf("Dropped() = %d, want 1", n)
+	}
+	s.mu.Lock()
+	var kept []string
+	for _, b := range s.queue {
+		var e struct{ Msg string }
+		json.Unmarshal(b, &e)
+		kept = append(kept, e.Msg)
+	}
+	s.mu.Unlock()
+	if len(kept) != 2 || kept[0] != "second" || kept[1] != "third" {
+		t.Errorf("kept %q, want the newest two", kept)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close blocked with the connection down")
+	}
+	if err := s.WriteEntry(ps.Entry{Msg: "late"}); err == nil {
+		t.Error("WriteEntry after Close succeeded")
+	}
+}
+
+func TestNewAddress(t *testing.T) {
+	for _, addr := range []string{"http://collector:7000", "collector:7000"} {
+		if s, err := New(addr); err == nil {
+			s.Close()
+			t.Errorf("New(%q) succeeded, want an unsupported address", addr)
+		}
+	}
+}
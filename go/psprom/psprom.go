@@ -0,0 +1,143 @@
+// Package psprom provides a ps sink counting tagged entries as a
+// Prometheus counter, so that debug instrumentation doubles as coarse
+// metrics:
+//
+//	s := psprom.New()
+//	ps.AddSink(s)
+//	http.Handle("/metrics", s)
+//
+// serves, in the Prometheus text format:
+//
+//	# HELP ps_events_total Entries printed with a tag, by tag and call site.
+//	# TYPE ps_events_total counter
+//	ps_events_total{tag="failure",file="x.go",line="42"} 3
+//
+// To add the counter to an existing metrics endpoint, call WriteTo from its
+// handler.
+package psprom
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+// Option configures a Sink.
+type Option func(*Sink)
+
+// Name sets the name of the counter. The default is ps_events_total.
+func Name(name string) Option {
+	return func(s *Sink) { s.name = name }
+}
+
+// Untagged counts entries without a tag too, with an empty tag label.
+func Untagged() Option {
+	return func(s *Sink) { s.untagged = true }
+}
+
+// Sink is a ps.Sink counting entries by tag, file and line.
+type Sink struct {
+	name     string
+	untagged bool
+
+	mu     sync.Mutex
+	counts map[key]uint64
+}
+
+// key identifies a series of the counter.
+type key struct {
+	tag  ps.Tag
+	file string
+	line int
+}
+
+// New returns a Sink counting the entries printed with a tag.
+func New(opts ...Option) *Sink {
+	s := &Sink{name: "ps_events_total", counts: map[key]uint64{}}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// WriteEntry implements ps.Sink.
+func (s *Sink) WriteEntry(e ps.Entry) error {
+	if e.Tag == "" && !s.untagged {
+		return nil
+	}
+	k := key{tag: e.Tag, line: e.Line}
+	if e.File != "" {
+		k.file = filepath.Base(e.File)
+	}
+	s.mu.Lock()
+	s.counts[k]++
+	s.mu.Unlock()
+	return nil
+}
+
+// WriteTo writes the counter to w in the Prometheus text format, with
+// series sorted by tag, file and line.
+func (s *Sink) WriteTo(w io.Writer) (int64, error) {
+	s.mu.Lock()
+	keys := make([]key, 0, len(s.counts))
+	for k := range s.counts {
+		keys = append(keys, k)
+	}
+	counts := make([]uint64, len(keys))
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.tag != b.tag {
+			return a.tag < b.tag
+		}
+		if a.file != b.file {
+			return a.file < b.file
+		}
+		return a.line < b.line
+	})
+	for i, k := range keys {
+		counts[i] = s.counts[k]
+	}
+	s.mu.Unlock()
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	fmt.Fprintf(bw, "# HELP %s Entries printed with a tag, by tag and call site.\n", s.name)
+	fmt.Fprintf(bw, "# TYPE %s counter\n", s.name)
+	for i, k := range keys {
+		fmt.Fprintf(bw, "%s{tag=%s,file=%s,line=\"%d\"} %d\n",
+			s.name, quote(string(k.tag)), quote(k.file), k.line, counts[i])
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+// ServeHTTP serves the counter in the Prometheus text format.
+func (s *Sink) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	s.WriteTo(w)
+}
+
+// quote quotes a label value as the Prometheus text format requires:
+// backslashes, double quotes and newlines are escaped.
+func quote(v string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v) + `"`
+}
+
+// countingWriter counts the bytes written to w.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
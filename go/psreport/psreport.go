@@ -0,0 +1,170 @@
+// Package psreport provides a ps sink that forwards failures to an error
+// reporting service, such as Sentry, with the stack of the code that
+// printed them, so that the same call prints a clickable line locally and
+// records the error centrally:
+//
+//	r, err := psreport.Sentry(os.Getenv("SENTRY_DSN"))
+//	...
+//	s := psreport.New(r)
+//	ps.AddSink(s)
+//	defer s.Close()
+//
+// Entries tagged ps.Failure and entries at ps.LevelError or above are
+// reported. Reports are sent by a background goroutine, so printing never
+// waits for the service.
+package psreport
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+// Frame is a stack frame of a report.
+type Frame struct {
+	Func string
+	File string
+	Line int
+}
+
+// Report is a failure reported to a Reporter.
+type Report struct {
+	Entry ps.Entry
+	// Frames is the stack of the goroutine that printed the entry,
+	// innermost first, starting at the frame that printed it.
+	Frames []Frame
+}
+
+// Reporter sends reports to an error reporting service.
+type Reporter interface {
+	Report(r Report) error
+}
+
+// ReporterFunc adapts a function to a Reporter.
+type ReporterFunc func(r Report) error
+
+// Report calls f(r).
+func (f ReporterFunc) Report(r Report) error {
+	return f(r)
+}
+
+// Sink is a ps.Sink reporting failures to a Reporter.
+type Sink struct {
+	r Reporter
+
+	mu      sync.Mutex
+	pending []Report
+	err     error
+	closed  bool
+	flush   chan struct{}
+	done    chan struct{}
+}
+
+// New returns a sink reporting failures to r and starts its background
+// reporter.
+func New(r Reporter) *Sink {
+	s := &Sink{
+		r:     r,
+		flush: make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// WriteEntry implements ps.Sink. It captures the stack of the printing
+// goroutine, which ps calls it on, and queues the report; errors from
+// reporting are reported by Err.
+func (s *Sink) WriteEntry(e ps.Entry) error {
+	if e.Tag != ps.Failure && e.Level < ps.LevelError {
+		return nil
+	}
+	r := Report{Entry: e, Frames: frames(e)}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return fmt.Errorf("psreport: sink closed")
+	}
+	s.pending = append(s.pending, r)
+	select {
+	case s.flush <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Err returns the error from the most recent failed report, if any.
+func (s *Sink) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Close sends any pending reports and stops the reporter.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+	close(s.flush)
+	<-s.done
+	return s.Err()
+}
+
+func (s *Sink) run() {
+	defer close(s.done)
+	for range s.flush {
+		s.reportPending()
+	}
+	s.reportPending()
+}
+
+func (s *Sink) reportPending() {
+	s.mu.Lock()
+	batch := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+	for _, r := range batch {
+		if err := s.r.Report(r); err != nil {
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
+		}
+	}
+}
+
+// modulePrefix is the import path prefix of the packages of this module,
+// whose frames are dropped from reports.
+const modulePrefix = "github.com/dandavison/hyperlinked/go/"
+
+// frames returns the stack of the calling goroutine from the frame at the
+// location of e, or, if that is not on the stack, from the first frame
+// outside this module.
+func frames(e ps.Entry) []Frame {
+	var pcs [64]uintptr
+	n := runtime.Callers(3, pcs[:])
+	var all []Frame
+	from := -1
+	iter := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := iter.Next()
+		all = append(all, Frame{Func: f.Function, File: f.File, Line: f.Line})
+		if from < 0 && f.File == e.File && f.Line == e.Line {
+			from = len(all) - 1
+		}
+		if !more {
+			break
+		}
+	}
+	if from < 0 {
+		for from = 0; from < len(all)-1 && strings.HasPrefix(all[from].Func, modulePrefix); from++ {
+		}
+	}
+	return all[from:]
+}
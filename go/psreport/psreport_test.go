@@ -0,0 +1,123 @@
+package psreport
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+func TestSink(t *testing.T) {
+	ps.SetOutput(io.Discard)
+	t.Cleanup(func() { ps.SetOutput(nil) })
+	var (
+		mu      sync.Mutex
+		reports []Report
+	)
+	s := New(ReporterFunc(func(r Report) error {
+		mu.Lock()
+		defer mu.Unlock()
+		reports = append(reports, r)
+		return nil
+	}))
+	ps.AddSink(s)
+	defer ps.RemoveSink(s)
+
+	ps.F("not a failure\n")
+	_, file, line, _ := runtime.Caller(0)
+	ps.T(ps.Failure, "failed\n")
+	ps.At(ps.LevelError).F("error\n")
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reports) != 2 || reports[0].Entry.Msg != "failed" || reports[1].Entry.Msg != "error" {
+		t.Fatalf("reported %+v, want the failure and the error", reports)
+	}
+	f := reports[0].Frames[0]
+	if f.File != file || f.Line != line+1 || !strings.HasSuffix(f.Func, ".TestSink") {
+		t.Errorf("stack starts at %s %s:%d, want the call of T at %s:%d", f.Func, f.File, f.Line, file, line+1)
+	}
+}
+
+func TestSentry(t *testing.T) {
+	var (
+		path, auth string
+		lines      []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("X-Sentry-Auth")
+		sc := bufio.NewScanner(r.Body)
+		for sc.Scan() {
+			lines = append(lines, sc.Text())
+		}
+	}))
+	defer srv.Close()
+
+	rep, err := Sentry(strings.Replace(srv.URL, "://", "://key@", 1) + "/sentry/42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rep.Report(Report{
+		Entry: ps.Entry{Msg: "save failed", Tag: ps.Failure, Fields: []ps.Field{{Key: "order", Value: 7}}},
+		Frames: []Frame{
+			{Func: "main.save", File: "/src/main.go", Line: 20},
+			{Func: "main.main", File: "/src/main.go", Line: 10},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if path != "/sentry/api/42/envelope/" {
+		t.Errorf("posted to %s, want /sentry/api/42/envelope/", path)
+	}
+	if !strings.Contains(auth, "sentry_key=key") {
+		t.Errorf("X-Sentry-Auth %q, want the key of the DSN", auth)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("posted %d lines, want an envelope header, an item header and an event: %q", len(lines), lines)
+	}
+	var event struct {
+		Exception struct {
+			Values []struct {
+				Type       string
+				Value      string
+				Stacktrace struct {
+					Frames []struct{ Function string }
+				}
+			}
+		}
+		Extra map[string]int
+	}
+	if err := json.Unmarshal([]byte(lines[2]), &event); err != nil {
+		t.Fatal(err)
+	}
+	if len(event.Exception.Values) != 1 {
+		t.Fatalf("event %s, want one exception", lines[2])
+	}
+	ex := event.Exception.Values[0]
+	if ex.Type != "failure" || ex.Value != "save failed" || event.Extra["order"] != 7 {
+		t.Errorf("event %s, want the tag, message and fields of the entry", lines[2])
+	}
+	if fs := ex.Stacktrace.Frames; len(fs) != 2 || fs[0].Function != "main.main" {
+		t.Errorf("frames %+v, want them outermost first", fs)
+	}
+}
+
+func TestSentryDSN(t *testing.T) {
+	for _, dsn := range []string{"https://o0.ingest.sentry.io/123", "https://key@o0.ingest.sentry.io/", "://"} {
+		if _, err := Sentry(dsn); err == nil {
+			t.Errorf("Sentry(%q) succeeded, want an invalid DSN", dsn)
+		}
+	}
+}
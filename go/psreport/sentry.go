@@ -0,0 +1,126 @@
+package psreport
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+// sentry is the Reporter returned by Sentry.
+type sentry struct {
+	dsn      string
+	endpoint string
+	auth     string
+	client   *http.Client
+}
+
+// Sentry returns a Reporter sending reports as events to the Sentry
+// project with the given DSN, such as
+// "https://key@o0.ingest.sentry.io/123". Each event carries the message
+// as an exception of the entry's tag, or level, with the stack of the
+// report; the fields of the entry are its extra data.
+func Sentry(dsn string) (Reporter, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("psreport: invalid DSN: %w", err)
+	}
+	project := strings.TrimPrefix(u.Path, "/")
+	if u.User == nil || u.User.Username() == "" || project == "" {
+		return nil, fmt.Errorf("psreport: invalid DSN %q: want scheme://key@host/project", dsn)
+	}
+	path := ""
+	if i := strings.LastIndexByte(project, '/'); i >= 0 {
+		path, project = "/"+project[:i], project[i+1:]
+	}
+	return &sentry{
+		dsn:      dsn,
+		endpoint: u.Scheme + "://" + u.Host + path + "/api/" + project + "/envelope/",
+		auth:     "Sentry sentry_version=7, sentry_client=hyperlinked/1, sentry_key=" + u.User.Username(),
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+// Report implements Reporter, sending r as an event envelope.
+func (s *sentry) Report(r Report) error {
+	var id [16]byte
+	rand.Read(id[:])
+	eventID := hex.EncodeToString(id[:])
+	event, err := json.Marshal(sentryEvent(eventID, r))
+	if err != nil {
+		return err
+	}
+	header, _ := json.Marshal(map[string]string{"event_id": eventID, "dsn": s.dsn})
+
+	var body bytes.Buffer
+	body.Write(header)
+	body.WriteString("\n{\"type\":\"event\"}\n")
+	body.Write(event)
+	body.WriteByte('\n')
+
+	req, err := http.NewRequest("POST", s.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("psreport: sentry returned %s", resp.Status)
+	}
+	return nil
+}
+
+// sentryEvent returns the Sentry event for r.
+func sentryEvent(eventID string, r Report) map[string]interface{} {
+	e := r.Entry
+	// Sentry lists frames outermost first.
+	frames := make([]map[string]interface{}, 0, len(r.Frames))
+	for i := len(r.Frames) - 1; i >= 0; i-- {
+		f := r.Frames[i]
+		frames = append(frames, map[string]interface{}{
+			"function": f.Func,
+			"filename": filepath.Base(f.File),
+			"abs_path": f.File,
+			"lineno":   f.Line,
+			"in_app":   !strings.HasPrefix(f.Func, "runtime."),
+		})
+	}
+	kind := string(e.Tag)
+	if kind == "" {
+		kind = e.Level.String()
+	}
+	event := map[string]interface{}{
+		"event_id":  eventID,
+		"timestamp": e.Time.UTC().Format(time.RFC3339Nano),
+		"platform":  "go",
+		"level":     "error",
+		"logger":    "ps",
+		"exception": map[string]interface{}{
+			"values": []map[string]interface{}{{
+				"type":       kind,
+				"value":      e.Msg,
+				"stacktrace": map[string]interface{}{"frames": frames},
+			}},
+		},
+	}
+	if len(e.Fields) > 0 {
+		event["extra"] = json.RawMessage(ps.MarshalFields(e.Fields))
+	}
+	if e.Goroutine != 0 {
+		event["tags"] = map[string]string{"goroutine": fmt.Sprint(e.Goroutine)}
+	}
+	return event
+}
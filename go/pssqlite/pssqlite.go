@@ -0,0 +1,228 @@
+// Package pssqlite provides a ps sink that appends entries to a SQLite
+// database, and queries to read them back.
+//
+//	s, err := pssqlite.Open("soak.db")
+//	...
+//	ps.AddSink(s)
+//	defer s.Close()
+//
+// The database can be searched later with `hyperlinked query`, which
+// re-renders matching entries as hyperlinked terminal output.
+//
+// The package is a module of its own, so that programs importing ps and
+// its other sinks do not depend on SQLite.
+package pssqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+	_ "modernc.org/sqlite"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS entries (
+	id        INTEGER PRIMARY KEY,
+	ts        INTEGER NOT NULL,
+	elapsed   INTEGER NOT NULL,
+	msg       TEXT NOT NULL,
+	file      TEXT NOT NULL,
+	line      INTEGER NOT NULL,
+	func      TEXT NOT NULL,
+	level     TEXT NOT NULL,
+	tag       TEXT NOT NULL,
+	goroutine INTEGER NOT NULL,
+	fields    TEXT
+);
+CREATE INDEX IF NOT EXISTS entries_ts ON entries (ts);
+CREATE INDEX IF NOT EXISTS entries_location ON entries (file, line);
+`
+
+// Sink is a ps.Sink appending to a SQLite database.
+type Sink struct {
+	db     *sql.DB
+	mu     sync.Mutex
+	insert *sql.Stmt
+}
+
+// Open opens (creating if necessary) the database at path and returns a
+// sink appending to it.
+func Open(path string) (*Sink, error) {
+	db, err := OpenDB(path)
+	if err != nil {
+		return nil, err
+	}
+	insert, err := db.Prepare(`INSERT INTO entries
+		(ts, elapsed, msg, file, line, func, level, tag, goroutine, fields)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Sink{db: db, insert: insert}, nil
+}
+
+// OpenDB opens (creating if necessary) the database at path and ensures
+// it has the entries table.
+func OpenDB(path string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// WriteEntry implements ps.Sink.
+func (s *Sink) WriteEntry(e ps.Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var fields interface{}
+	if len(e.Fields) > 0 {
+		fields = string(ps.MarshalFields(e.Fields))
+	}
+	_, err := s.insert.Exec(e.Time.UnixNano(), int64(e.Elapsed), e.Msg, e.File, e.Line, e.Func,
+		e.Level.String(), string(e.Tag), e.Goroutine, fields)
+	return err
+}
+
+// DB returns the underlying database.
+func (s *Sink) DB() *sql.DB {
+	return s.db
+}
+
+// Close closes the database.
+func (s *Sink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.insert.Close()
+	return s.db.Close()
+}
+
+// Filter selects entries in Query. Zero fields match everything.
+type Filter struct {
+	// File matches entries whose file path ends with File, e.g.
+	// "server.go" or "pkg/server/server.go".
+	File string
+	// Line matches entries at this line (together with File).
+	Line int
+	// Func matches entries whose function name contains Func.
+	Func string
+	// Text matches entries whose message contains Text.
+	Text string
+	// Level matches entries at or above this level, if non-nil.
+	Level *ps.Level
+	// Tag matches entries with this tag.
+	Tag ps.Tag
+	// Goroutine matches entries printed by this goroutine.
+	Goroutine int64
+	// After and Before bound the wall-clock time of entries.
+	After, Before time.Time
+	// From and To bound the time of entries relative to the start timer.
+	From, To time.Duration
+	// Limit caps the number of entries returned.
+	Limit int
+	// Offset skips this many matching entries, e.g. those already read
+	// when tailing the database.
+	Offset int
+}
+
+// Query returns the entries in db matching f, in the order they were written.
+func Query(db *sql.DB, f Filter) ([]ps.Entry, error) {
+	var where []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		where = append(where, cond)
+		args = append(args, arg)
+	}
+	if f.File != "" {
+		add("(file = ? OR file LIKE ? ESCAPE '\\')", f.File)
+		args = append(args, "%/"+escapeLike(f.File))
+	}
+	if f.Line != 0 {
+		add("line = ?", f.Line)
+	}
+	if f.Func != "" {
+		add("func LIKE ? ESCAPE '\\'", "%"+escapeLike(f.Func)+"%")
+	}
+	if f.Text != "" {
+		add("msg LIKE ? ESCAPE '\\'", "%"+escapeLike(f.Text)+"%")
+	}
+	if f.Level != nil {
+		var levels []string
+		for _, l := range []ps.Level{ps.LevelDebug, ps.LevelInfo, ps.LevelWarn, ps.LevelError} {
+			if l >= *f.Level {
+				levels = append(levels, "'"+l.String()+"'")
+			}
+		}
+		where = append(where, "level IN ("+strings.Join(levels, ", ")+")")
+	}
+	if f.Tag != "" {
+		add("tag = ?", string(f.Tag))
+	}
+	if f.Goroutine != 0 {
+		add("goroutine = ?", f.Goroutine)
+	}
+	if !f.After.IsZero() {
+		add("ts >= ?", f.After.UnixNano())
+	}
+	if !f.Before.IsZero() {
+		add("ts < ?", f.Before.UnixNano())
+	}
+	if f.From != 0 {
+		add("elapsed >= ?", int64(f.From))
+	}
+	if f.To != 0 {
+		add("elapsed < ?", int64(f.To))
+	}
+
+	query := "SELECT ts, elapsed, msg, file, line, func, level, tag, goroutine, fields FROM entries"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY id"
+	if f.Limit > 0 || f.Offset > 0 {
+		limit := f.Limit
+		if limit <= 0 {
+			limit = -1
+		}
+		query += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, f.Offset)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []ps.Entry
+	for rows.Next() {
+		var e ps.Entry
+		var ts, elapsed int64
+		var level, tag string
+		var fields sql.NullString
+		if err := rows.Scan(&ts, &elapsed, &e.Msg, &e.File, &e.Line, &e.Func, &level, &tag, &e.Goroutine, &fields); err != nil {
+			return nil, err
+		}
+		if e.Fields, err = ps.UnmarshalFields([]byte(fields.String)); err != nil {
+			return nil, err
+		}
+		e.Time = time.Unix(0, ts)
+		e.Elapsed = time.Duration(elapsed)
+		e.Level, _ = ps.ParseLevel(level)
+		e.Tag = ps.Tag(tag)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
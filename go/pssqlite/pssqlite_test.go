@@ -0,0 +1,77 @@
+package pssqlite
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+func TestQuery(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "soak.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	start := time.Unix(1700000000, 0)
+	for i, e := range []ps.Entry{
+		{Msg: "listening", File: "/src/pkg/server/server.go", Line: 10, Func: "server.Serve", Level: ps.LevelInfo},
+		{Msg: "100% slow", File: "/src/pkg/server/server.go", Line: 20, Func: "server.handle", Level: ps.LevelWarn,
+			Fields: []ps.Field{{Key: "ms", Value: 1500}}},
+		{Msg: "saved", File: "/src/pkg/db/db.go", Line: 20, Func: "db.Save", Level: ps.LevelInfo, Tag: ps.Success},
+		{Msg: "failed", File: "/src/xserver.go", Line: 20, Func: "main.main", Level: ps.LevelError, Goroutine: 7},
+	} {
+		e.Time = start.Add(time.Duration(i) * time.Second)
+		e.Elapsed = time.Duration(i) * time.Second
+		if err := s.WriteEntry(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	warn := ps.LevelWarn
+	for _, tt := range []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"all", Filter{}, []string{"listening", "100% slow", "saved", "failed"}},
+		// server.go does not match xserver.go, as it matches whole path
+		// elements.
+		{"file", Filter{File: "server.go"}, []string{"listening", "100% slow"}},
+		{"file and line", Filter{File: "server/server.go", Line: 20}, []string{"100% slow"}},
+		{"func", Filter{Func: "Save"}, []string{"saved"}},
+		{"text with a wildcard", Filter{Text: "100%"}, []string{"100% slow"}},
+		{"level", Filter{Level: &warn}, []string{"100% slow", "failed"}},
+		{"tag", Filter{Tag: ps.Success}, []string{"saved"}},
+		{"goroutine", Filter{Goroutine: 7}, []string{"failed"}},
+		{"time", Filter{After: start.Add(time.Second), Before: start.Add(3 * time.Second)}, []string{"100% slow", "saved"}},
+		{"elapsed", Filter{From: 2 * time.Second}, []string{"saved", "failed"}},
+		{"limit and offset", Filter{Limit: 2, Offset: 1}, []string{"100% slow", "saved"}},
+		{"offset", Filter{Offset: 3}, []string{"failed"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := Query(s.DB(), tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, e := range entries {
+				got = append(got, e.Msg)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Query() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	entries, err := Query(s.DB(), Filter{Text: "slow"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := entries[0]
+	if e.Level != ps.LevelWarn || !e.Time.Equal(start.Add(time.Second)) || e.Elapsed != time.Second || len(e.Fields) != 1 || e.Fields[0].Key != "ms" {
+		t.Errorf("read back %+v, want the entry written", e)
+	}
+}
@@ -0,0 +1,65 @@
+//go:build !windows && !plan9
+
+// Package pssyslog provides a ps sink that forwards entries to syslog.
+//
+// Since syslog messages have no structured fields, the source location is
+// included in the message text:
+//
+//	server.go:42: accepted connection
+package pssyslog
+
+import (
+	"fmt"
+	"log/syslog"
+	"path/filepath"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+// Sink is a ps.Sink writing to syslog.
+type Sink struct {
+	w *syslog.Writer
+}
+
+// New connects to the local syslog daemon, logging with the given facility
+// and tag. An empty tag defaults to the program name.
+func New(facility syslog.Priority, tag string) (*Sink, error) {
+	w, err := syslog.New(facility|syslog.LOG_INFO, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &Sink{w: w}, nil
+}
+
+// Dial connects to the syslog daemon at raddr on the given network.
+// See syslog.Dial.
+func Dial(network, raddr string, facility syslog.Priority, tag string) (*Sink, error) {
+	w, err := syslog.Dial(network, raddr, facility|syslog.LOG_INFO, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &Sink{w: w}, nil
+}
+
+// WriteEntry implements ps.Sink.
+func (s *Sink) WriteEntry(e ps.Entry) error {
+	msg := e.Msg
+	if e.File != "" {
+		msg = fmt.Sprintf("%s:%d: %s", filepath.Base(e.File), e.Line, e.Msg)
+	}
+	switch {
+	case e.Level >= ps.LevelError:
+		return s.w.Err(msg)
+	case e.Level >= ps.LevelWarn:
+		return s.w.Warning(msg)
+	case e.Level >= ps.LevelInfo:
+		return s.w.Info(msg)
+	default:
+		return s.w.Debug(msg)
+	}
+}
+
+// Close closes the connection to syslog.
+func (s *Sink) Close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,272 @@
+// Package pstest provides test assertions whose failure messages link to
+// the failing assertion, so that clicking a failure in the test output
+// opens the editor at that line.
+//
+//	func TestParse(t *testing.T) {
+//		require := pstest.Require(t)
+//		assert := pstest.Assert(t)
+//
+//		v, err := Parse("42")
+//		require.NoError(err)
+//		assert.Equal(v, 42)
+//	}
+//
+// A failure reports the assertion's source text and the values involved:
+//
+//	❌ assert.Equal(v, 42)
+//	     got: 41
+//	    want: 42
+//
+// Assert's checks mark the test as failed and continue; Require's stop the
+// test with t.FailNow.
+//
+// A failure in a test helper marked with ps.Helper links to the call of the
+// helper instead, as t.Helper does for the file:line testing reports.
+//
+// Capture records the entries a test prints, and ExpectSequence checks
+// that they include a sequence of tagged events.
+package pstest
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+// Checker performs assertions for a test.
+type Checker struct {
+	t     testing.TB
+	fatal bool
+}
+
+// Assert returns a checker whose failed checks call t.Fail and continue.
+func Assert(t testing.TB) *Checker {
+	return &Checker{t: t}
+}
+
+// Require returns a checker whose failed checks call t.FailNow.
+func Require(t testing.TB) *Checker {
+	return &Checker{t: t, fatal: true}
+}
+
+// Equal checks that got and want are deeply equal.
+func (c *Checker) Equal(got, want interface{}) bool {
+	c.t.Helper()
+	ps.Helper()
+	if equal(got, want) {
+		return true
+	}
+	c.fail(fmt.Sprintf("got: %#v", got), fmt.Sprintf("want: %#v", want))
+	return false
+}
+
+// NotEqual checks that got and other are not deeply equal.
+func (c *Checker) NotEqual(got, other interface{}) bool {
+	c.t.Helper()
+	ps.Helper()
+	if !equal(got, other) {
+		return true
+	}
+	c.fail(fmt.Sprintf("got: %#v", got), "want: anything else")
+	return false
+}
+
+// True checks that cond is true.
+func (c *Checker) True(cond bool) bool {
+	c.t.Helper()
+	ps.Helper()
+	if cond {
+		return true
+	}
+	c.fail("got: false", "want: true")
+	return false
+}
+
+// False checks that cond is false.
+func (c *Checker) False(cond bool) bool {
+	c.t.Helper()
+	ps.Helper()
+	if !cond {
+		return true
+	}
+	c.fail("got: true", "want: false")
+	return false
+}
+
+// Nil checks that v is nil, or a nil pointer, map, slice, etc.
+func (c *Checker) Nil(v interface{}) bool {
+	c.t.Helper()
+	ps.Helper()
+	if isNil(v) {
+		return true
+	}
+	c.fail(fmt.Sprintf("got: %#v", v), "want: nil")
+	return false
+}
+
+// NotNil checks that v is not nil.
+func (c *Checker) NotNil(v interface{}) bool {
+	c.t.Helper()
+	ps.Helper()
+	if !isNil(v) {
+		return true
+	}
+	c.fail("got: nil", "want: non-nil")
+	return false
+}
+
+// NoError checks that err is nil.
+func (c *Checker) NoError(err error) bool {
+	c.t.Helper()
+	ps.Helper()
+	if err == nil {
+		return true
+	}
+	c.fail(fmt.Sprintf("error: %v", err))
+	return false
+}
+
+// Error checks that err is non-nil.
+func (c *Checker) Error(err error) bool {
+	c.t.Helper()
+	ps.Helper()
+	if err != nil {
+		return true
+	}
+	c.fail("got: nil", "want: an error")
+	return false
+}
+
+// ErrorIs checks that errors.Is(err, target).
+func (c *Checker) ErrorIs(err, target error) bool {
+	c.t.Helper()
+	ps.Helper()
+	if errors.Is(err, target) {
+		return true
+	}
+	c.fail(fmt.Sprintf("got: %v", err), fmt.Sprintf("want: %v", target))
+	return false
+}
+
+// Contains checks that s contains substr.
+func (c *Checker) Contains(s, substr string) bool {
+	c.t.Helper()
+	ps.Helper()
+	if strings.Contains(s, substr) {
+		return true
+	}
+	c.fail(fmt.Sprintf("got: %q", s), fmt.Sprintf("want substring: %q", substr))
+	return false
+}
+
+// FlushDeferredOnFailure arranges for the lines recorded with ps.Defer to
+// be printed when t finishes if it failed, and discarded if it passed:
+//
+//	func TestSync(t *testing.T) {
+//		pstest.FlushDeferredOnFailure(t)
+//		...
+//	}
+//
+// Deferred lines are recorded globally, so tests running in parallel see
+// each other's lines.
+func FlushDeferredOnFailure(t testing.TB) {
+	t.Cleanup(func() {
+		if t.Failed() {
+			ps.FlushDeferred()
+		} else {
+			ps.DiscardDeferred()
+		}
+	})
+}
+
+// fail reports a failed check made by the first caller of fail not marked
+// with ps.Helper, as the checks are, with the given detail lines.
+func (c *Checker) fail(details ...string) {
+	c.t.Helper()
+	ps.Helper()
+	file, line, ok := ps.Caller(0)
+	header := "❌ assertion failed"
+	if ok {
+		if src := sourceLine(file, line); src != "" {
+			header = "❌ " + src
+		}
+		header = ps.FormatOSC8(header, ps.FormatURL(file, line))
+	}
+
+	var b strings.Builder
+	b.WriteString(header)
+	for _, d := range details {
+		// Right-align the labels so that the values line up.
+		label, value, _ := strings.Cut(d, ": ")
+		fmt.Fprintf(&b, "\n    %*s: %s", labelWidth(details), label, value)
+	}
+	if c.fatal {
+		c.t.Fatal(b.String())
+	} else {
+		c.t.Error(b.String())
+	}
+}
+
+func labelWidth(details []string) int {
+	w := 0
+	for _, d := range details {
+		label, _, _ := strings.Cut(d, ": ")
+		w = max(w, len(label))
+	}
+	return w
+}
+
+func equal(got, want interface{}) bool {
+	if g, ok := got.([]byte); ok {
+		if w, ok := want.([]byte); ok {
+			return bytes.Equal(g, w)
+		}
+	}
+	return reflect.DeepEqual(got, want)
+}
+
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Pointer, reflect.Slice:
+		return rv.IsNil()
+	}
+	return false
+}
+
+var (
+	sourceMu    sync.Mutex
+	sourceFiles = map[string][]string{}
+)
+
+// sourceLine returns the trimmed text of line in file, or "" if the file
+// cannot be read.
+func sourceLine(file string, line int) string {
+	sourceMu.Lock()
+	defer sourceMu.Unlock()
+	lines, ok := sourceFiles[file]
+	if !ok {
+		if f, err := os.Open(file); err == nil {
+			sc := bufio.NewScanner(f)
+			for sc.Scan() {
+				lines = append(lines, sc.Text())
+			}
+			f.Close()
+		}
+		sourceFiles[file] = lines
+	}
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return strings.TrimSpace(lines[line-1])
+}
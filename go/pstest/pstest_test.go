@@ -0,0 +1,114 @@
+package pstest
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+// fakeT records the failures reported to it in place of failing the test.
+type fakeT struct {
+	testing.TB
+	errors   []string
+	fatal    bool
+	cleanups []func()
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Error(args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprint(args...))
+}
+
+func (t *fakeT) Fatal(args ...interface{}) {
+	t.Error(args...)
+	t.fatal = true
+}
+
+func (t *fakeT) Cleanup(f func()) {
+	t.cleanups = append(t.cleanups, f)
+}
+
+// cleanup runs the functions registered with Cleanup, last first.
+func (t *fakeT) cleanup() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
+
+// only returns the only failure reported to t.
+func (t *fakeT) only(tb testing.TB) string {
+	tb.Helper()
+	if len(t.errors) != 1 {
+		tb.Fatalf("reported %d failures, want 1: %q", len(t.errors), t.errors)
+	}
+	return t.errors[0]
+}
+
+// checkPositive is a test helper marked with ps.Helper.
+func checkPositive(assert *Checker, n int) {
+	ps.Helper()
+	assert.True(n > 0)
+}
+
+func TestCheckerFailure(t *testing.T) {
+	ft := &fakeT{}
+	assert := Assert(ft)
+	if !assert.Equal(1, 1) || len(ft.errors) > 0 {
+		t.Fatalf("Equal(1, 1) failed: %q", ft.errors)
+	}
+	assert.Equal(41, 42)
+	got := ft.only(t)
+	for _, want := range []string{"❌ assert.Equal(41, 42)", "\n     got: 41", "\n    want: 42"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("reported %q, want it to contain %q", got, want)
+		}
+	}
+	if ft.fatal {
+		t.Error("Assert called Fatal")
+	}
+}
+
+func TestCheckerHelper(t *testing.T) {
+	ft := &fakeT{}
+	checkPositive(Assert(ft), -1)
+	if got, want := ft.only(t), "❌ checkPositive(Assert(ft), -1)"; !strings.Contains(got, want) {
+		t.Errorf("reported %q, want it to contain %q, the call of the helper", got, want)
+	}
+}
+
+func TestRequireFatal(t *testing.T) {
+	ft := &fakeT{}
+	Require(ft).NoError(io.EOF)
+	if got := ft.only(t); !strings.Contains(got, "error: EOF") {
+		t.Errorf("reported %q, want the error", got)
+	}
+	if !ft.fatal {
+		t.Error("Require did not call Fatal")
+	}
+}
+
+func TestExpectSequence(t *testing.T) {
+	ps.SetOutput(io.Discard)
+	t.Cleanup(func() { ps.SetOutput(nil) })
+	ft := &fakeT{}
+	c := Capture(ft)
+	defer ft.cleanup()
+	ps.T(ps.Sent, "GET /orders\n")
+	ps.F("unrelated\n")
+	ps.T(ps.Received, "200 OK\n")
+
+	if !c.ExpectSequence(Tagged(ps.Sent), Tagged(ps.Received).Containing("200")) {
+		t.Fatalf("sequence not found: %q", ft.errors)
+	}
+	c.ExpectSequence(Tagged(ps.Received), Tagged(ps.Sent))
+	got := ft.only(t)
+	for _, want := range []string{"expected sequence not printed", "✓ " + Tagged(ps.Received).String(), "✗ " + Tagged(ps.Sent).String(), "printed (3 entries)"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("reported %q, want it to contain %q", got, want)
+		}
+	}
+}
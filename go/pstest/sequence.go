@@ -0,0 +1,173 @@
+package pstest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dandavison/hyperlinked/go/ps"
+)
+
+// Recorder records the entries printed while a test runs.
+type Recorder struct {
+	t       testing.TB
+	mu      sync.Mutex
+	entries []ps.Entry
+}
+
+// Capture starts recording the entries printed by ps, until the end of the
+// test. Entries are recorded globally, so tests running in parallel see
+// each other's entries.
+func Capture(t testing.TB) *Recorder {
+	c := &Recorder{t: t}
+	ps.AddSink(c)
+	t.Cleanup(func() { ps.RemoveSink(c) })
+	return c
+}
+
+// WriteEntry implements ps.Sink.
+func (c *Recorder) WriteEntry(e ps.Entry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = append(c.entries, e)
+	return nil
+}
+
+// Entries returns the entries recorded so far.
+func (c *Recorder) Entries() []ps.Entry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]ps.Entry(nil), c.entries...)
+}
+
+// Matcher matches an entry in a sequence checked by ExpectSequence.
+type Matcher struct {
+	tag      ps.Tag
+	contains string
+	within   time.Duration
+}
+
+// Tagged matches entries tagged tag.
+func Tagged(tag ps.Tag) Matcher {
+	return Matcher{tag: tag}
+}
+
+// Containing returns a copy of m that also requires the message to
+// contain s.
+func (m Matcher) Containing(s string) Matcher {
+	m.contains = s
+	return m
+}
+
+// Within returns a copy of m that also requires the entry to be printed
+// within d of the entry matched before it.
+func (m Matcher) Within(d time.Duration) Matcher {
+	m.within = d
+	return m
+}
+
+// String describes m as in failure messages.
+func (m Matcher) String() string {
+	s := string(m.tag)
+	if e := m.tag.Emoji(); e != "" {
+		s = e + " " + s
+	}
+	if m.contains != "" {
+		s += fmt.Sprintf(" containing %q", m.contains)
+	}
+	if m.within > 0 {
+		s += " within " + m.within.String()
+	}
+	return s
+}
+
+// matches reports whether m matches e, printed after prev, which is zero
+// for the first entry of a sequence.
+func (m Matcher) matches(e ps.Entry, prev time.Time) bool {
+	return e.Tag == m.tag && strings.Contains(e.Msg, m.contains) &&
+		(m.within <= 0 || prev.IsZero() || e.Time.Sub(prev) <= m.within)
+}
+
+// ExpectSequence checks that the entries recorded so far include entries
+// matching matchers, in order; other entries may come between them. On
+// failure it reports the first matcher not matched, linked to the call of
+// ExpectSequence, and the entries recorded, each linked to where it was
+// printed.
+//
+//	c := pstest.Capture(t)
+//	client.Get("/orders")
+//	c.ExpectSequence(
+//		pstest.Tagged(ps.Sent),
+//		pstest.Tagged(ps.Received).Containing("200").Within(time.Second),
+//		pstest.Tagged(ps.Success),
+//	)
+func (c *Recorder) ExpectSequence(matchers ...Matcher) bool {
+	c.t.Helper()
+	ps.Helper()
+	file, line, ok := ps.Caller(0)
+	return c.check(file, line, ok, matchers)
+}
+
+// ExpectSequence starts a Capture and checks at the end of the test that
+// the entries printed include entries matching matchers, in order, as
+// Recorder.ExpectSequence does.
+func ExpectSequence(t testing.TB, matchers ...Matcher) {
+	t.Helper()
+	ps.Helper()
+	file, line, ok := ps.Caller(0)
+	c := &Recorder{t: t}
+	ps.AddSink(c)
+	t.Cleanup(func() {
+		ps.RemoveSink(c)
+		c.check(file, line, ok, matchers)
+	})
+}
+
+// check checks matchers against the entries, reporting a failure linked
+// to file:line if ok.
+func (c *Recorder) check(file string, line int, ok bool, matchers []Matcher) bool {
+	c.t.Helper()
+	entries := c.Entries()
+	var prev time.Time
+	i := 0
+	for _, e := range entries {
+		if i < len(matchers) && matchers[i].matches(e, prev) {
+			prev = e.Time
+			i++
+		}
+	}
+	if i == len(matchers) {
+		return true
+	}
+
+	header := "❌ expected sequence not printed"
+	if ok {
+		header = ps.FormatOSC8(header, ps.FormatURL(file, line))
+	}
+	var b strings.Builder
+	b.WriteString(header)
+	for j, m := range matchers {
+		mark := "✓"
+		switch {
+		case j == i:
+			mark = "✗"
+		case j > i:
+			mark = " "
+		}
+		fmt.Fprintf(&b, "\n    %s %s", mark, m)
+	}
+	fmt.Fprintf(&b, "\n  printed (%d entries):", len(entries))
+	for _, e := range entries {
+		// Drop the newline ending the line, inside its hyperlink.
+		text := e.String()
+		if k := strings.LastIndexByte(text, '\n'); k >= 0 {
+			text = text[:k] + text[k+1:]
+		}
+		b.WriteString("\n    ")
+		b.WriteString(text)
+	}
+	c.t.Error(b.String())
+	return false
+}
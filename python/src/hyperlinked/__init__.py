@@ -3,10 +3,27 @@ import inspect
 import os
 import sys
 import traceback
+import unicodedata
 from pathlib import Path
 from typing import Any, Optional
+from urllib.parse import quote
 
-SCHEME = os.environ.get("HYPERLINKED_SCHEME", "file")
+
+def _env_format() -> str:
+    """
+    Return the link format set by HYPERLINKED_FORMAT, as read by the Go ps
+    package, or by its former name HYPERLINKED_SCHEME. Of a comma-separated
+    list, the first format is used, as formats are not probed here.
+    """
+    spec = os.environ.get("HYPERLINKED_FORMAT") or os.environ.get("HYPERLINKED_SCHEME")
+    return (spec or "cursor").split(",")[0].strip() or "cursor"
+
+
+# The link format: "cursor" (the default), "vscode", "file" or "wormhole".
+SCHEME = _env_format()
+WORMHOLE = os.environ.get("HYPERLINKED_WORMHOLE") or "wormhole:7117"
+TRUNCATE = not os.environ.get("HYPERLINKED_NO_TRUNCATE")
+ELLIPSIS = os.environ.get("HYPERLINKED_ELLIPSIS") or "…"
 
 
 def print(
@@ -21,13 +38,14 @@ def print(
     Print to terminal, hyperlinked to the current file and line number.
 
     The same as the built-in print function, but adds an OSC8 hyperlink to the call site.
+    The text is truncated to the width set by HYPERLINKED_COLUMNS, as by the Go ps package.
     """
     if (frame := inspect.currentframe()) is not None:
         if len(caller_frame_info := inspect.getouterframes(frame, 2)) >= 2:
             caller_frame = caller_frame_info[1]
             builtins.print(
                 hyperlink_to_path(
-                    text=sep.join(map(str, args)),
+                    text=_truncate(sep.join(map(str, args))),
                     path=Path(caller_frame.filename).resolve(),
                     line=caller_frame.lineno,
                     scheme=scheme,
@@ -37,13 +55,15 @@ def print(
                 flush=flush,
             )
             return
-    print(*args, sep=sep, end=end, file=file, flush=flush)
+    builtins.print(*args, sep=sep, end=end, file=file, flush=flush)
 
 
 def hyperlinked(text: str) -> str:
     """
     Return the given string with an OSC8 hyperlink added, pointing to the call site.
+    The text is truncated as by print.
     """
+    text = _truncate(text)
     frame = inspect.currentframe()
     if frame is None:
         return text
@@ -70,10 +90,72 @@ def hyperlink_to_path(
     scheme: str = SCHEME,
 ) -> str:
     """Creates an OSC8 hyperlink pointing to a file path, optionally with a line number."""
-    url = f"{scheme}://file/{path.resolve()}"
-    if line is not None:
-        url += f":{line}"
-    return hyperlink(text=text, url=url)
+    return hyperlink(text=text, url=format_url(path.resolve(), line, scheme))
+
+
+def format_url(path: Path, line: Optional[int] = None, scheme: str = SCHEME) -> str:
+    """
+    Returns the URL of a file path and line in the given link format, as the
+    Go ps package's FormatURL does. file URLs have no way to give the line.
+    """
+    file = quote(path.as_posix(), safe="/:")
+    suffix = f":{line}" if line is not None else ""
+    if scheme == "file":
+        return f"file://{file}"
+    if scheme == "wormhole":
+        return f"http://{WORMHOLE}/file/{file}{suffix}?land-in=editor"
+    if scheme == "vscode":
+        return f"vscode://file/{file}{suffix}"
+    return f"cursor://file/{file}{suffix}"
+
+
+def term_width() -> int:
+    """Returns the width set by HYPERLINKED_COLUMNS, or 0 if it is not set."""
+    try:
+        width = int(os.environ.get("HYPERLINKED_COLUMNS", ""))
+    except ValueError:
+        return 0
+    return max(width, 0)
+
+
+def text_width(text: str) -> int:
+    """
+    Returns the number of terminal columns text takes: two for wide East
+    Asian characters, none for combining and control characters.
+    """
+    width = 0
+    for c in text:
+        if unicodedata.combining(c) or unicodedata.category(c) in ("Cc", "Cf"):
+            continue
+        width += 2 if unicodedata.east_asian_width(c) in ("W", "F") else 1
+    return width
+
+
+def _truncate(text: str) -> str:
+    """Truncates each line of text as set by HYPERLINKED_NO_TRUNCATE and HYPERLINKED_COLUMNS."""
+    width = term_width()
+    if not TRUNCATE or width <= 0:
+        return text
+    return "\n".join(truncate_to_width(line, width) for line in text.split("\n"))
+
+
+def truncate_to_width(text: str, width: int, ellipsis: str = ELLIPSIS) -> str:
+    """
+    Truncates text to leave the last of width columns free, marking the cut
+    with ellipsis, in which "%d" is replaced by the number of characters cut,
+    as the Go ps package does.
+    """
+    if width <= 0 or text_width(text) <= width:
+        return text
+    budget = width - 1 - text_width(ellipsis.replace("%d", str(len(text))))
+    kept, used = 0, 0
+    for c in text:
+        w = text_width(c)
+        if used + w > budget:
+            break
+        used += w
+        kept += 1
+    return text[:kept] + ellipsis.replace("%d", str(len(text) - kept))
 
 
 def print_stack(f=None, limit=None, file=None, *, scheme: str = SCHEME):